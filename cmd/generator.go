@@ -2,8 +2,13 @@ package main
 
 import (
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
 	"sync"
+	"syscall"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
@@ -16,22 +21,118 @@ import (
 )
 
 var (
-	remoteURL              = kingpin.Flag("remote-url", "URL to send samples via remote_write API.").Required().URL()
-	remoteWriteInterval    = kingpin.Flag("remote-write-interval", "Frequency to generate new series data points and send them to the remote endpoint.").Default("10s").Duration()
-	remoteWriteTimeout     = kingpin.Flag("remote-write-timeout", "Remote endpoint write timeout.").Default("5s").Duration()
-	remoteWriteConcurrency = kingpin.Flag("remote-write-concurrency", "The max number of concurrent batch write requests per tenant.").Default("10").Int()
-	remoteBatchSize        = kingpin.Flag("remote-batch-size", "how many samples to send with each write request.").Default("1000").Int()
-	queryEnabled           = kingpin.Flag("query-enabled", "True to run queries to assess correctness").Default("false").Enum("true", "false")
-	queryURL               = kingpin.Flag("query-url", "Base URL of the query endpoint.").String()
-	queryInterval          = kingpin.Flag("query-interval", "Frequency to query each tenant.").Default("10s").Duration()
-	queryTimeout           = kingpin.Flag("query-timeout", "Query timeout.").Default("30s").Duration()
-	queryMaxAge            = kingpin.Flag("query-max-age", "How back in the past metrics can be queried at most.").Default("24h").Duration()
-	additionalQueries      = kingpin.Flag("query-additional-queries", "PromQL queries to run in addition to the default.").Strings()
-	tenantsCount           = kingpin.Flag("tenants-count", "Number of tenants to fake.").Default("1").Int()
-	seriesCount            = kingpin.Flag("series-count", "Number of series to generate for each tenant.").Default("1000").Int()
-	seriesChurnPeriod      = kingpin.Flag("series-churn-period", "How frequently the series should churn. Each series will churn over this duration, and series churning time is spread over the configured period (they will not churn all at the same time). 0 to disable churning.").Default("0").Duration()
-	extraLabelCount        = kingpin.Flag("extra-labels-count", "Number of extra labels to generate for series.").Default("0").Int()
-	serverMetricsPort      = kingpin.Flag("server-metrics-port", "The port where metrics are exposed.").Default("9900").Int()
+	writeEnabled             = kingpin.Flag("write-enabled", "True to write series. Set to false to only run queries against a pre-populated backend, e.g. written by another generator with the same config.").Default("true").Enum("true", "false")
+	remoteURL                = kingpin.Flag("remote-url", "URL to send samples via remote_write API. The {tenant} placeholder in the URL path is replaced with the tenant ID. Required if writes are enabled.").URL()
+	writeTenantPool          = kingpin.Flag("write-tenant-pool", "Tenant the write requests are sent to, drawn at random for each request, instead of one tenant per write client, like a single producer writing many tenants of a tenant-sharded ingest. Can be specified multiple times. The series of each tenant are incomplete, so they can't be verified by querying.").Strings()
+	tenantHeaderName         = kingpin.Flag("tenant-header-name", "Name of the HTTP header carrying the tenant ID in write and query requests, e.g. THANOS-TENANT for Thanos Receive.").Default("X-Scope-OrgID").String()
+	remoteWriteMethod        = kingpin.Flag("remote-write-method", "HTTP method used to send samples via remote_write API.").Default("POST").String()
+	remoteAcceptEncoding     = kingpin.Flag("remote-write-accept-encoding", "Accept-Encoding header of write requests (e.g. gzip). Gzip-encoded responses are decoded anyway, since some gateways gzip error messages. Empty to let the HTTP client negotiate it.").String()
+	remoteWriteVersion       = kingpin.Flag("remote-write-version-header", "Value of the X-Prometheus-Remote-Write-Version header sent with write requests, for gateways branching on it. It must be compatible with the remote write 1.0 wire format of the write requests (e.g. 0.1.0).").Default("0.1.0").String()
+	sigV4Region              = kingpin.Flag("sigv4-region", "AWS region to sign write requests for with AWS SigV4 (e.g. for Amazon Managed Service for Prometheus), using the default AWS credential chain. Empty to disable signing.").String()
+	remoteWriteInterval      = kingpin.Flag("remote-write-interval", "Frequency to generate new series data points and send them to the remote endpoint.").Default("10s").Duration()
+	remoteWriteTimeout       = kingpin.Flag("remote-write-timeout", "Remote endpoint write timeout.").Default("5s").Duration()
+	remoteWriteConcurrency   = kingpin.Flag("remote-write-concurrency", "The max number of concurrent batch write requests per tenant.").Default("10").Int()
+	concurrencySweepMin      = kingpin.Flag("concurrency-sweep-min", "Write concurrency at the start of the concurrency sweep.").Default("1").Int()
+	concurrencySweepMax      = kingpin.Flag("concurrency-sweep-max", "Max write concurrency of the concurrency sweep. 0 for no limit.").Default("0").Int()
+	concurrencySweepStep     = kingpin.Flag("concurrency-sweep-step", "Write concurrency increase at each step of the concurrency sweep.").Default("1").Int()
+	concurrencySweepDuration = kingpin.Flag("concurrency-sweep-step-duration", "Duration of each step of the concurrency sweep, which overrides remote-write-concurrency with a concurrency increasing over time and tracks the throughput achieved at each level. 0 to disable the sweep.").Default("0").Duration()
+	circuitBreakerThreshold  = kingpin.Flag("circuit-breaker-threshold", "Number of consecutive failed write requests (network errors, HTTP 5xx or 429) pausing writes for circuit-breaker-cooldown, to let the remote endpoint recover. 0 to disable the circuit breaker.").Default("0").Int()
+	circuitBreakerCooldown   = kingpin.Flag("circuit-breaker-cooldown", "How long writes are paused once the circuit breaker opens.").Default("1m").Duration()
+	halfOpenConcurrency      = kingpin.Flag("circuit-breaker-half-open-concurrency", "Write concurrency once the circuit breaker cooldown expired, until a write request succeeds. A failure pauses writes again.").Default("1").Int()
+	remoteWriteDeadlineRatio = kingpin.Flag("remote-write-deadline-ratio", "Fraction of the write interval within which all batches must be written. Batches not written by then are cancelled. 0 to disable.").Default("0").Float64()
+	maxInflightSamples       = kingpin.Flag("max-inflight-samples", "Max number of samples in flight across all write requests of all tenants, to bound the memory used when the remote endpoint is slow. 0 to disable.").Default("0").Int()
+	remoteBatchSize          = kingpin.Flag("remote-batch-size", "how many samples to send with each write request.").Default("1000").Int()
+	adaptiveBatchLatency     = kingpin.Flag("adaptive-batch-size-target-latency", "Target average latency of write requests. If set, the batch size auto-tunes after each write interval, starting from remote-batch-size: it's halved if write requests were slower than the target, and doubled if they were faster than half of it. 0 to disable.").Default("0").Duration()
+	adaptiveBatchMin         = kingpin.Flag("adaptive-batch-size-min", "Min batch size when the batch size auto-tunes.").Default("100").Int()
+	adaptiveBatchMax         = kingpin.Flag("adaptive-batch-size-max", "Max batch size when the batch size auto-tunes.").Default("10000").Int()
+	maxWriteBytes            = kingpin.Flag("max-write-bytes", "Max size (in bytes) of each compressed write request. Batches exceeding it are split further. 0 to disable.").Default("0").Int()
+	batchAssignment          = kingpin.Flag("batch-assignment", "How series are assigned to write requests: contiguous series in the same request, or round-robin across requests.").Default(client.BatchAssignmentContiguous).Enum(client.BatchAssignmentContiguous, client.BatchAssignmentRoundRobin)
+	injectWriteFailureRate   = kingpin.Flag("inject-write-failure-rate", "Probability (0-1) of a write request to fail without hitting the remote endpoint, for chaos testing. 0 to disable.").Default("0").Float64()
+	injectWriteFailureSeed   = kingpin.Flag("inject-write-failure-seed", "Seed used to randomly inject write failures, so that they're reproducible.").Default("1").Int64()
+	honorRetryAfter          = kingpin.Flag("remote-write-honor-retry-after", "Pause writes until the time specified by the Retry-After header of rate limited (HTTP 429) responses.").Default("false").Bool()
+	skipInitialWrite         = kingpin.Flag("skip-initial-write", "Wait one write interval before the first write, instead of writing immediately at startup.").Default("false").Bool()
+	emitStalenessOnShutdown  = kingpin.Flag("emit-staleness-on-shutdown", "On graceful shutdown (SIGINT or SIGTERM), send a stale marker for each series of the last write, so that the backend doesn't consider them active until they time out.").Default("false").Bool()
+	sendUnsortedLabels       = kingpin.Flag("send-unsorted-labels", "Deliberately send series labels unsorted, to verify the remote endpoint rejects them.").Default("false").Bool()
+	sortSeriesInRequest      = kingpin.Flag("sort-series-in-request", "Sort the series of each write request by their label sets.").Default("false").Bool()
+	haReplicas               = kingpin.Flag("ha-replicas", "Number of HA replicas writing each series, to test the remote endpoint deduplication. If greater than 1, each series is written once per replica with a shared cluster label and a different __replica__ label. Queries expect replicas to be deduplicated.").Default("0").Int()
+	checksumLabel            = kingpin.Flag("checksum-label", "Add a label to each series with the checksum of its value, and verify it at query time instead of the default query, to detect silent data corruption. Each sample is written to a new series, so it's only meant for storage-layer testing.").Default("false").Bool()
+	queryEnabled             = kingpin.Flag("query-enabled", "True to run queries to assess correctness").Default("false").Enum("true", "false")
+	queryURL                 = kingpin.Flag("query-url", "Base URL of the query endpoint.").String()
+	queryURLSecondary        = kingpin.Flag("query-url-secondary", "Base URL of a second query endpoint. If set, the default and additional queries are run against both query endpoints and their results are compared to each other, instead of being verified against the expected values.").String()
+	queryMinStep             = kingpin.Flag("query-min-step", "Min step of queries, rounded up to a multiple of the write interval. It takes precedence over query-max-samples. 0 to disable.").Default("0").Duration()
+	queryMaxSamples          = kingpin.Flag("query-max-samples", "Max number of samples per series returned by each query. The query step is the smallest multiple of the write interval honoring it.").Default("1000").Int()
+	queryHeaders             = kingpin.Flag("query-header", "Additional HTTP header to set on query requests, in the format name=value (e.g. Accept=application/json). Can be specified multiple times.").StringMap()
+	queryNoCache             = kingpin.Flag("query-no-cache", "Ask the query-frontend to bypass the results cache for all queries, setting the Cache-Control: no-store header.").Default("false").Bool()
+	queryNoCacheAlternate    = kingpin.Flag("query-no-cache-alternate", "Ask the query-frontend to bypass the results cache for every other query, to compare the cached and uncached query latency.").Default("false").Bool()
+	queryGzipRequests        = kingpin.Flag("query-gzip-requests", "Gzip-compress the body of POST query requests, setting the Content-Encoding: gzip header.").Default("false").Bool()
+	queryInterval            = kingpin.Flag("query-interval", "Frequency to query each tenant.").Default("10s").Duration()
+	queryTimeout             = kingpin.Flag("query-timeout", "Query timeout.").Default("30s").Duration()
+	queryMaxAge              = kingpin.Flag("query-max-age", "How back in the past metrics can be queried at most.").Default("24h").Duration()
+	additionalQueries        = kingpin.Flag("query-additional-queries", "PromQL queries to run in addition to the default. Each query can be prefixed by options in square brackets, e.g. \"[timeout=1m]sum(up)\" to override the query timeout, or \"[expected=0.5*sum]my:recording:rule\" to verify the results against a constant or the expected sum of sine wave series multiplied by a constant. Add the window option, e.g. \"[expected=sum,window=5m]sum(avg_over_time(cortex_load_generator_sine_wave[5m]))\", to verify queries averaging the written samples over a range.").Strings()
+	queryConcurrency         = kingpin.Flag("query-concurrency", "Number of concurrent copies of each query to run at every query interval, to simulate many users querying the same dashboard. It also bounds the number of queries in flight, unless -query-concurrency-limit is set.").Default("1").Int()
+	queryConcurrencyLimit    = kingpin.Flag("query-concurrency-limit", "Max number of queries in flight at the same time for each tenant, overriding the bound set by -query-concurrency, e.g. to not overwhelm small query endpoints when many queries are configured. 0 to bound them to -query-concurrency.").Default("0").Int()
+	queryVerifyRecorded      = kingpin.Flag("query-verify-recorded", "Verify the default query results against the values actually pushed, recorded in memory up to query-max-age, instead of the expected sine wave.").Default("false").Bool()
+	queryExpectStepAverage   = kingpin.Flag("query-expect-step-average", "Expect the default query to return the average value over each step instead of the value at the step timestamp, to verify backends serving downsampled data.").Default("false").Bool()
+	exitOnComparisonFailure  = kingpin.Flag("exit-on-comparison-failure", "Exit with a non-zero status code on the first failed query result comparison, e.g. to gate CI runs.").Default("false").Bool()
+	queryVerifySeriesCount   = kingpin.Flag("query-verify-series-count", "Run a count query on the sine wave series too, and verify it matches the expected number of series. Not reliable with series churn, because churned out series are counted until they exit the query lookback window.").Default("false").Bool()
+	queryVerifyRawSeries     = kingpin.Flag("query-verify-raw-series", "Run the default query against the raw sine wave series, instead of their sum, and verify each series individually. Not compatible with series churn.").Default("false").Bool()
+	queryVerifyResultsCache  = kingpin.Flag("query-verify-results-cache", "Run the default query twice in a row too, and verify the second run, expected to be served from the query-frontend results cache, is faster and returns the same result. Not meaningful with query-no-cache.").Default("false").Bool()
+	queryStreamDefault       = kingpin.Flag("query-stream-default", "Verify the default query results while the response is read, instead of decoding the whole response first, so that memory stays bounded for large query ranges. Ignored with query-verify-raw-series or query-verify-recorded.").Default("false").Bool()
+	skipInitialQuery         = kingpin.Flag("skip-initial-query", "Wait one query interval before the first queries, instead of querying immediately at startup.").Default("false").Bool()
+	queryStartupSpread       = kingpin.Flag("query-startup-spread", "Time window over which the query clients of all tenants are evenly started, to avoid spiking the read path at startup. 0 to start all of them immediately.").Default("0").Duration()
+	queryBackoffMaxInterval  = kingpin.Flag("query-backoff-max-interval", "Max interval between queries while they're failing. The query interval is doubled after each run with failed queries, up to this max, and reset once all queries succeed. 0 to disable backoff.").Default("0").Duration()
+	queryBackoffJitter       = kingpin.Flag("query-backoff-jitter", "Max fraction (0-1) of the query backoff interval randomly subtracted from it, so that tenants don't query in lockstep.").Default("0.1").Float64()
+	additionalQueryTemplate  = kingpin.Flag("additional-query-template", "PromQL query template used to generate additional queries. The {i} placeholder is replaced with a number from 1 to additional-query-count.").String()
+	additionalQueryCount     = kingpin.Flag("additional-query-count", "Number of additional queries to generate from additional-query-template.").Default("0").Int()
+	tenantsCount             = kingpin.Flag("tenants-count", "Number of tenants to fake.").Default("1").Int()
+	seriesCount              = kingpin.Flag("series-count", "Number of series to generate for each tenant, on average according to tenant-size-distribution. When ramping up, this is the target number of series.").Default("1000").Int()
+	tenantSizeDistribution   = kingpin.Flag("tenant-size-distribution", "How series are distributed across tenants: uniform gives each tenant series-count series, zipf splits a budget of series-count × tenants-count series so that the k-th tenant gets a number of series proportional to 1/k^tenant-size-zipf-exponent.").Default(client.TenantSizeUniform).Enum(client.TenantSizeUniform, client.TenantSizeZipf)
+	tenantSizeZipfExponent   = kingpin.Flag("tenant-size-zipf-exponent", "Exponent of the zipf tenant size distribution. The higher, the longer the tail of small tenants.").Default("1").Float64()
+	tenantRates              = kingpin.Flag("tenant-rate", "Samples per second pushed by a tenant, in the format tenant=rate (e.g. load-generator-1=1000), to simulate noisy neighbors. The number of series of the tenant is set accordingly, overriding the series count so that the tenant can push more (or less) than the others, and capped during bursts. It takes into account all the samples written for each series (metric names, HA replicas, summary quantiles, churn backfill) and the info series. The rate is approximate, since samples are pushed in bursts every write interval and churn backfills are averaged over the churn period. Can be specified multiple times.").StringMap()
+	seriesCountStart         = kingpin.Flag("series-count-start", "Number of series to generate for each tenant at startup, when ramping up.").Default("0").Int()
+	seriesRampDuration       = kingpin.Flag("series-ramp-duration", "Duration over which the number of series linearly grows from series-count-start to series-count, then holds. 0 to disable ramping up.").Default("0").Duration()
+	burstInterval            = kingpin.Flag("burst-interval", "Frequency of series count bursts. At the end of each interval, the number of series is multiplied by burst-multiplier for burst-duration. The series dropped at the end of a burst are marked as stale. 0 to disable bursts.").Default("0").Duration()
+	burstDuration            = kingpin.Flag("burst-duration", "Duration of each series count burst.").Default("1m").Duration()
+	burstMultiplier          = kingpin.Flag("burst-multiplier", "Multiplier of the number of series during bursts.").Default("2").Float64()
+	seriesChurnPeriod        = kingpin.Flag("series-churn-period", "How frequently the series should churn. Each series will churn over this duration, and series churning time is spread over the configured period (they will not churn all at the same time). Churning never alters the series values. 0 to disable churning.").Default("0").Duration()
+	valueChurnLabel          = kingpin.Flag("value-churn-label", "Name of a label whose value rotates every value-churn-period for all series, while the other labels don't change. Empty to disable.").String()
+	valueChurnPeriod         = kingpin.Flag("value-churn-period", "How frequently the value of value-churn-label rotates.").Default("1h").Duration()
+	churnMode                = kingpin.Flag("churn-mode", "How series churn: gradual spreads the churning over the churn period, cliff churns all series at the same time at the end of each period.").Default(client.ChurnModeGradual).Enum(client.ChurnModeGradual, client.ChurnModeCliff)
+	churnEpoch               = kingpin.Flag("churn-epoch", "Anchor of the churn period boundaries, in RFC3339 format (e.g. 2023-06-29T00:00:00Z), for reproducible churn across runs. Churn IDs count the periods elapsed since then. Empty to anchor them to the Unix epoch.").String()
+	churnTarget              = kingpin.Flag("churn-target", "Which part of the series identity changes when series churn: label adds a churn label, name adds a suffix to the metric name (not compatible with query verification), id changes the wave label value (not compatible with query-verify-raw-series).").Default(client.ChurnTargetLabel).Enum(client.ChurnTargetLabel, client.ChurnTargetName, client.ChurnTargetID)
+	clockSkewStdDev          = kingpin.Flag("clock-skew-stddev", "Standard deviation of the clock skew of each series, to simulate agents with slightly skewed clocks. Sample timestamps of each series are moved back in time by a deterministic skew, bounded to less than the write interval. 0 to disable.").Default("0").Duration()
+	fullChurn                = kingpin.Flag("full-churn", "Add a label to each series whose value changes every write interval, so that no series persists across intervals, for maximum index churn (effectively infinite cardinality growth). Queries are disabled, because there's nothing stable to verify.").Default("false").Bool()
+	churnBackfillSamples     = kingpin.Flag("churn-backfill-samples", "Number of samples of the previous write intervals sent along with the first sample of each newly churned series, to test the head block handling of series starting in the past. Query results can't be verified at backfilled timestamps. 0 to disable.").Default("0").Int()
+	metricHelp               = kingpin.Flag("metric-help", "HELP of the generated sine wave metrics, sent as metadata in each write request along with metric-type. Empty to not send metadata.").String()
+	metricType               = kingpin.Flag("metric-type", "TYPE of the generated sine wave metrics, sent as metadata in each write request along with metric-help.").Default("gauge").Enum(client.MetricTypes()...)
+	metricNamesCount         = kingpin.Flag("metric-names-count", "Number of distinct metric names to generate. Each metric name gets series-count series. If greater than 1, metric names get a numeric suffix.").Default("1").Int()
+	tenantMetricName         = kingpin.Flag("tenant-metric-name", "Suffix the sine wave, info and summary metric names with the tenant ID (e.g. cortex_load_generator_sine_wave_load_generator_1), so that the series of different tenants have distinct names even if a backend strips the tenant label. Queries target the tenant metric names.").Default("false").Bool()
+	metricNameRotation       = kingpin.Flag("metric-name-rotation-period", "How frequently the metric names are rotated, adding a version suffix (e.g. _v2) which retires all series at once and starts new ones, to stress the compaction of metric name turnover. Queries target the current metric names. 0 to disable.").Default("0").Duration()
+	extraLabelCount          = kingpin.Flag("extra-labels-count", "Number of extra labels to generate for series.").Default("0").Int()
+	externalLabels           = kingpin.Flag("external-label", "Static label added to all generated series, in the format name=value (e.g. env=staging). Can be specified multiple times.").StringMap()
+	distinctLabelNames       = kingpin.Flag("distinct-label-names", "Size of the pool of label names series are spread across, to stress the number of distinct label names in the index. Each series gets a random subset of the pool. 0 to disable.").Default("0").Int()
+	fakeInstances            = kingpin.Flag("fake-instances", "Number of synthetic instances series are spread across, through an instance label whose value cycles over series. 0 to disable.").Default("0").Int()
+	emitCreatedLabel         = kingpin.Flag("emit-created-label", "Add a created label to each series with a deterministic creation timestamp, stable over time but unique across series.").Default("false").Bool()
+	seriesShards             = kingpin.Flag("series-shards", "Number of shards series are hashed into (xxhash of the sorted labels modulo the number of shards), to evaluate how evenly series are sharded. Each series gets a shard label, and the distribution of the number of series per shard is exported as a histogram. 0 to disable.").Default("0").Int()
+	seriesPool               = kingpin.Flag("series-pool", "Build the label sets of the series once, and only generate their samples each write interval, to reduce the CPU usage. Only applies when series labels don't change over time (e.g. without churning series).").Default("false").Bool()
+	dimensions               = kingpin.Flag("dimensions", "Comma-separated list of dimension labels in the format name:size (e.g. region:10,host:20,job:5). Series are generated as the cross-product of all dimensions, and series-count is overridden by the number of combinations. Empty to disable.").String()
+	replayFile               = kingpin.Flag("replay-file", "Path to a file of recorded samples (one \"<timestamp ms> <value>\" per line) to replay instead of generating a sine wave. The replay starts from the first recorded sample when the load generator starts, and loops once exhausted.").String()
+	targetCompressionRatio   = kingpin.Flag("target-compression-ratio", "Generate values made of constant runs and random jumps, approximating the target snappy compression ratio, instead of a sine wave. 0 to disable.").Default("0").Float64()
+	logNormalMu              = kingpin.Flag("lognormal-mu", "Mean of the logarithm of the log-normally distributed values, generated when lognormal-sigma is greater than 0.").Default("0").Float64()
+	logNormalSigma           = kingpin.Flag("lognormal-sigma", "Standard deviation of the logarithm of the log-normally distributed values. If greater than 0, each series gets log-normally distributed values, like latency metrics, instead of a sine wave. 0 to disable.").Default("0").Float64()
+	valueSeedFile            = kingpin.Flag("value-seed-file", "Path to a file of value seeds (one integer per line). If set, each series gets a reproducible sequence of adversarial values (random sign, exponent and mantissa) driven by its seed, instead of a sine wave, to fuzz the float handling of the backend. Series loop over the seeds. The verifier reads the same file to compute the expected values.").String()
+	valuesSeed               = kingpin.Flag("values-seed", "Seed used to generate random values, so that they can be reproduced to verify query results.").Default("1").Int64()
+	interSeriesDecorrelation = kingpin.Flag("inter-series-decorrelation", "Max offset added to each series value, so that series don't share the same exact value. The offset is a deterministic function of the series ID, so the aggregated value can still be verified. 0 to disable.").Default("0").Float64()
+	inverseSeriesRatio       = kingpin.Flag("inverse-series-ratio", "Fraction (0-1) of series whose value is negated (e.g. an inverse sine wave), so that their sum with the other series cancels out toward zero. 0 to disable.").Default("0").Float64()
+	valueQuantization        = kingpin.Flag("value-quantization", "Number of decimal places generated values are rounded to. 0 to disable quantization.").Default("0").Int()
+	valueMantissaBits        = kingpin.Flag("value-mantissa-bits", "Number of mantissa bits (1-52) generated values are truncated to, to test how precision affects the compression of samples (e.g. Gorilla XOR encoding). The verifier applies the same truncation. 0 to keep the full float64 precision.").Default("0").Int()
+	valueMin                 = kingpin.Flag("value-min", "Min value of the generated sine wave. The sine wave is linearly mapped from [-1, 1] to [value-min, value-max] if value-max is greater than value-min.").Default("0").Float64()
+	valueMax                 = kingpin.Flag("value-max", "Max value of the generated sine wave. The sine wave is linearly mapped from [-1, 1] to [value-min, value-max] if value-max is greater than value-min.").Default("0").Float64()
+	infoSeriesCount          = kingpin.Flag("info-series-count", "Number of info series (constant value 1, many labels) to generate for each tenant.").Default("0").Int()
+	infoSeriesLabels         = kingpin.Flag("info-series-labels", "Number of labels to generate for each info series.").Default("10").Int()
+	summaryQuantiles         = kingpin.Flag("summary-quantiles", "Comma-separated list of quantiles (e.g. 0.5,0.9,0.99). If set, the quantile series of a classic summary are generated for each sine wave series. Empty to disable.").String()
+	httpProxy                = kingpin.Flag("http-proxy", "URL of the HTTP proxy used to send write and query requests. If unset, the proxy environment variables (HTTP_PROXY, HTTPS_PROXY, NO_PROXY) are honored.").URL()
+	serverMetricsPort        = kingpin.Flag("server-metrics-port", "The port where metrics are exposed.").Default("9900").Int()
 )
 
 func main() {
@@ -40,54 +141,369 @@ func main() {
 	kingpin.CommandLine.Help = "cortex-load-generator"
 	kingpin.Parse()
 
-	// Run the instrumentation server.
+	// Setup the instrumentation server.
 	logger := log.NewLogfmtLogger(os.Stdout)
 	reg := prometheus.NewRegistry()
 	reg.MustRegister(collectors.NewGoCollector())
 
 	i := util.NewInstrumentationServer(*serverMetricsPort, logger, reg)
-	if err := i.Start(); err != nil {
-		level.Error(logger).Log("msg", "Unable to start instrumentation server", "err", err.Error())
+
+	if *valueMantissaBits < 0 || *valueMantissaBits > 52 {
+		level.Error(logger).Log("msg", "Invalid value mantissa bits, expected a number between 0 and 52", "bits", *valueMantissaBits)
 		os.Exit(1)
 	}
 
+	// Configure the generated values, loading the samples to replay if any.
+	values := client.ValueConfig{
+		Decorrelation: *interSeriesDecorrelation,
+		Quantization:  *valueQuantization,
+		MantissaBits:  *valueMantissaBits,
+		InverseRatio:  *inverseSeriesRatio,
+		Min:           *valueMin,
+		Max:           *valueMax,
+	}
+	if *replayFile != "" {
+		// The replay starts from the first recorded sample now.
+		replay, err := client.LoadReplayFile(*replayFile, time.Now())
+		if err != nil {
+			level.Error(logger).Log("msg", "Unable to load replay file", "err", err.Error())
+			os.Exit(1)
+		}
+		values.Replay = replay
+	}
+	if *targetCompressionRatio > 0 {
+		values.Compressible = client.NewCompressibleValues(*targetCompressionRatio, *remoteWriteInterval, *valuesSeed)
+	}
+	if *logNormalSigma > 0 {
+		values.LogNormal = client.NewLogNormalValues(*logNormalMu, *logNormalSigma, *valuesSeed)
+	}
+	if *valueSeedFile != "" {
+		seeds, err := client.LoadSeedValuesFile(*valueSeedFile)
+		if err != nil {
+			level.Error(logger).Log("msg", "Unable to load value seed file", "err", err.Error())
+			os.Exit(1)
+		}
+		values.Seeds = seeds
+	}
+
+	// Parse the additional queries, including the ones generated from the template.
+	rawQueries := *additionalQueries
+	if *additionalQueryTemplate != "" {
+		rawQueries = append(rawQueries, client.ExpandAdditionalQueryTemplate(*additionalQueryTemplate, *additionalQueryCount)...)
+	}
+
+	queries := make([]client.AdditionalQuery, 0, len(rawQueries))
+	for _, s := range rawQueries {
+		query, err := client.ParseAdditionalQuery(s)
+		if err != nil {
+			level.Error(logger).Log("msg", "Unable to parse additional query", "err", err.Error())
+			os.Exit(1)
+		}
+		queries = append(queries, query)
+	}
+
+	// Send the metadata of the generated metrics, if configured.
+	var metadata *client.MetricMetadata
+	if *metricHelp != "" {
+		metadata = &client.MetricMetadata{Type: *metricType, Help: *metricHelp}
+	}
+
+	// Generate series as the cross-product of the dimensions, if any.
+	var seriesDimensions []client.Dimension
+	if *dimensions != "" {
+		var err error
+		if seriesDimensions, err = client.ParseDimensions(*dimensions); err != nil {
+			level.Error(logger).Log("msg", "Unable to parse dimensions", "err", err.Error())
+			os.Exit(1)
+		}
+		*seriesCount = client.DimensionsCardinality(seriesDimensions)
+	}
+
+	if err := client.ValidateRemoteWriteVersion(*remoteWriteVersion); err != nil {
+		level.Error(logger).Log("msg", "Invalid remote write version header", "err", err.Error())
+		os.Exit(1)
+	}
+
+	if *writeEnabled == "true" && *remoteURL == nil {
+		level.Error(logger).Log("msg", "The remote URL is required when writes are enabled")
+		os.Exit(1)
+	}
+	if *writeEnabled == "false" && *queryVerifyRecorded {
+		level.Error(logger).Log("msg", "Query results can't be verified against the recorded values when writes are disabled")
+		os.Exit(1)
+	}
+
+	if *fullChurn && *queryEnabled == "true" {
+		level.Warn(logger).Log("msg", "Queries are disabled because series fully churn every write interval")
+	}
+
+	var churnEpochTime time.Time
+	if *churnEpoch != "" {
+		var err error
+		if churnEpochTime, err = time.Parse(time.RFC3339, *churnEpoch); err != nil {
+			level.Error(logger).Log("msg", "Unable to parse churn epoch", "err", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	// Generate summary quantile series, if any.
+	var quantiles []float64
+	if *summaryQuantiles != "" {
+		var err error
+		if quantiles, err = client.ParseSummaryQuantiles(*summaryQuantiles); err != nil {
+			level.Error(logger).Log("msg", "Unable to parse summary quantiles", "err", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	// Split the series budget across tenants.
+	tenantSeriesCounts, err := client.TenantSeriesCounts(*tenantSizeDistribution, *tenantsCount, *seriesCount**tenantsCount, *tenantSizeZipfExponent)
+	if err != nil {
+		level.Error(logger).Log("msg", "Unable to compute the number of series of each tenant", "err", err.Error())
+		os.Exit(1)
+	}
+
+	// Parse the per-tenant rate limits.
+	tenantMaxRates := map[string]float64{}
+	for tenant, value := range *tenantRates {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate <= 0 {
+			level.Error(logger).Log("msg", "Invalid tenant rate, expected a positive number of samples per second", "tenant", tenant, "rate", value)
+			os.Exit(1)
+		}
+
+		tenantMaxRates[tenant] = rate
+	}
+
+	// All tenants share the same series schedule, starting now.
+	schedule := client.SeriesSchedule{
+		StartTime:       time.Now(),
+		RampStartCount:  *seriesCountStart,
+		RampDuration:    *seriesRampDuration,
+		BurstInterval:   *burstInterval,
+		BurstDuration:   *burstDuration,
+		BurstMultiplier: *burstMultiplier,
+	}
+
+	// All tenants share the same concurrency sweep, starting now.
+	concurrencySweep := client.ConcurrencySweep{
+		StartTime:    time.Now(),
+		Min:          *concurrencySweepMin,
+		Max:          *concurrencySweepMax,
+		Step:         *concurrencySweepStep,
+		StepDuration: *concurrencySweepDuration,
+	}
+
+	// All tenants share the same in-flight samples limit.
+	var inflightSamples *client.InflightSamplesLimiter
+	if *maxInflightSamples > 0 {
+		inflightSamples = client.NewInflightSamplesLimiter(*maxInflightSamples)
+	}
+
+	circuitBreaker := client.CircuitBreaker{
+		FailureThreshold:    *circuitBreakerThreshold,
+		Cooldown:            *circuitBreakerCooldown,
+		HalfOpenConcurrency: *halfOpenConcurrency,
+	}
+
+	adaptiveBatchSize := client.AdaptiveBatchSize{
+		TargetLatency: *adaptiveBatchLatency,
+		Min:           *adaptiveBatchMin,
+		Max:           *adaptiveBatchMax,
+	}
+
+	// Share the same transport across all tenants, to reuse connections. The tenant ID is
+	// injected in each request by the clients.
+	transport := &http.Transport{
+		Proxy:               client.ProxyFunc(*httpProxy),
+		MaxIdleConnsPerHost: *tenantsCount * *remoteWriteConcurrency,
+	}
+
+	// Sign write requests, if required.
+	writeTransport := http.RoundTripper(transport)
+	if *sigV4Region != "" {
+		var err error
+		if writeTransport, err = client.NewSigV4RoundTripper(*sigV4Region, transport); err != nil {
+			level.Error(logger).Log("msg", "Unable to configure AWS SigV4 signing", "err", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	// Receive the query result comparison failures, to exit on the first one if configured.
+	var comparisonFailures chan error
+	if *exitOnComparisonFailure {
+		comparisonFailures = make(chan error, 1)
+	}
+
 	// Start a client for each tenant.
-	wg := sync.WaitGroup{}
-	wg.Add(*tenantsCount)
 
+	var (
+		writeClients []*client.WriteClient
+		queryClients []*client.QueryClient
+	)
 	for t := 1; t <= *tenantsCount; t++ {
 		userID := fmt.Sprintf("load-generator-%d", t)
 
-		writeClient := client.NewWriteClient(client.WriteClientConfig{
-			URL:               **remoteURL,
-			WriteInterval:     *remoteWriteInterval,
-			WriteTimeout:      *remoteWriteTimeout,
-			WriteConcurrency:  *remoteWriteConcurrency,
-			WriteBatchSize:    *remoteBatchSize,
-			UserID:            userID,
-			SeriesCount:       *seriesCount,
-			SeriesChurnPeriod: *seriesChurnPeriod,
-			ExtraLabels:       *extraLabelCount,
-		}, logger)
-
-		writeClient.Start()
-
-		if *queryEnabled == "true" {
+		// Record the values pushed, to verify query results against them.
+		var recorder *client.Recorder
+		if *queryVerifyRecorded {
+			recorder = client.NewRecorder(int(*queryMaxAge / *remoteWriteInterval) + 1)
+		}
+
+		writeCfg := client.WriteClientConfig{
+			WriteMethod:            *remoteWriteMethod,
+			RemoteWriteVersion:     *remoteWriteVersion,
+			AcceptEncoding:         *remoteAcceptEncoding,
+			Transport:              writeTransport,
+			WriteInterval:          *remoteWriteInterval,
+			WriteTimeout:           *remoteWriteTimeout,
+			WriteConcurrency:       *remoteWriteConcurrency,
+			ConcurrencySweep:       concurrencySweep,
+			InflightSamples:        inflightSamples,
+			CircuitBreaker:         circuitBreaker,
+			WriteBatchSize:         *remoteBatchSize,
+			AdaptiveBatchSize:      adaptiveBatchSize,
+			MaxWriteBytes:          *maxWriteBytes,
+			WriteDeadlineRatio:     *remoteWriteDeadlineRatio,
+			BatchAssignment:        *batchAssignment,
+			UserID:                 userID,
+			TenantHeaderName:       *tenantHeaderName,
+			TenantPool:             *writeTenantPool,
+			SeriesCount:            tenantSeriesCounts[t-1],
+			SeriesChurnPeriod:      *seriesChurnPeriod,
+			ChurnMode:              *churnMode,
+			ChurnEpoch:             churnEpochTime,
+			ChurnTarget:            *churnTarget,
+			ChurnBackfillSamples:   *churnBackfillSamples,
+			FullChurn:              *fullChurn,
+			ValueChurnLabel:        *valueChurnLabel,
+			ValueChurnPeriod:       *valueChurnPeriod,
+			Schedule:               schedule,
+			MetricNamesCount:       *metricNamesCount,
+			TenantMetricName:       *tenantMetricName,
+			NameRotationPeriod:     *metricNameRotation,
+			ExtraLabels:            *extraLabelCount,
+			ExternalLabels:         *externalLabels,
+			DistinctLabelNames:     *distinctLabelNames,
+			Dimensions:             seriesDimensions,
+			FakeInstances:          *fakeInstances,
+			CreatedLabel:           *emitCreatedLabel,
+			SeriesShards:           *seriesShards,
+			SeriesPool:             *seriesPool,
+			InfoSeriesCount:        *infoSeriesCount,
+			InfoSeriesLabels:       *infoSeriesLabels,
+			SummaryQuantiles:       quantiles,
+			Values:                 values,
+			InjectWriteFailureRate: *injectWriteFailureRate,
+			InjectWriteFailureSeed: *injectWriteFailureSeed,
+			HonorRetryAfter:        *honorRetryAfter,
+			SkipInitialWrite:       *skipInitialWrite,
+			StaleMarkersOnStop:     *emitStalenessOnShutdown,
+			SendUnsortedLabels:     *sendUnsortedLabels,
+			SortSeriesInRequest:    *sortSeriesInRequest,
+			ChecksumLabel:          *checksumLabel,
+			HAReplicas:             *haReplicas,
+			ClockSkewStdDev:        *clockSkewStdDev,
+			Recorder:               recorder,
+			Metadata:               metadata,
+		}
+
+		// Honor the tenant rate, if any, sizing the number of series of the tenant to push samples at
+		// the rate, above or below the configured series count. It's also the cap during bursts.
+		tenantSchedule := schedule
+		if rate, ok := tenantMaxRates[userID]; ok {
+			writeCfg.SeriesCount = client.SeriesCountForRate(rate, writeCfg)
+			tenantSchedule.MaxCount = writeCfg.SeriesCount
+			writeCfg.Schedule = tenantSchedule
+		}
+
+		if *writeEnabled == "true" {
+			writeCfg.URL = **remoteURL
+			writeClient := client.NewWriteClient(writeCfg, logger, reg)
+
+			writeClient.Start()
+			writeClients = append(writeClients, writeClient)
+		}
+
+		if *queryEnabled == "true" && !*fullChurn {
 			queryClient := client.NewQueryClient(client.QueryClientConfig{
-				URL:                   *queryURL,
-				UserID:                userID,
-				QueryInterval:         *queryInterval,
-				QueryTimeout:          *queryTimeout,
-				QueryMaxAge:           *queryMaxAge,
-				ExpectedSeries:        *seriesCount,
-				ExpectedWriteInterval: *remoteWriteInterval,
-				AdditionalQueries:     *additionalQueries,
+				URL:                      *queryURL,
+				SecondaryURL:             *queryURLSecondary,
+				Transport:                transport,
+				UserID:                   userID,
+				TenantHeaderName:         *tenantHeaderName,
+				QueryInterval:            *queryInterval,
+				QueryTimeout:             *queryTimeout,
+				QueryMaxAge:              *queryMaxAge,
+				QueryMinStep:             *queryMinStep,
+				QueryMaxSamples:          *queryMaxSamples,
+				QueryHeaders:             *queryHeaders,
+				QueryNoCache:             *queryNoCache,
+				QueryNoCacheAlternate:    *queryNoCacheAlternate,
+				QueryGzipRequests:        *queryGzipRequests,
+				ExpectedSeries:           writeCfg.SeriesCount,
+				Schedule:                 tenantSchedule,
+				ExpectedWriteInterval:    *remoteWriteInterval,
+				ExternalWrites:           *writeEnabled == "false",
+				ExpectedMetricNamesCount: *metricNamesCount,
+				TenantMetricName:         *tenantMetricName,
+				NameRotationPeriod:       *metricNameRotation,
+				ExpectedInfoSeries:       *infoSeriesCount,
+				AdditionalQueries:        queries,
+				QueryConcurrency:         *queryConcurrency,
+				QueryConcurrencyLimit:    *queryConcurrencyLimit,
+				Values:                   values,
+				SkipInitialQuery:         *skipInitialQuery,
+				QueryBackoffMaxInterval:  *queryBackoffMaxInterval,
+				QueryBackoffJitter:       *queryBackoffJitter,
+				VerifyChecksums:          *checksumLabel,
+				ExpectStepAverage:        *queryExpectStepAverage,
+				VerifyResultsCache:       *queryVerifyResultsCache,
+				VerifySeriesCount:        *queryVerifySeriesCount,
+				VerifyRawSeries:          *queryVerifyRawSeries,
+				StreamDefaultQuery:       *queryStreamDefault,
+				Recorder:                 recorder,
+				ComparisonFailures:       comparisonFailures,
 			}, logger, reg)
 
-			queryClient.Start()
+			// Stagger the query clients startup across tenants.
+			time.AfterFunc(client.StartupDelay(t-1, *tenantsCount, *queryStartupSpread), queryClient.Start)
+			queryClients = append(queryClients, queryClient)
 		}
 	}
 
-	// Will wait indefinitely.
+	client.NewActiveClients(reg).Set(*tenantsCount, len(writeClients))
+	i.Handle("/flush", client.FlushHandler(writeClients), http.MethodPost)
+	i.Handle("/series-preview", client.SeriesPreviewHandler(writeClients), http.MethodGet)
+	i.Handle("/query-errors", client.QueryErrorsHandler(queryClients), http.MethodGet)
+
+	// Run the instrumentation server.
+	if err := i.Start(); err != nil {
+		level.Error(logger).Log("msg", "Unable to start instrumentation server", "err", err.Error())
+		os.Exit(1)
+	}
+
+	shutdown := make(chan os.Signal, 1)
+	signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM)
+
+	// Will wait until shutdown, unless exiting on the first comparison failure (receiving
+	// from a nil channel blocks forever).
+	select {
+	case err = <-comparisonFailures:
+		level.Error(logger).Log("msg", "Exiting because of a failed query result comparison", "err", err.Error())
+		os.Exit(1)
+	case sig := <-shutdown:
+		level.Info(logger).Log("msg", "Shutting down", "signal", sig.String())
+	}
+
+	// Stop the write clients, which send the stale markers if configured.
+	wg := sync.WaitGroup{}
+	wg.Add(len(writeClients))
+	for _, c := range writeClients {
+		go func(c *client.WriteClient) {
+			defer wg.Done()
+			c.Stop()
+		}(c)
+	}
 	wg.Wait()
 }
@@ -0,0 +1,32 @@
+package client
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ActiveClients exposes the number of active tenants and clients, to confirm the
+// configuration took effect.
+type ActiveClients struct {
+	activeTenants      prometheus.Gauge
+	activeWriteClients prometheus.Gauge
+}
+
+func NewActiveClients(reg prometheus.Registerer) *ActiveClients {
+	return &ActiveClients{
+		activeTenants: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "cortex_load_generator_active_tenants",
+			Help: "Number of tenants for which series are generated.",
+		}),
+		activeWriteClients: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "cortex_load_generator_active_write_clients",
+			Help: "Number of running write clients.",
+		}),
+	}
+}
+
+// Set sets the number of active tenants and write clients.
+func (a *ActiveClients) Set(tenants, writeClients int) {
+	a.activeTenants.Set(float64(tenants))
+	a.activeWriteClients.Set(float64(writeClients))
+}
@@ -0,0 +1,26 @@
+package client
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActiveClients(t *testing.T) {
+	const numTenants = 3
+
+	reg := prometheus.NewPedanticRegistry()
+	NewActiveClients(reg).Set(numTenants, numTenants)
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_load_generator_active_tenants Number of tenants for which series are generated.
+		# TYPE cortex_load_generator_active_tenants gauge
+		cortex_load_generator_active_tenants 3
+		# HELP cortex_load_generator_active_write_clients Number of running write clients.
+		# TYPE cortex_load_generator_active_write_clients gauge
+		cortex_load_generator_active_write_clients 3
+	`), "cortex_load_generator_active_tenants", "cortex_load_generator_active_write_clients"))
+}
@@ -0,0 +1,93 @@
+package client
+
+import (
+	"sync"
+	"time"
+)
+
+// AdaptiveBatchSize configures the write batch size to auto-tune based on the write requests
+// latency, to maximize the throughput without overrunning the write interval.
+type AdaptiveBatchSize struct {
+	// TargetLatency is the target average latency of write requests. After each write interval,
+	// the batch size is halved if the average latency was above the target, and doubled if it
+	// was below half of the target. 0 to disable adaptive batch sizing.
+	TargetLatency time.Duration
+
+	// Min and Max bound the batch size.
+	Min int
+	Max int
+}
+
+// enabled returns whether adaptive batch sizing is enabled.
+func (a AdaptiveBatchSize) enabled() bool {
+	return a.TargetLatency > 0
+}
+
+// clamp returns the input batch size bounded to [Min, Max].
+func (a AdaptiveBatchSize) clamp(size int) int {
+	if a.Max > 0 && size > a.Max {
+		size = a.Max
+	}
+	if size < a.Min {
+		size = a.Min
+	}
+	if size < 1 {
+		size = 1
+	}
+
+	return size
+}
+
+// adaptiveBatchSizer tracks the write requests latency and adjusts the batch size accordingly.
+type adaptiveBatchSizer struct {
+	cfg AdaptiveBatchSize
+
+	mx         sync.Mutex
+	size       int
+	latencySum time.Duration
+	requests   int
+}
+
+func newAdaptiveBatchSizer(cfg AdaptiveBatchSize, initialSize int) *adaptiveBatchSizer {
+	return &adaptiveBatchSizer{cfg: cfg, size: cfg.clamp(initialSize)}
+}
+
+// batchSize returns the current batch size.
+func (s *adaptiveBatchSizer) batchSize() int {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	return s.size
+}
+
+// observe tracks the latency of a write request.
+func (s *adaptiveBatchSizer) observe(latency time.Duration) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.latencySum += latency
+	s.requests++
+}
+
+// adjust updates the batch size given the latency of the write requests observed since the
+// previous adjustment, and returns the new batch size.
+func (s *adaptiveBatchSizer) adjust() int {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if s.requests == 0 {
+		return s.size
+	}
+
+	avg := s.latencySum / time.Duration(s.requests)
+	s.latencySum, s.requests = 0, 0
+
+	switch {
+	case avg > s.cfg.TargetLatency:
+		s.size = s.cfg.clamp(s.size / 2)
+	case avg < s.cfg.TargetLatency/2:
+		s.size = s.cfg.clamp(s.size * 2)
+	}
+
+	return s.size
+}
@@ -0,0 +1,102 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdaptiveBatchSizer(t *testing.T) {
+	s := newAdaptiveBatchSizer(AdaptiveBatchSize{TargetLatency: 100 * time.Millisecond, Min: 10, Max: 100}, 40)
+	assert.Equal(t, 40, s.batchSize())
+
+	// The batch size should not change without observations.
+	assert.Equal(t, 40, s.adjust())
+
+	// Slow requests should shrink the batch size, down to the min.
+	for _, expected := range []int{20, 10, 10} {
+		s.observe(150 * time.Millisecond)
+		s.observe(100 * time.Millisecond)
+		assert.Equal(t, expected, s.adjust())
+	}
+
+	// Requests within the target latency should not change the batch size.
+	s.observe(80 * time.Millisecond)
+	assert.Equal(t, 10, s.adjust())
+
+	// Fast requests should grow the batch size, up to the max.
+	for _, expected := range []int{20, 40, 80, 100, 100} {
+		s.observe(10 * time.Millisecond)
+		assert.Equal(t, expected, s.adjust())
+	}
+	assert.Equal(t, 100, s.batchSize())
+
+	// The initial batch size should be bounded too.
+	assert.Equal(t, 100, newAdaptiveBatchSizer(AdaptiveBatchSize{TargetLatency: time.Second, Min: 10, Max: 100}, 1000).batchSize())
+}
+
+func TestWriteClient_ShouldAdaptBatchSizeToWriteLatency(t *testing.T) {
+	var (
+		latencyMx sync.Mutex
+		latency   time.Duration
+	)
+
+	setLatency := func(value time.Duration) {
+		latencyMx.Lock()
+		latency = value
+		latencyMx.Unlock()
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		latencyMx.Lock()
+		value := latency
+		latencyMx.Unlock()
+
+		time.Sleep(value)
+	}))
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	client := NewWriteClient(WriteClientConfig{
+		URL:               *serverURL,
+		UserID:            "user-1",
+		SeriesCount:       64,
+		WriteInterval:     10 * time.Second,
+		WriteTimeout:      time.Second,
+		WriteConcurrency:  64,
+		WriteBatchSize:    16,
+		AdaptiveBatchSize: AdaptiveBatchSize{TargetLatency: 40 * time.Millisecond, Min: 4, Max: 64},
+	}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+	writeSeries := func(expectedBatchSize, expectedBatches int) {
+		t.Helper()
+
+		assert.Equal(t, 64, client.writeSeries())
+		assert.Equal(t, float64(expectedBatchSize), testutil.ToFloat64(client.writeBatchSize))
+		assert.Equal(t, float64(expectedBatches), testutil.ToFloat64(client.writeBatchesLastInterval))
+	}
+
+	// Slow responses should shrink the batch size.
+	setLatency(100 * time.Millisecond)
+	writeSeries(16, 4)
+	writeSeries(8, 8)
+	writeSeries(4, 16)
+
+	// Fast responses should grow the batch size.
+	setLatency(0)
+	writeSeries(4, 16)
+	writeSeries(8, 8)
+	writeSeries(16, 4)
+	assert.Equal(t, 32, client.batchSizer.batchSize())
+}
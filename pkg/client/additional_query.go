@@ -0,0 +1,142 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AdditionalQuery is a query to run in addition to the default one.
+type AdditionalQuery struct {
+	Query string
+
+	// Timeout overrides the configured query timeout, if greater than 0.
+	Timeout time.Duration
+
+	// Expected, if set, is the expected value of the query results, which are verified.
+	Expected *ExpectedValue
+
+	// Window, if greater than 0, expects each result sample to be the average of the expected
+	// values at the write timestamps within the window ending at the sample timestamp, like an
+	// avg_over_time() query with the same range does. Unlike point queries, the result of these
+	// queries at a step larger than the write interval depends on the skipped samples too.
+	Window time.Duration
+}
+
+// timeout returns the timeout of the query, given the configured default one.
+func (q AdditionalQuery) timeout(defaultTimeout time.Duration) time.Duration {
+	if q.Timeout > 0 {
+		return q.Timeout
+	}
+
+	return defaultTimeout
+}
+
+// ExpectedValue is the expected value of an additional query, like a recording rule derived
+// from the generated series. The expected value is Constant + SumFactor * sum, where sum is
+// the expected sum of the sine wave series at each timestamp.
+type ExpectedValue struct {
+	Constant  float64
+	SumFactor float64
+}
+
+// value returns the expected value, given the expected sum of sine wave series.
+func (e ExpectedValue) value(sum float64) float64 {
+	return e.Constant + e.SumFactor*sum
+}
+
+// parseExpectedValue parses an expected value expression, which can either be a constant
+// (e.g. "42"), the sum of sine wave series ("sum"), or the sum multiplied by a constant
+// (e.g. "0.5*sum").
+func parseExpectedValue(s string) (*ExpectedValue, error) {
+	s = strings.TrimSpace(s)
+
+	if s == "sum" {
+		return &ExpectedValue{SumFactor: 1}, nil
+	}
+
+	if strings.HasSuffix(s, "*sum") {
+		factor, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(s, "*sum")), 64)
+		if err != nil {
+			return nil, err
+		}
+		return &ExpectedValue{SumFactor: factor}, nil
+	}
+
+	constant, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &ExpectedValue{Constant: constant}, nil
+}
+
+// ExpandAdditionalQueryTemplate generates count queries from the input template, replacing
+// the {i} placeholder with a number from 1 to count.
+func ExpandAdditionalQueryTemplate(template string, count int) []string {
+	queries := make([]string, 0, count)
+	for i := 1; i <= count; i++ {
+		queries = append(queries, strings.ReplaceAll(template, "{i}", strconv.Itoa(i)))
+	}
+
+	return queries
+}
+
+// ParseAdditionalQuery parses an additional query. The query can optionally be prefixed by a
+// comma-separated list of options enclosed in square brackets, e.g. "[timeout=1m]sum(up)".
+// Supported options are:
+// - timeout: the query timeout, overriding the default one.
+// - expected: the expected value of the query results, which are verified (see parseExpectedValue).
+// - window: the range over which the query averages the expected value (see AdditionalQuery.Window).
+func ParseAdditionalQuery(s string) (AdditionalQuery, error) {
+	q := AdditionalQuery{Query: s}
+
+	// PromQL expressions can't start with "[", so there's no ambiguity.
+	if !strings.HasPrefix(s, "[") {
+		return q, nil
+	}
+
+	end := strings.Index(s, "]")
+	if end < 0 {
+		return q, fmt.Errorf("unterminated options in additional query %q", s)
+	}
+
+	q.Query = strings.TrimSpace(s[end+1:])
+	if q.Query == "" {
+		return q, fmt.Errorf("empty additional query %q", s)
+	}
+
+	for _, option := range strings.Split(s[1:end], ",") {
+		parts := strings.SplitN(strings.TrimSpace(option), "=", 2)
+		if len(parts) != 2 {
+			return q, fmt.Errorf("invalid option %q in additional query %q", option, s)
+		}
+
+		name, value := parts[0], parts[1]
+
+		switch name {
+		case "timeout":
+			timeout, err := time.ParseDuration(value)
+			if err != nil {
+				return q, fmt.Errorf("invalid timeout in additional query %q: %w", s, err)
+			}
+			q.Timeout = timeout
+		case "expected":
+			expected, err := parseExpectedValue(value)
+			if err != nil {
+				return q, fmt.Errorf("invalid expected value in additional query %q: %w", s, err)
+			}
+			q.Expected = expected
+		case "window":
+			window, err := time.ParseDuration(value)
+			if err != nil || window <= 0 {
+				return q, fmt.Errorf("invalid window in additional query %q: expected a positive duration", s)
+			}
+			q.Window = window
+		default:
+			return q, fmt.Errorf("unknown option %q in additional query %q", name, s)
+		}
+	}
+
+	return q, nil
+}
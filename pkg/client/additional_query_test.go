@@ -0,0 +1,90 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAdditionalQuery(t *testing.T) {
+	tests := map[string]struct {
+		input       string
+		expected    AdditionalQuery
+		expectedErr string
+	}{
+		"query without options": {
+			input:    `sum(rate(cortex_load_generator_sine_wave{wave=~"1|2"}[5m]))`,
+			expected: AdditionalQuery{Query: `sum(rate(cortex_load_generator_sine_wave{wave=~"1|2"}[5m]))`},
+		},
+		"query with timeout": {
+			input:    "[timeout=2m] sum(cortex_load_generator_sine_wave)",
+			expected: AdditionalQuery{Query: "sum(cortex_load_generator_sine_wave)", Timeout: 2 * time.Minute},
+		},
+		"query with expected constant": {
+			input:    "[expected=42]count(cortex_load_generator_sine_wave)",
+			expected: AdditionalQuery{Query: "count(cortex_load_generator_sine_wave)", Expected: &ExpectedValue{Constant: 42}},
+		},
+		"query with expected sum": {
+			input:    "[timeout=1m,expected=sum]sum:cortex_load_generator_sine_wave",
+			expected: AdditionalQuery{Query: "sum:cortex_load_generator_sine_wave", Timeout: time.Minute, Expected: &ExpectedValue{SumFactor: 1}},
+		},
+		"query with expected sum multiplied by a constant": {
+			input:    "[expected=0.5*sum]avg:cortex_load_generator_sine_wave",
+			expected: AdditionalQuery{Query: "avg:cortex_load_generator_sine_wave", Expected: &ExpectedValue{SumFactor: 0.5}},
+		},
+		"query with expected value averaged over a window": {
+			input:    "[expected=sum,window=5m]sum(avg_over_time(cortex_load_generator_sine_wave[5m]))",
+			expected: AdditionalQuery{Query: "sum(avg_over_time(cortex_load_generator_sine_wave[5m]))", Expected: &ExpectedValue{SumFactor: 1}, Window: 5 * time.Minute},
+		},
+		"invalid window": {
+			input:       "[expected=sum,window=0s]up",
+			expectedErr: "invalid window",
+		},
+		"invalid expected value": {
+			input:       "[expected=max]up",
+			expectedErr: "invalid expected value",
+		},
+		"unterminated options": {
+			input:       "[timeout=2m sum(cortex_load_generator_sine_wave)",
+			expectedErr: "unterminated options",
+		},
+		"empty query": {
+			input:       "[timeout=2m]",
+			expectedErr: "empty additional query",
+		},
+		"invalid timeout": {
+			input:       "[timeout=abc]up",
+			expectedErr: "invalid timeout",
+		},
+		"unknown option": {
+			input:       "[foo=bar]up",
+			expectedErr: "unknown option",
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			actual, err := ParseAdditionalQuery(testData.input)
+			if testData.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), testData.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, testData.expected, actual)
+		})
+	}
+}
+
+func TestExpandAdditionalQueryTemplate(t *testing.T) {
+	assert.Equal(t, []string{
+		`sum(rate(cortex_load_generator_sine_wave{wave="1"}[5m]))`,
+		`sum(rate(cortex_load_generator_sine_wave{wave="2"}[5m]))`,
+		`sum(rate(cortex_load_generator_sine_wave{wave="3"}[5m]))`,
+	}, ExpandAdditionalQueryTemplate(`sum(rate(cortex_load_generator_sine_wave{wave="{i}"}[5m]))`, 3))
+
+	assert.Empty(t, ExpandAdditionalQueryTemplate(`up{i="{i}"}`, 0))
+}
@@ -0,0 +1,115 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Circuit breaker states, exported as the value of the circuit state metric.
+const (
+	circuitClosed   = 0
+	circuitOpen     = 1
+	circuitHalfOpen = 2
+)
+
+// CircuitBreaker configures when writes are paused to let an overwhelmed remote endpoint recover.
+type CircuitBreaker struct {
+	// FailureThreshold is the number of consecutive failed write requests opening the circuit,
+	// which pauses writes for Cooldown. 0 to disable the circuit breaker.
+	FailureThreshold int
+	Cooldown         time.Duration
+
+	// HalfOpenConcurrency is the write concurrency once the cooldown expired, until a write
+	// request succeeds and closes the circuit again. A failure re-opens the circuit.
+	HalfOpenConcurrency int
+}
+
+// enabled returns whether the circuit breaker is enabled.
+func (cb CircuitBreaker) enabled() bool {
+	return cb.FailureThreshold > 0
+}
+
+// circuitBreaker tracks the state of the circuit from the outcome of write requests.
+type circuitBreaker struct {
+	cfg CircuitBreaker
+
+	mx                  sync.Mutex
+	open                bool
+	openedAt            time.Time
+	consecutiveFailures int
+}
+
+func newCircuitBreaker(cfg CircuitBreaker) *circuitBreaker {
+	return &circuitBreaker{cfg: cfg}
+}
+
+// state returns the state of the circuit at now. An open circuit becomes half-open once the
+// cooldown expired.
+func (cb *circuitBreaker) state(now time.Time) int {
+	cb.mx.Lock()
+	defer cb.mx.Unlock()
+
+	return cb.stateLocked(now)
+}
+
+func (cb *circuitBreaker) stateLocked(now time.Time) int {
+	switch {
+	case !cb.open:
+		return circuitClosed
+	case now.Sub(cb.openedAt) < cb.cfg.Cooldown:
+		return circuitOpen
+	default:
+		return circuitHalfOpen
+	}
+}
+
+// concurrency returns the write concurrency to honor in the input state.
+func (cb *circuitBreaker) concurrency(state, defaultConcurrency int) int {
+	if state != circuitHalfOpen || cb.cfg.HalfOpenConcurrency <= 0 || cb.cfg.HalfOpenConcurrency >= defaultConcurrency {
+		return defaultConcurrency
+	}
+
+	return cb.cfg.HalfOpenConcurrency
+}
+
+// recordResult updates the circuit with the outcome of a write request completed at now.
+func (cb *circuitBreaker) recordResult(err error, now time.Time) {
+	cb.mx.Lock()
+	defer cb.mx.Unlock()
+
+	if !isCircuitBreakerFailure(err) {
+		cb.consecutiveFailures = 0
+		cb.open = false
+		return
+	}
+
+	cb.consecutiveFailures++
+
+	switch cb.stateLocked(now) {
+	case circuitHalfOpen:
+		// The remote endpoint hasn't recovered yet.
+		cb.openedAt = now
+	case circuitClosed:
+		if cb.consecutiveFailures >= cb.cfg.FailureThreshold {
+			cb.open = true
+			cb.openedAt = now
+		}
+	}
+}
+
+// isCircuitBreakerFailure returns whether err signals the remote endpoint is overwhelmed.
+// Rejected requests (HTTP 4xx) are not, except when rate limited.
+func isCircuitBreakerFailure(err error) bool {
+	var statusErr httpStatusError
+
+	switch {
+	case err == nil:
+		return false
+	case errors.As(err, &statusErr) && statusErr.statusCode/100 == 4:
+		return statusErr.statusCode == http.StatusTooManyRequests
+	default:
+		return true
+	}
+}
@@ -0,0 +1,115 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Date(2023, 6, 29, 0, 0, 0, 0, time.UTC)
+	failure := errors.New("connection refused")
+
+	cb := newCircuitBreaker(CircuitBreaker{FailureThreshold: 3, Cooldown: time.Minute, HalfOpenConcurrency: 2})
+	assert.Equal(t, circuitClosed, cb.state(now))
+
+	// A success resets the consecutive failures.
+	cb.recordResult(failure, now)
+	cb.recordResult(failure, now)
+	cb.recordResult(nil, now)
+	cb.recordResult(failure, now)
+	cb.recordResult(failure, now)
+	assert.Equal(t, circuitClosed, cb.state(now))
+
+	// Rejected requests don't count as failures.
+	cb.recordResult(httpStatusError{statusCode: http.StatusBadRequest}, now)
+	assert.Equal(t, circuitClosed, cb.state(now))
+
+	// Consecutive failures should open the circuit.
+	cb.recordResult(failure, now)
+	cb.recordResult(httpStatusError{statusCode: http.StatusTooManyRequests}, now)
+	cb.recordResult(httpStatusError{statusCode: http.StatusInternalServerError}, now)
+	assert.Equal(t, circuitOpen, cb.state(now))
+	assert.Equal(t, circuitOpen, cb.state(now.Add(59*time.Second)))
+
+	// The circuit should half-open after the cooldown, reducing the concurrency.
+	now = now.Add(time.Minute)
+	assert.Equal(t, circuitHalfOpen, cb.state(now))
+	assert.Equal(t, 2, cb.concurrency(circuitHalfOpen, 10))
+	assert.Equal(t, 1, cb.concurrency(circuitHalfOpen, 1))
+	assert.Equal(t, 10, cb.concurrency(circuitClosed, 10))
+
+	// A failure while half-open should re-open the circuit for another cooldown.
+	cb.recordResult(failure, now)
+	assert.Equal(t, circuitOpen, cb.state(now.Add(59*time.Second)))
+
+	// A success while half-open should close the circuit.
+	now = now.Add(time.Minute)
+	assert.Equal(t, circuitHalfOpen, cb.state(now))
+	cb.recordResult(nil, now)
+	assert.Equal(t, circuitClosed, cb.state(now))
+
+	// The failures count should start from scratch.
+	cb.recordResult(failure, now)
+	assert.Equal(t, circuitClosed, cb.state(now))
+}
+
+func TestWriteClient_ShouldPauseWritesWhenCircuitBreakerOpens(t *testing.T) {
+	var (
+		requestsMx sync.Mutex
+		requests   int
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestsMx.Lock()
+		requests++
+		requestsMx.Unlock()
+
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	client := NewWriteClient(WriteClientConfig{
+		URL:              *serverURL,
+		UserID:           "user-1",
+		SeriesCount:      2,
+		WriteInterval:    time.Second,
+		WriteTimeout:     time.Second,
+		WriteConcurrency: 1,
+		WriteBatchSize:   1,
+		CircuitBreaker:   CircuitBreaker{FailureThreshold: 2, Cooldown: 100 * time.Millisecond, HalfOpenConcurrency: 1},
+	}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+	client.writeSeries()
+	assert.Equal(t, float64(circuitClosed), testutil.ToFloat64(client.circuitState))
+
+	// The next write should be skipped, because the circuit is open.
+	client.writeSeries()
+	assert.Equal(t, float64(circuitOpen), testutil.ToFloat64(client.circuitState))
+
+	requestsMx.Lock()
+	assert.Equal(t, 2, requests)
+	requestsMx.Unlock()
+
+	// Writes should resume once the circuit half-opens.
+	time.Sleep(100 * time.Millisecond)
+	client.writeSeries()
+	assert.Equal(t, float64(circuitHalfOpen), testutil.ToFloat64(client.circuitState))
+
+	requestsMx.Lock()
+	assert.Equal(t, 4, requests)
+	requestsMx.Unlock()
+}
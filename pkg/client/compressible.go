@@ -0,0 +1,108 @@
+package client
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+
+	"github.com/golang/snappy"
+)
+
+const (
+	// Number of samples generated to calibrate the compressible values run length.
+	compressibleCalibrationSamples = 10000
+
+	// Max number of samples in a single constant run.
+	compressibleMaxRunLength = 1024
+)
+
+// CompressibleValues generates values made of constant runs followed by random jumps,
+// with the run length tuned to approximate a target snappy compression ratio. Values are
+// a function of the timestamp and seed alone, so they can be reproduced to verify them.
+type CompressibleValues struct {
+	seed uint64
+
+	// The duration (in milliseconds) of each constant run.
+	runDuration int64
+}
+
+// NewCompressibleValues returns values approximating the target compression ratio when
+// a new sample is generated every interval. Sample timestamps have a millisecond precision,
+// so intervals shorter than 1ms are considered 1ms.
+func NewCompressibleValues(targetRatio float64, interval time.Duration, seed int64) *CompressibleValues {
+	runLength := calibrateCompressibleRunLength(targetRatio, uint64(seed))
+
+	intervalMillis := interval.Milliseconds()
+	if intervalMillis < 1 {
+		intervalMillis = 1
+	}
+
+	return &CompressibleValues{
+		seed:        uint64(seed),
+		runDuration: intervalMillis * int64(runLength),
+	}
+}
+
+// Value returns the value at t.
+func (v *CompressibleValues) Value(t time.Time) float64 {
+	run := t.UnixMilli() / v.runDuration
+	if t.UnixMilli() < 0 && t.UnixMilli()%v.runDuration != 0 {
+		run--
+	}
+
+	return hashToUnitValue(v.seed, uint64(run))
+}
+
+// calibrateCompressibleRunLength returns the run length whose compression ratio is the
+// closest to the target one.
+func calibrateCompressibleRunLength(targetRatio float64, seed uint64) int {
+	bestRunLength, bestDelta := 1, math.Inf(1)
+
+	for runLength := 1; runLength <= compressibleMaxRunLength; runLength++ {
+		values := make([]float64, 0, compressibleCalibrationSamples)
+		for i := 0; i < compressibleCalibrationSamples; i++ {
+			values = append(values, hashToUnitValue(seed, uint64(i/runLength)))
+		}
+
+		ratio := snappyCompressionRatio(values)
+		if delta := math.Abs(ratio - targetRatio); delta < bestDelta {
+			bestRunLength, bestDelta = runLength, delta
+		}
+
+		// The ratio grows with the run length, so there's no need to look further.
+		if ratio > targetRatio {
+			break
+		}
+	}
+
+	return bestRunLength
+}
+
+// snappyCompressionRatio returns the ratio between the raw and snappy compressed size
+// of the values, encoded as a sequence of float64.
+func snappyCompressionRatio(values []float64) float64 {
+	raw := make([]byte, 8*len(values))
+	for i, value := range values {
+		binary.LittleEndian.PutUint64(raw[8*i:], math.Float64bits(value))
+	}
+
+	return float64(len(raw)) / float64(len(snappy.Encode(nil, raw)))
+}
+
+// hashToUnitValue deterministically maps the input seed and key to a value in [-1, 1].
+func hashToUnitValue(seed, key uint64) float64 {
+	return 2*hashToUnitInterval(seed, key) - 1
+}
+
+// hashToUnitInterval deterministically maps the input seed and key to a value in [0, 1).
+func hashToUnitInterval(seed, key uint64) float64 {
+	return float64(splitmix64(seed^splitmix64(key))>>11) / (1 << 53)
+}
+
+// splitmix64 is a fast, well distributed, 64 bit hash function.
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
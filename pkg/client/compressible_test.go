@@ -0,0 +1,63 @@
+package client
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressibleValues_ShouldApproximateTargetCompressionRatio(t *testing.T) {
+	const interval = 10 * time.Second
+
+	for _, targetRatio := range []float64{2, 4, 8, 16} {
+		t.Run(fmt.Sprintf("target ratio: %.0f", targetRatio), func(t *testing.T) {
+			values := NewCompressibleValues(targetRatio, interval, 1)
+
+			generated := make([]float64, 0, 5000)
+			for ts := time.UnixMilli(0); len(generated) < cap(generated); ts = ts.Add(interval) {
+				generated = append(generated, values.Value(ts))
+			}
+
+			assert.InEpsilon(t, targetRatio, snappyCompressionRatio(generated), 0.2)
+		})
+	}
+}
+
+func TestCompressibleValues_ShouldBeReproducibleFromSeed(t *testing.T) {
+	const (
+		interval  = 10 * time.Second
+		numSeries = 2
+	)
+
+	cfg := ValueConfig{Compressible: NewCompressibleValues(4, interval, 1)}
+
+	var samples []model.SamplePair
+	for ts := time.UnixMilli(0); len(samples) < 100; ts = ts.Add(interval) {
+		value := NewCompressibleValues(4, interval, 1).Value(ts)
+		assert.Equal(t, value, cfg.value(ts, 1))
+		assert.GreaterOrEqual(t, value, -1.0)
+		assert.LessOrEqual(t, value, 1.0)
+
+		samples = append(samples, newSamplePair(ts, numSeries*value))
+	}
+
+	assert.NoError(t, verifySineWaveSamples(samples, numSeries, SeriesSchedule{}, interval, cfg, nil))
+	assert.Error(t, verifySineWaveSamples(samples, numSeries, SeriesSchedule{}, interval, ValueConfig{Compressible: NewCompressibleValues(4, interval, 2)}, nil))
+}
+
+func TestCompressibleValues_ShouldSupportSubMillisecondIntervals(t *testing.T) {
+	for _, interval := range []time.Duration{0, time.Microsecond, 999 * time.Microsecond} {
+		t.Run(fmt.Sprintf("interval: %s", interval), func(t *testing.T) {
+			values := NewCompressibleValues(4, interval, 1)
+
+			// The interval should be considered 1ms, so values shouldn't degenerate.
+			assert.Equal(t, NewCompressibleValues(4, time.Millisecond, 1), values)
+			for ts := time.UnixMilli(-10); ts.Before(time.UnixMilli(10)); ts = ts.Add(time.Millisecond) {
+				assert.Equal(t, NewCompressibleValues(4, time.Millisecond, 1).Value(ts), values.Value(ts))
+			}
+		})
+	}
+}
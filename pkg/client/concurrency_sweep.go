@@ -0,0 +1,41 @@
+package client
+
+import "time"
+
+// ConcurrencySweep configures how the write concurrency increases over time, to find the
+// concurrency level at which the remote endpoint throughput saturates.
+type ConcurrencySweep struct {
+	// StartTime is the reference time of the sweep.
+	StartTime time.Time
+
+	// The write concurrency starts from Min and is increased by Step every StepDuration
+	// since StartTime, up to Max. 0 StepDuration to disable the sweep.
+	Min          int
+	Max          int
+	Step         int
+	StepDuration time.Duration
+}
+
+// enabled returns whether the sweep is enabled.
+func (s ConcurrencySweep) enabled() bool {
+	return s.StepDuration > 0 && s.Step > 0 && s.Min > 0
+}
+
+// concurrency returns the write concurrency at t, or the input default if the sweep is disabled.
+func (s ConcurrencySweep) concurrency(t time.Time, defaultConcurrency int) int {
+	if !s.enabled() {
+		return defaultConcurrency
+	}
+
+	elapsed := t.Sub(s.StartTime)
+	if elapsed < 0 {
+		return s.Min
+	}
+
+	concurrency := s.Min + s.Step*int(elapsed/s.StepDuration)
+	if s.Max > 0 && concurrency > s.Max {
+		return s.Max
+	}
+
+	return concurrency
+}
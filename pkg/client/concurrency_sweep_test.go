@@ -0,0 +1,113 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrencySweep_Concurrency(t *testing.T) {
+	start := time.Unix(1000, 0)
+
+	tests := map[string]struct {
+		sweep    ConcurrencySweep
+		expected map[time.Duration]int
+	}{
+		"disabled": {
+			sweep:    ConcurrencySweep{StartTime: start, Min: 1, Step: 1},
+			expected: map[time.Duration]int{0: 10, time.Hour: 10},
+		},
+		"should advance the concurrency every step duration": {
+			sweep: ConcurrencySweep{StartTime: start, Min: 2, Step: 3, StepDuration: time.Minute},
+			expected: map[time.Duration]int{
+				-time.Minute:                   2,
+				0:                              2,
+				59 * time.Second:               2,
+				time.Minute:                    5,
+				2*time.Minute + 30*time.Second: 8,
+				10 * time.Minute:               32,
+			},
+		},
+		"should honor the max concurrency": {
+			sweep: ConcurrencySweep{StartTime: start, Min: 1, Max: 4, Step: 2, StepDuration: time.Minute},
+			expected: map[time.Duration]int{
+				0:                1,
+				time.Minute:      3,
+				2 * time.Minute:  4,
+				10 * time.Minute: 4,
+			},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			for elapsed, expected := range testData.expected {
+				assert.Equal(t, expected, testData.sweep.concurrency(start.Add(elapsed), 10), "elapsed: %s", elapsed)
+			}
+		})
+	}
+}
+
+func TestWriteClient_ShouldHonorConcurrencySweep(t *testing.T) {
+	const stepDuration = time.Hour
+
+	var inflight, maxInflight int64
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt64(&inflight, 1)
+		defer atomic.AddInt64(&inflight, -1)
+
+		for {
+			prev := atomic.LoadInt64(&maxInflight)
+			if current <= prev || atomic.CompareAndSwapInt64(&maxInflight, prev, current) {
+				break
+			}
+		}
+
+		// Slow down requests, so that they overlap.
+		time.Sleep(20 * time.Millisecond)
+	}))
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	for step, expectedConcurrency := range []int{1, 3, 5} {
+		atomic.StoreInt64(&maxInflight, 0)
+
+		// Move the sweep start back in time, to simulate the steps advancing. The sweep is
+		// evaluated at the write interval timestamp.
+		client := NewWriteClient(WriteClientConfig{
+			URL:              *serverURL,
+			UserID:           "user-1",
+			SeriesCount:      20,
+			WriteInterval:    10 * time.Second,
+			WriteTimeout:     time.Second,
+			WriteConcurrency: 10,
+			WriteBatchSize:   1,
+			ConcurrencySweep: ConcurrencySweep{
+				StartTime:    alignTimestampToInterval(time.Now(), 10*time.Second).Add(-time.Duration(step) * stepDuration),
+				Min:          1,
+				Step:         2,
+				StepDuration: stepDuration,
+			},
+		}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+		require.Equal(t, 20, client.writeSeries())
+
+		assert.Equal(t, int64(expectedConcurrency), atomic.LoadInt64(&maxInflight), "step: %d", step)
+		assert.Equal(t, float64(expectedConcurrency), testutil.ToFloat64(client.writeConcurrency), "step: %d", step)
+		assert.Equal(t, 1, testutil.CollectAndCount(client.sweepSamplesPerSecond), "step: %d", step)
+		assert.Greater(t, testutil.ToFloat64(client.sweepSamplesPerSecond.WithLabelValues(strconv.Itoa(expectedConcurrency))), 0.0, "step: %d", step)
+	}
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// Dimension is a label whose values are combined with the values of the other dimensions, so
+// that series are the cross-product of all dimensions, e.g. 10 regions × 20 hosts × 5 jobs.
+type Dimension struct {
+	Name string
+	Size int
+}
+
+// ParseDimensions parses a comma-separated list of dimensions in the format name:size,
+// e.g. "region:10,host:20,job:5".
+func ParseDimensions(s string) ([]Dimension, error) {
+	var dimensions []Dimension
+
+	for _, part := range strings.Split(s, ",") {
+		parts := strings.SplitN(strings.TrimSpace(part), ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid dimension %q, expected name:size", part)
+		}
+
+		size, err := strconv.Atoi(parts[1])
+		if err != nil || size < 1 {
+			return nil, fmt.Errorf("invalid size of dimension %q, expected a positive integer", part)
+		}
+
+		dimensions = append(dimensions, Dimension{Name: parts[0], Size: size})
+	}
+
+	return dimensions, nil
+}
+
+// DimensionsCardinality returns the number of series in the cross-product of the input dimensions.
+func DimensionsCardinality(dimensions []Dimension) int {
+	cardinality := 1
+	for _, d := range dimensions {
+		cardinality *= d.Size
+	}
+
+	return cardinality
+}
+
+// generateDimensionLabels returns the dimension labels of the series with the input ID. Series
+// enumerate the cross-product of dimensions in order, with the last dimension changing first,
+// and wrap around once all combinations have been generated.
+func generateDimensionLabels(seriesID int, dimensions []Dimension) []*prompb.Label {
+	if len(dimensions) == 0 {
+		return nil
+	}
+
+	labels := make([]*prompb.Label, len(dimensions))
+	idx := (seriesID - 1) % DimensionsCardinality(dimensions)
+
+	for j := len(dimensions) - 1; j >= 0; j-- {
+		labels[j] = &prompb.Label{
+			Name:  dimensions[j].Name,
+			Value: fmt.Sprintf("%s-%d", dimensions[j].Name, idx%dimensions[j].Size),
+		}
+		idx /= dimensions[j].Size
+	}
+
+	return labels
+}
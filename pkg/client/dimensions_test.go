@@ -0,0 +1,87 @@
+package client
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDimensions(t *testing.T) {
+	tests := map[string]struct {
+		input       string
+		expected    []Dimension
+		expectedErr string
+	}{
+		"single dimension": {
+			input:    "region:10",
+			expected: []Dimension{{Name: "region", Size: 10}},
+		},
+		"multiple dimensions": {
+			input:    "region:10, host:20,job:5",
+			expected: []Dimension{{Name: "region", Size: 10}, {Name: "host", Size: 20}, {Name: "job", Size: 5}},
+		},
+		"missing size": {
+			input:       "region",
+			expectedErr: "invalid dimension",
+		},
+		"invalid size": {
+			input:       "region:0",
+			expectedErr: "invalid size of dimension",
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			actual, err := ParseDimensions(testData.input)
+			if testData.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), testData.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, testData.expected, actual)
+		})
+	}
+}
+
+func TestGenerateSineWaveSeries_WithDimensions(t *testing.T) {
+	dimensions := []Dimension{{Name: "region", Size: 2}, {Name: "host", Size: 3}, {Name: "job", Size: 4}}
+	cardinality := DimensionsCardinality(dimensions)
+	require.Equal(t, 24, cardinality)
+
+	ts, err := time.Parse(time.RFC3339, "2023-06-29T00:00:10Z")
+	require.NoError(t, err)
+
+	series := generateSineWaveSeries(ts, WriteClientConfig{SeriesCount: cardinality, Dimensions: dimensions})
+	require.Len(t, series, cardinality)
+
+	combinations := map[[3]string]struct{}{}
+	for _, s := range series {
+		labels := map[string]string{}
+		for _, l := range s.Labels {
+			labels[l.Name] = l.Value
+		}
+
+		combinations[[3]string{labels["region"], labels["host"], labels["job"]}] = struct{}{}
+	}
+
+	// Each series should get a distinct combination of dimension values.
+	assert.Len(t, combinations, cardinality)
+	for region := 0; region < 2; region++ {
+		for host := 0; host < 3; host++ {
+			for job := 0; job < 4; job++ {
+				assert.Contains(t, combinations, [3]string{"region-" + strconv.Itoa(region), "host-" + strconv.Itoa(host), "job-" + strconv.Itoa(job)})
+			}
+		}
+	}
+
+	// Combinations should be enumerated in order, with the last dimension changing first.
+	assert.Equal(t, "job-0", generateDimensionLabels(1, dimensions)[2].Value)
+	assert.Equal(t, "job-1", generateDimensionLabels(2, dimensions)[2].Value)
+	assert.Equal(t, "host-1", generateDimensionLabels(5, dimensions)[1].Value)
+	assert.Equal(t, "region-1", generateDimensionLabels(13, dimensions)[0].Value)
+}
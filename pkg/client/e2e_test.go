@@ -0,0 +1,130 @@
+package client
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pracucci/cortex-load-generator/pkg/clienttest"
+)
+
+func TestWriteAndQueryClients_EndToEnd(t *testing.T) {
+	const (
+		numSeries     = 5
+		numIntervals  = 8
+		writeInterval = 100 * time.Millisecond
+	)
+
+	backend := clienttest.NewBackend()
+	t.Cleanup(backend.Close)
+
+	pushURL, err := url.Parse(backend.PushURL())
+	require.NoError(t, err)
+
+	writeClient := NewWriteClient(WriteClientConfig{
+		URL:              *pushURL,
+		UserID:           "user-1",
+		SeriesCount:      numSeries,
+		WriteInterval:    writeInterval,
+		WriteTimeout:     time.Second,
+		WriteConcurrency: 1,
+		WriteBatchSize:   100,
+	}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+	// Push a few intervals, waiting for the next one after each write.
+	firstInterval := alignTimestampToInterval(time.Now(), writeInterval)
+	for i := 0; i < numIntervals; i++ {
+		require.Equal(t, numSeries, writeClient.writeSeries())
+		time.Sleep(time.Until(alignTimestampToInterval(time.Now(), writeInterval).Add(writeInterval)))
+	}
+
+	require.Equal(t, numSeries, backend.SeriesCount("user-1"))
+
+	tests := map[string]QueryClientConfig{
+		"default query": {},
+		"streaming default query": {
+			StreamDefaultQuery: true,
+		},
+		"raw series": {
+			VerifyRawSeries: true,
+		},
+	}
+
+	for testName, cfg := range tests {
+		t.Run(testName, func(t *testing.T) {
+			cfg.URL = backend.URL()
+			cfg.UserID = "user-1"
+			cfg.QueryTimeout = time.Second
+			cfg.QueryMaxAge = time.Hour
+			cfg.ExpectedSeries = numSeries
+			cfg.ExpectedWriteInterval = writeInterval
+
+			queryClient := NewQueryClient(cfg, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+			// Query since the first pushed interval.
+			queryClient.startTime = firstInterval.Add(-2 * writeInterval)
+
+			require.True(t, queryClient.runQueries())
+			assert.Equal(t, float64(1), testutil.ToFloat64(queryClient.resultsComparedTotal.WithLabelValues(comparisonSuccess, queryClient.defaultQuery)))
+			assert.Equal(t, float64(0), testutil.ToFloat64(queryClient.resultsComparedTotal.WithLabelValues(comparisonFailed, queryClient.defaultQuery)))
+		})
+	}
+}
+
+func TestWriteAndQueryClients_EndToEnd_ShouldVerifyQueryResultsAfterBursts(t *testing.T) {
+	const (
+		numSeries     = 5
+		numIntervals  = 8
+		writeInterval = 100 * time.Millisecond
+	)
+
+	backend := clienttest.NewBackend()
+	t.Cleanup(backend.Close)
+
+	pushURL, err := url.Parse(backend.PushURL())
+	require.NoError(t, err)
+
+	// The series count doubles for 2 intervals every 4 intervals, so the query range spans the end
+	// of a burst, after which the burst series must not be returned anymore.
+	firstInterval := alignTimestampToInterval(time.Now(), writeInterval)
+	schedule := SeriesSchedule{StartTime: firstInterval, BurstInterval: 4 * writeInterval, BurstDuration: 2 * writeInterval, BurstMultiplier: 2}
+
+	writeClient := NewWriteClient(WriteClientConfig{
+		URL:              *pushURL,
+		UserID:           "user-1",
+		SeriesCount:      numSeries,
+		Schedule:         schedule,
+		WriteInterval:    writeInterval,
+		WriteTimeout:     time.Second,
+		WriteConcurrency: 1,
+		WriteBatchSize:   100,
+	}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+	for i := 0; i < numIntervals; i++ {
+		writeClient.writeSeries()
+		time.Sleep(time.Until(alignTimestampToInterval(time.Now(), writeInterval).Add(writeInterval)))
+	}
+
+	require.Equal(t, 2*numSeries, backend.SeriesCount("user-1"))
+
+	queryClient := NewQueryClient(QueryClientConfig{
+		URL:                   backend.URL(),
+		UserID:                "user-1",
+		QueryTimeout:          time.Second,
+		QueryMaxAge:           time.Hour,
+		ExpectedSeries:        numSeries,
+		ExpectedWriteInterval: writeInterval,
+		Schedule:              schedule,
+	}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	queryClient.startTime = firstInterval.Add(-2 * writeInterval)
+
+	require.True(t, queryClient.runQueries())
+	assert.Equal(t, float64(1), testutil.ToFloat64(queryClient.resultsComparedTotal.WithLabelValues(comparisonSuccess, queryClient.defaultQuery)))
+	assert.Equal(t, float64(0), testutil.ToFloat64(queryClient.resultsComparedTotal.WithLabelValues(comparisonFailed, queryClient.defaultQuery)))
+}
@@ -0,0 +1,41 @@
+package client
+
+import (
+	"sort"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// sortedExternalLabels returns the input external labels, sorted by name.
+func sortedExternalLabels(external map[string]string) []*prompb.Label {
+	labels := make([]*prompb.Label, 0, len(external))
+	for name, value := range external {
+		labels = append(labels, &prompb.Label{Name: name, Value: value})
+	}
+
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].Name < labels[j].Name
+	})
+
+	return labels
+}
+
+// addExternalLabels appends the external labels to the series labels. Like Prometheus does, the
+// series labels take precedence over external labels with the same name. Labels are not sorted.
+func addExternalLabels(labels, external []*prompb.Label) []*prompb.Label {
+	for _, e := range external {
+		found := false
+		for _, l := range labels {
+			if l.Name == e.Name {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			labels = append(labels, e)
+		}
+	}
+
+	return labels
+}
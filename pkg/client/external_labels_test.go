@@ -0,0 +1,64 @@
+package client
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateSeries_WithExternalLabels(t *testing.T) {
+	cfg := WriteClientConfig{
+		SeriesCount:      3,
+		ExtraLabels:      1,
+		HAReplicas:       2,
+		InfoSeriesCount:  1,
+		InfoSeriesLabels: 1,
+		SummaryQuantiles: []float64{0.5},
+		ExternalLabels:   map[string]string{"env": "staging", "cluster": "eu1", "wave": "overridden"},
+	}
+
+	now := time.Now()
+	series := generateSineWaveSeries(now, cfg)
+	series = append(series, generateInfoSeries(now, cfg)...)
+	series = append(series, generateSummarySeries(now, cfg)...)
+	require.Len(t, series, 3*2+1+3)
+
+	for _, s := range series {
+		labels := map[string]string{}
+		for _, l := range s.Labels {
+			labels[l.Name] = l.Value
+		}
+
+		// The HA cluster label generated for sine wave series should take precedence.
+		if _, ok := labels["__replica__"]; ok {
+			assert.Equal(t, "cortex-load-generator", labels["cluster"])
+		} else {
+			assert.Equal(t, "eu1", labels["cluster"])
+		}
+		assert.Equal(t, "staging", labels["env"])
+		if labels["__name__"] != "cortex_load_generator_info" {
+			assert.NotEqual(t, "overridden", labels["wave"])
+		}
+		assert.Len(t, labels, len(s.Labels), "labels should be unique")
+
+		// Labels should be sorted.
+		assert.True(t, sort.SliceIsSorted(s.Labels, func(i, j int) bool { return s.Labels[i].Name < s.Labels[j].Name }))
+	}
+}
+
+func TestAddExternalLabels(t *testing.T) {
+	labels := []*prompb.Label{{Name: "__name__", Value: "up"}, {Name: "job", Value: "test"}}
+	external := sortedExternalLabels(map[string]string{"job": "external", "env": "prod", "cluster": "eu1"})
+
+	assert.Equal(t, []*prompb.Label{{Name: "cluster", Value: "eu1"}, {Name: "env", Value: "prod"}, {Name: "job", Value: "external"}}, external)
+	assert.Equal(t, []*prompb.Label{
+		{Name: "__name__", Value: "up"},
+		{Name: "job", Value: "test"},
+		{Name: "cluster", Value: "eu1"},
+		{Name: "env", Value: "prod"},
+	}, addExternalLabels(labels, external))
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+type flushResponse struct {
+	Series int `json:"series"`
+}
+
+// FlushHandler returns an HTTP handler which synchronously writes the series of the current
+// write interval for all the input (started) clients, without waiting for their next write, and
+// responds with the number of series successfully pushed.
+func FlushHandler(clients []*WriteClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			mx     sync.Mutex
+			series int
+		)
+
+		wg := sync.WaitGroup{}
+		wg.Add(len(clients))
+
+		for _, c := range clients {
+			go func(c *WriteClient) {
+				defer wg.Done()
+
+				pushed := c.Flush()
+
+				mx.Lock()
+				series += pushed
+				mx.Unlock()
+			}(c)
+		}
+
+		wg.Wait()
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(flushResponse{Series: series}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlushHandler(t *testing.T) {
+	var requests int64
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+	}))
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	var clients []*WriteClient
+	for i := 1; i <= 2; i++ {
+		clients = append(clients, NewWriteClient(WriteClientConfig{
+			URL:              *serverURL,
+			UserID:           fmt.Sprintf("user-%d", i),
+			SeriesCount:      3,
+			WriteInterval:    time.Minute,
+			WriteTimeout:     time.Second,
+			WriteConcurrency: 1,
+			WriteBatchSize:   10,
+			SkipInitialWrite: true,
+		}, log.NewNopLogger(), prometheus.NewPedanticRegistry()))
+	}
+
+	for _, c := range clients {
+		c.Start()
+		t.Cleanup(c.Stop)
+	}
+
+	rec := httptest.NewRecorder()
+	FlushHandler(clients).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/flush", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var actual struct {
+		Series int `json:"series"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &actual))
+	assert.Equal(t, 6, actual.Series)
+
+	// Each client should have written synchronously, without waiting for the write interval.
+	assert.Equal(t, int64(2), atomic.LoadInt64(&requests))
+}
+
+func TestFlushHandler_ShouldNotOverlapWithTheWriteLoop(t *testing.T) {
+	const (
+		numSeries     = 5
+		writeInterval = 20 * time.Millisecond
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	recorder := NewRecorder(1000)
+	c := NewWriteClient(WriteClientConfig{
+		URL:                *serverURL,
+		UserID:             "user-1",
+		SeriesCount:        numSeries,
+		MetricNamesCount:   2,
+		WriteInterval:      writeInterval,
+		WriteTimeout:       time.Second,
+		WriteConcurrency:   2,
+		WriteBatchSize:     2,
+		NameRotationPeriod: 5 * writeInterval,
+		Metadata:           &MetricMetadata{Type: "gauge", Help: "help"},
+		Recorder:           recorder,
+	}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	c.Start()
+
+	// Flush concurrently while the write loop is running, so that the same write intervals are
+	// written more than once.
+	handler := FlushHandler([]*WriteClient{c})
+	start := time.Now()
+
+	for time.Since(start) < 10*writeInterval {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/flush", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	c.Stop()
+
+	// The value recorded for each write interval shouldn't be counted more than once.
+	var verified int
+	for ts := alignTimestampToInterval(start, writeInterval); !ts.After(c.getLastWrite()); ts = ts.Add(writeInterval) {
+		if value, ok := recorder.Value(ts); ok {
+			assert.InDelta(t, numSeries*generateSineWaveValue(ts), value, 1e-9, "timestamp: %s", ts)
+			verified++
+		}
+	}
+	assert.Greater(t, verified, 1)
+}
@@ -0,0 +1,42 @@
+package client
+
+import (
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// dropFutureSamples removes the samples with a timestamp after now from the input series, since
+// backends reject them, and tracks them as dropped. Series left without samples are removed.
+// The input series are filtered in place.
+func (c *WriteClient) dropFutureSamples(series []*prompb.TimeSeries, now time.Time) []*prompb.TimeSeries {
+	var (
+		nowMillis = now.UnixMilli()
+		dropped   int
+		out       = series[:0]
+	)
+
+	for _, s := range series {
+		samples := s.Samples[:0]
+		for _, sample := range s.Samples {
+			if sample.Timestamp > nowMillis {
+				dropped++
+				continue
+			}
+			samples = append(samples, sample)
+		}
+		s.Samples = samples
+
+		if len(s.Samples) > 0 {
+			out = append(out, s)
+		}
+	}
+
+	if dropped > 0 {
+		c.futureSamplesDroppedTotal.Add(float64(dropped))
+		level.Warn(c.logger).Log("msg", "dropped samples with a timestamp in the future", "samples", dropped)
+	}
+
+	return out
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteClient_DropFutureSamples(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Minute).UnixMilli()
+	future := now.Add(time.Minute).UnixMilli()
+
+	client := NewWriteClient(WriteClientConfig{
+		URL:           url.URL{Scheme: "http", Host: "localhost"},
+		UserID:        "user-1",
+		WriteInterval: 10 * time.Second,
+	}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+	// Samples generated at the current write interval should never be dropped.
+	series := generateSineWaveSeries(alignTimestampToInterval(now, 10*time.Second), WriteClientConfig{SeriesCount: 3})
+	assert.Len(t, client.dropFutureSamples(series, now), 3)
+	assert.Equal(t, 0.0, testutil.ToFloat64(client.futureSamplesDroppedTotal))
+
+	// Deliberately future-dated samples should be detected, dropped and counted.
+	actual := client.dropFutureSamples([]*prompb.TimeSeries{
+		{Labels: []*prompb.Label{{Name: "wave", Value: "1"}}, Samples: []prompb.Sample{{Timestamp: past, Value: 1}}},
+		{Labels: []*prompb.Label{{Name: "wave", Value: "2"}}, Samples: []prompb.Sample{{Timestamp: past, Value: 2}, {Timestamp: future, Value: 2}}},
+		{Labels: []*prompb.Label{{Name: "wave", Value: "3"}}, Samples: []prompb.Sample{{Timestamp: future, Value: 3}}},
+		{Labels: []*prompb.Label{{Name: "wave", Value: "4"}}, Samples: []prompb.Sample{{Timestamp: now.UnixMilli(), Value: 4}}},
+	}, now)
+
+	assert.Equal(t, []*prompb.TimeSeries{
+		{Labels: []*prompb.Label{{Name: "wave", Value: "1"}}, Samples: []prompb.Sample{{Timestamp: past, Value: 1}}},
+		{Labels: []*prompb.Label{{Name: "wave", Value: "2"}}, Samples: []prompb.Sample{{Timestamp: past, Value: 2}}},
+		{Labels: []*prompb.Label{{Name: "wave", Value: "4"}}, Samples: []prompb.Sample{{Timestamp: now.UnixMilli(), Value: 4}}},
+	}, actual)
+	assert.Equal(t, 2.0, testutil.ToFloat64(client.futureSamplesDroppedTotal))
+}
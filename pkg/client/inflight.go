@@ -0,0 +1,39 @@
+package client
+
+import (
+	"context"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// InflightSamplesLimiter caps the total number of samples in flight across all write requests.
+// It can be shared across the write clients of different tenants, to bound the memory used
+// when the remote endpoint is slow.
+type InflightSamplesLimiter struct {
+	max int64
+	sem *semaphore.Weighted
+}
+
+// NewInflightSamplesLimiter returns a limiter allowing up to max samples in flight.
+func NewInflightSamplesLimiter(max int) *InflightSamplesLimiter {
+	return &InflightSamplesLimiter{
+		max: int64(max),
+		sem: semaphore.NewWeighted(int64(max)),
+	}
+}
+
+// acquire blocks until the input number of samples can be sent, or ctx is done. Requests with
+// more samples than the limit acquire the whole limit, so that they're sent alone instead of
+// blocking forever. The returned function must be called once the request completed.
+func (l *InflightSamplesLimiter) acquire(ctx context.Context, samples int) (func(), error) {
+	weight := int64(samples)
+	if weight > l.max {
+		weight = l.max
+	}
+
+	if err := l.sem.Acquire(ctx, weight); err != nil {
+		return nil, err
+	}
+
+	return func() { l.sem.Release(weight) }, nil
+}
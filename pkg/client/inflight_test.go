@@ -0,0 +1,114 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInflightSamplesLimiter_ShouldNotBlockRequestsLargerThanTheLimit(t *testing.T) {
+	limiter := NewInflightSamplesLimiter(2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	release, err := limiter.acquire(ctx, 10)
+	require.NoError(t, err)
+
+	// The whole limit is acquired.
+	blockedCtx, blockedCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer blockedCancel()
+	_, err = limiter.acquire(blockedCtx, 1)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	release()
+	release, err = limiter.acquire(ctx, 2)
+	require.NoError(t, err)
+	release()
+}
+
+func TestWriteClient_ShouldHonorMaxInflightSamplesAcrossTenants(t *testing.T) {
+	const (
+		maxInflightSamples = 5
+		numTenants         = 3
+	)
+
+	var (
+		mx          sync.Mutex
+		inflight    int
+		maxInflight int
+		received    int
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		compressed, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		data, err := snappy.Decode(nil, compressed)
+		require.NoError(t, err)
+		req := &prompb.WriteRequest{}
+		require.NoError(t, proto.Unmarshal(data, req))
+		samples := countSamples(req)
+
+		mx.Lock()
+		inflight += samples
+		if inflight > maxInflight {
+			maxInflight = inflight
+		}
+		received += samples
+		mx.Unlock()
+
+		// Simulate a slow remote endpoint.
+		time.Sleep(10 * time.Millisecond)
+
+		mx.Lock()
+		inflight -= samples
+		mx.Unlock()
+	}))
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	limiter := NewInflightSamplesLimiter(maxInflightSamples)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < numTenants; i++ {
+		client := NewWriteClient(WriteClientConfig{
+			URL:              *serverURL,
+			UserID:           fmt.Sprintf("user-%d", i),
+			SeriesCount:      20,
+			WriteInterval:    10 * time.Second,
+			WriteTimeout:     time.Second,
+			WriteConcurrency: 20,
+			WriteBatchSize:   2,
+			InflightSamples:  limiter,
+		}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, 20, client.writeSeries())
+		}()
+	}
+	wg.Wait()
+
+	mx.Lock()
+	defer mx.Unlock()
+	assert.Equal(t, numTenants*20, received)
+	assert.LessOrEqual(t, maxInflight, maxInflightSamples)
+	assert.Greater(t, maxInflight, 0)
+}
@@ -0,0 +1,39 @@
+package client
+
+import (
+	"math"
+	"time"
+)
+
+// LogNormalValues generates log-normally distributed values, like the ones of latency metrics.
+// Each series gets a different sequence of values, which is a function of the timestamp, series
+// ID and seed alone, so they can be reproduced to verify them.
+type LogNormalValues struct {
+	mu    float64
+	sigma float64
+	seed  uint64
+}
+
+// NewLogNormalValues returns values whose logarithm is normally distributed with mean mu
+// and standard deviation sigma.
+func NewLogNormalValues(mu, sigma float64, seed int64) *LogNormalValues {
+	return &LogNormalValues{
+		mu:    mu,
+		sigma: sigma,
+		seed:  uint64(seed),
+	}
+}
+
+// Value returns the value of the series with the input ID at t.
+func (v *LogNormalValues) Value(t time.Time, seriesID int) float64 {
+	seriesSeed := splitmix64(v.seed ^ uint64(seriesID))
+	key := uint64(t.UnixMilli()) << 1
+
+	// Generate a standard normal value with the Box-Muller transform. The first uniform
+	// value must be in (0, 1] to take its logarithm.
+	u1 := 1 - hashToUnitInterval(seriesSeed, key)
+	u2 := hashToUnitInterval(seriesSeed, key|1)
+	z := math.Sqrt(-2*math.Log(u1)) * math.Cos(2*math.Pi*u2)
+
+	return math.Exp(v.mu + v.sigma*z)
+}
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogNormalValues_ShouldMatchConfiguredDistribution(t *testing.T) {
+	const (
+		interval   = 10 * time.Second
+		numSamples = 100000
+	)
+
+	for _, params := range []struct{ mu, sigma float64 }{{0, 0.25}, {-2, 0.5}, {1, 1}} {
+		t.Run(fmt.Sprintf("mu: %v sigma: %v", params.mu, params.sigma), func(t *testing.T) {
+			values := NewLogNormalValues(params.mu, params.sigma, 1)
+
+			sum, sumSquares := 0.0, 0.0
+			for i := 0; i < numSamples; i++ {
+				value := values.Value(time.UnixMilli(0).Add(time.Duration(i)*interval), 1)
+				sum += value
+				sumSquares += value * value
+			}
+
+			mean := sum / numSamples
+			variance := sumSquares/numSamples - mean*mean
+
+			sigma2 := params.sigma * params.sigma
+			assert.InEpsilon(t, math.Exp(params.mu+sigma2/2), mean, 0.02)
+			assert.InEpsilon(t, (math.Exp(sigma2)-1)*math.Exp(2*params.mu+sigma2), variance, 0.1)
+		})
+	}
+}
+
+func TestLogNormalValues_ShouldBeReproducibleFromSeed(t *testing.T) {
+	const (
+		interval  = 10 * time.Second
+		numSeries = 3
+	)
+
+	cfg := ValueConfig{LogNormal: NewLogNormalValues(0, 0.5, 1)}
+
+	var samples []model.SamplePair
+	for ts := time.UnixMilli(0); len(samples) < 100; ts = ts.Add(interval) {
+		// Each series should have a different value.
+		assert.NotEqual(t, cfg.value(ts, 1), cfg.value(ts, 2))
+
+		sum := 0.0
+		for seriesID := 1; seriesID <= numSeries; seriesID++ {
+			value := NewLogNormalValues(0, 0.5, 1).Value(ts, seriesID)
+			assert.Equal(t, value, cfg.value(ts, seriesID))
+			assert.Greater(t, value, 0.0)
+
+			sum += value
+		}
+
+		samples = append(samples, newSamplePair(ts, sum))
+	}
+
+	assert.NoError(t, verifySineWaveSamples(samples, numSeries, SeriesSchedule{}, interval, cfg, nil))
+	assert.Error(t, verifySineWaveSamples(samples, numSeries, SeriesSchedule{}, interval, ValueConfig{LogNormal: NewLogNormalValues(0, 0.5, 2)}, nil))
+}
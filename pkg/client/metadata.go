@@ -0,0 +1,60 @@
+package client
+
+import (
+	"sort"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// metricTypes maps the supported metric types to the values of the remote write
+// MetricMetadata.MetricType enum.
+var metricTypes = map[string]uint64{
+	"unknown":        0,
+	"counter":        1,
+	"gauge":          2,
+	"histogram":      3,
+	"gaugehistogram": 4,
+	"summary":        5,
+	"info":           6,
+	"stateset":       7,
+}
+
+// MetricMetadata is the metadata of the generated sine wave metrics, sent along with the series.
+type MetricMetadata struct {
+	// Type is the metric type, one of MetricTypes().
+	Type string
+	Help string
+}
+
+// MetricTypes returns the supported metric types.
+func MetricTypes() []string {
+	out := make([]string, 0, len(metricTypes))
+	for t := range metricTypes {
+		out = append(out, t)
+	}
+	sort.Strings(out)
+
+	return out
+}
+
+// encodeMetadata returns the protobuf encoded metadata of the input metric names, as repeated
+// WriteRequest.metadata fields, which can be appended to an encoded WriteRequest. The metadata
+// is encoded manually because the vendored prompb predates metadata support.
+func encodeMetadata(metricNames []string, md MetricMetadata) []byte {
+	var out []byte
+
+	for _, name := range metricNames {
+		var entry []byte
+		entry = protowire.AppendTag(entry, 1, protowire.VarintType)
+		entry = protowire.AppendVarint(entry, metricTypes[md.Type])
+		entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+		entry = protowire.AppendString(entry, name)
+		entry = protowire.AppendTag(entry, 4, protowire.BytesType)
+		entry = protowire.AppendString(entry, md.Help)
+
+		out = protowire.AppendTag(out, 3, protowire.BytesType)
+		out = protowire.AppendBytes(out, entry)
+	}
+
+	return out
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestWriteClient_ShouldSendMetadata(t *testing.T) {
+	var (
+		receivedMx sync.Mutex
+		received   [][]byte
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		compressed, _ := io.ReadAll(r.Body)
+		data, err := snappy.Decode(nil, compressed)
+		require.NoError(t, err)
+
+		receivedMx.Lock()
+		received = append(received, data)
+		receivedMx.Unlock()
+	}))
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	client := NewWriteClient(WriteClientConfig{
+		URL:              *serverURL,
+		UserID:           "user-1",
+		SeriesCount:      3,
+		MetricNamesCount: 2,
+		WriteInterval:    10 * time.Second,
+		WriteTimeout:     time.Second,
+		WriteConcurrency: 1,
+		WriteBatchSize:   10,
+		Metadata:         &MetricMetadata{Type: "gauge", Help: "A sine wave."},
+	}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+	client.writeSeries()
+
+	receivedMx.Lock()
+	defer receivedMx.Unlock()
+	require.Len(t, received, 1)
+
+	// The series should still be decoded, ignoring the metadata.
+	req := &prompb.WriteRequest{}
+	require.NoError(t, req.Unmarshal(received[0]))
+	assert.Len(t, req.Timeseries, 6)
+
+	type metadata struct {
+		metricType uint64
+		name       string
+		help       string
+	}
+
+	var actual []metadata
+	for data := received[0]; len(data) > 0; {
+		num, typ, n := protowire.ConsumeTag(data)
+		require.GreaterOrEqual(t, n, 0)
+		data = data[n:]
+
+		if num != 3 {
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			require.GreaterOrEqual(t, n, 0)
+			data = data[n:]
+			continue
+		}
+
+		entry, n := protowire.ConsumeBytes(data)
+		require.GreaterOrEqual(t, n, 0)
+		data = data[n:]
+
+		var md metadata
+		for len(entry) > 0 {
+			num, typ, n := protowire.ConsumeTag(entry)
+			require.GreaterOrEqual(t, n, 0)
+			entry = entry[n:]
+
+			switch num {
+			case 1:
+				md.metricType, n = protowire.ConsumeVarint(entry)
+			case 2:
+				md.name, n = protowire.ConsumeString(entry)
+			case 4:
+				md.help, n = protowire.ConsumeString(entry)
+			default:
+				n = protowire.ConsumeFieldValue(num, typ, entry)
+			}
+			require.GreaterOrEqual(t, n, 0)
+			entry = entry[n:]
+		}
+		actual = append(actual, md)
+	}
+
+	assert.Equal(t, []metadata{
+		{metricType: 2, name: "cortex_load_generator_sine_wave_0", help: "A sine wave."},
+		{metricType: 2, name: "cortex_load_generator_sine_wave_1", help: "A sine wave."},
+	}, actual)
+}
@@ -0,0 +1,28 @@
+package client
+
+import (
+	"fmt"
+	"time"
+)
+
+// rotatedMetricNames returns the metric names at t. If the rotation period is greater than 0, each
+// metric name gets a version suffix (e.g. _v2) changing every period, so that the series written
+// with the previous names are retired and new ones are started.
+func rotatedMetricNames(names []string, t time.Time, period time.Duration) []string {
+	if period <= 0 {
+		return names
+	}
+
+	version := metricNameRotationStart(t, period).UnixNano() / int64(period)
+	rotated := make([]string, 0, len(names))
+	for _, name := range names {
+		rotated = append(rotated, fmt.Sprintf("%s_v%d", name, version))
+	}
+
+	return rotated
+}
+
+// metricNameRotationStart returns the time when the metric names active at t started being written.
+func metricNameRotationStart(t time.Time, period time.Duration) time.Time {
+	return alignTimestampToInterval(t, period)
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateSineWaveSeries_WithNameRotation(t *testing.T) {
+	const rotationPeriod = time.Hour
+
+	start, err := time.Parse(time.RFC3339, "2023-06-29T00:00:00Z")
+	require.NoError(t, err)
+
+	metricNames := func(ts time.Time) []string {
+		var names []string
+		for _, s := range generateSineWaveSeries(ts, WriteClientConfig{SeriesCount: 2, MetricNamesCount: 2, NameRotationPeriod: rotationPeriod}) {
+			for _, l := range s.Labels {
+				if l.Name == "__name__" {
+					names = append(names, l.Value)
+				}
+			}
+		}
+		return names
+	}
+
+	// The metric names should not change within the rotation period.
+	expected := []string{"cortex_load_generator_sine_wave_0_v468888", "cortex_load_generator_sine_wave_0_v468888", "cortex_load_generator_sine_wave_1_v468888", "cortex_load_generator_sine_wave_1_v468888"}
+	assert.Equal(t, expected, metricNames(start))
+	assert.Equal(t, expected, metricNames(start.Add(rotationPeriod-time.Second)))
+
+	// The metric names should rotate at the end of the rotation period.
+	expected = []string{"cortex_load_generator_sine_wave_0_v468889", "cortex_load_generator_sine_wave_0_v468889", "cortex_load_generator_sine_wave_1_v468889", "cortex_load_generator_sine_wave_1_v468889"}
+	assert.Equal(t, expected, metricNames(start.Add(rotationPeriod)))
+
+	// The metric names should not be rotated if disabled.
+	assert.Equal(t, []string{sineWaveMetricName}, rotatedMetricNames([]string{sineWaveMetricName}, start, 0))
+}
+
+func TestQueryClient_ShouldQueryTheCurrentRotatedMetricName(t *testing.T) {
+	const (
+		numSeries      = 2
+		writeInterval  = time.Second
+		rotationPeriod = 2 * time.Minute
+	)
+
+	var (
+		queried     []string
+		queryStarts []time.Time
+	)
+
+	// The server only has data for the metric name active at each timestamp.
+	server := httptest.NewServer(newQueryRangeHandler(func(query string, start, end time.Time, step time.Duration) model.Matrix {
+		queried = append(queried, query)
+		queryStarts = append(queryStarts, start)
+
+		stream := &model.SampleStream{Metric: model.Metric{}}
+		for ts := start; !ts.After(end); ts = ts.Add(step) {
+			if query == defaultQuery(QueryClientConfig{}, rotatedMetricNames([]string{sineWaveMetricName}, ts, rotationPeriod)[0]) {
+				stream.Values = append(stream.Values, newSamplePair(ts, numSeries*generateSineWaveValue(ts)))
+			}
+		}
+		return model.Matrix{stream}
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewQueryClient(QueryClientConfig{
+		URL:                   server.URL,
+		UserID:                "user-1",
+		QueryTimeout:          time.Second,
+		QueryMaxAge:           time.Hour,
+		ExpectedSeries:        numSeries,
+		ExpectedWriteInterval: writeInterval,
+		NameRotationPeriod:    rotationPeriod,
+	}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	client.startTime = time.Now().Add(-time.Hour)
+
+	// Make sure the query time range isn't empty, because the metric names have just been rotated.
+	if now := time.Now(); now.Sub(metricNameRotationStart(now, rotationPeriod)) < 10*writeInterval {
+		time.Sleep(10 * writeInterval)
+	}
+
+	require.True(t, client.runQueries())
+
+	require.Len(t, queried, 1)
+	assert.Regexp(t, `^sum\(cortex_load_generator_sine_wave_v\d+\)$`, queried[0])
+	assert.Equal(t, queried[0], client.defaultQuery)
+	assert.False(t, queryStarts[0].Before(metricNameRotationStart(time.Now().Add(-2*writeInterval), rotationPeriod)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(client.resultsComparedTotal.WithLabelValues(comparisonSuccess, client.defaultQuery)))
+}
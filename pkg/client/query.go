@@ -5,8 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"math/rand"
 	"net/http"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-kit/log"
@@ -16,6 +19,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/gate"
 )
 
 const (
@@ -24,43 +28,223 @@ const (
 	comparisonSuccess = "success"
 	comparisonFailed  = "fail"
 
+	comparisonValueMismatch      = "value_mismatch"
+	comparisonTimestampGap       = "timestamp_gap"
+	comparisonDuplicateTimestamp = "duplicate_timestamp"
+	comparisonOutOfOrder         = "out_of_order"
+	comparisonOther              = "other"
+
 	querySkipped = "skipped"
 	querySuccess = "success"
 	queryFailed  = "fail"
 
-	defaultQuery = "sum(cortex_load_generator_sine_wave)"
+	queryCacheDefault  = "default"
+	queryCacheBypassed = "bypassed"
+
+	defaultQueryMaxSamples = 1000
 )
 
 type QueryClientConfig struct {
 	URL string
 
+	// SecondaryURL, if set, is the base URL of a second query endpoint. The default and additional
+	// queries are run against both endpoints and their results are compared to each other, instead
+	// of being verified against the expected values, e.g. to validate a migration between backends.
+	SecondaryURL string
+
+	// Transport used to send query requests. It's safe to share the same transport across
+	// clients of different tenants, because the tenant ID is injected in each request.
+	// If nil, a dedicated transport honoring the environment proxy settings is created.
+	Transport http.RoundTripper
+
 	// The tenant ID to use to push metrics to Cortex.
 	UserID string
 
+	// TenantHeaderName is the name of the HTTP header carrying the tenant ID.
+	// If empty, defaults to X-Scope-OrgID.
+	TenantHeaderName string
+
 	QueryInterval time.Duration
 	QueryTimeout  time.Duration
 	QueryMaxAge   time.Duration
 
+	// QueryMinStep is the min query step. It's rounded up to a multiple of the write interval,
+	// and takes precedence over QueryMaxSamples. 0 to disable.
+	QueryMinStep time.Duration
+
+	// QueryMaxSamples is the max number of samples per series returned by each query, used to
+	// compute the query step. Defaults to 1000.
+	QueryMaxSamples int
+
+	// QueryHeaders are additional HTTP headers set on query requests, e.g. Accept.
+	QueryHeaders map[string]string
+
+	// QueryNoCache asks the query-frontend to bypass the results cache for all queries, while
+	// QueryNoCacheAlternate bypasses it for every other query, to compare the cached and
+	// uncached query latency.
+	QueryNoCache          bool
+	QueryNoCacheAlternate bool
+
+	// QueryGzipRequests gzip-compresses the body of POST query requests, for gateways which
+	// benefit from compressed long PromQL queries.
+	QueryGzipRequests bool
+
 	ExpectedSeries        int
 	ExpectedWriteInterval time.Duration
 
-	AdditionalQueries []string
+	// ExternalWrites means the series are written by someone else (e.g. a pre-populated backend)
+	// instead of a write client started along with this query client, so the queried time range
+	// isn't bound to the client start time.
+	ExternalWrites bool
+
+	// ExpectedMetricNamesCount is the number of distinct metric names written. The default
+	// query targets the first one.
+	ExpectedMetricNamesCount int
+
+	// TenantMetricName means the sine wave, info and summary metric names are suffixed with the
+	// tenant ID. It must match the config of the write client.
+	TenantMetricName bool
+
+	// NameRotationPeriod is the rotation period of the metric names. It must match the
+	// config of the write client. The queries target the metric names active at the end of the
+	// query time range, which doesn't start before they've been rotated.
+	NameRotationPeriod time.Duration
+
+	// Schedule configures how the number of expected series changes over time, up to
+	// ExpectedSeries. It must match the config of the write client.
+	Schedule SeriesSchedule
+
+	// ExpectedInfoSeries is the number of info series expected to be written. If greater
+	// than 0, the info series are queried and verified too.
+	ExpectedInfoSeries int
+
+	AdditionalQueries []AdditionalQuery
+
+	// QueryConcurrency is the number of concurrent copies of each query run at every
+	// query interval, to simulate many users querying the same dashboard. It also
+	// bounds the number of queries in flight at the same time, unless QueryConcurrencyLimit
+	// is set.
+	QueryConcurrency int
+
+	// QueryConcurrencyLimit is the max number of queries in flight at the same time, overriding
+	// the bound set by QueryConcurrency, e.g. to not overwhelm small query endpoints when many
+	// queries are configured. 0 to bound them to QueryConcurrency.
+	QueryConcurrencyLimit int
+
+	// Values configures how the expected sample values are generated. It must match
+	// the config of the write client.
+	Values ValueConfig
+
+	// VerifyResultsCache runs the default query twice in a row too, and verifies the second run,
+	// expected to be served from the query-frontend results cache, is faster and returns the same
+	// result. It's not meaningful if the results cache is bypassed.
+	VerifyResultsCache bool
+
+	// VerifySeriesCount runs a count query on the sine wave series too, and verifies it matches
+	// the expected number of series. It catches missing or extra series the sum may not detect.
+	VerifySeriesCount bool
+
+	// VerifyRawSeries runs the default query against the raw sine wave series, instead of their
+	// sum, and verifies each series individually. It's not compatible with churning series.
+	VerifyRawSeries bool
+
+	// Recorder, if set, verifies the default query results against the values recorded by the
+	// write client, instead of the expected sine wave. It must be shared with the write client.
+	Recorder *Recorder
+
+	// ComparisonFailures, if set, receives the error of each failed query result comparison,
+	// e.g. to shut down on the first failure. Errors are dropped if the channel is full, so
+	// that queries are never blocked.
+	ComparisonFailures chan<- error
+
+	// ExpectStepAverage expects the default query to return the average value over each step,
+	// instead of the value at the step timestamp, to verify backends serving downsampled data.
+	ExpectStepAverage bool
+
+	// StreamDefaultQuery verifies the default query results while the response is read, instead
+	// of decoding the whole response first, so that memory stays bounded for large results. It's
+	// ignored when verifying raw series or recorded values.
+	StreamDefaultQuery bool
+
+	// VerifyChecksums verifies the value of each sample matches the checksum label of its
+	// series, instead of verifying the default query. It requires the write client to be
+	// configured with ChecksumLabel, in which case each sample is written to a new series
+	// and the aggregated value of the default query can't be verified.
+	VerifyChecksums bool
+
+	// SkipInitialQuery delays the first queries by one query interval, instead of
+	// querying immediately once started.
+	SkipInitialQuery bool
+
+	// QueryBackoffMaxInterval is the max interval between queries while they're failing. The
+	// query interval is doubled after each consecutive run with failed queries, up to this max,
+	// and reset once all queries succeed. 0 to disable backoff.
+	QueryBackoffMaxInterval time.Duration
+
+	// QueryBackoffJitter is the max fraction of the backoff interval randomly subtracted from it,
+	// so that the clients of different tenants don't query in lockstep.
+	QueryBackoffJitter float64
 }
 
 type QueryClient struct {
-	cfg       QueryClientConfig
-	client    v1.API
-	startTime time.Time
-	logger    log.Logger
+	cfg           QueryClientConfig
+	defaultQuery  string
+	checksumQuery string
+	countQuery    string
+	infoQuery     string
+	client        v1.API
+	httpClient    *http.Client
+	startTime     time.Time
+	logger        log.Logger
+
+	// The client of the secondary query endpoint, if configured.
+	secondaryClient v1.API
+
+	// failedQueries is the number of queries failed since the client started. It must be
+	// accessed atomically.
+	failedQueries int64
+
+	// queriesCount is the number of queries run, used to alternate cached and uncached
+	// queries. It must be accessed atomically.
+	queriesCount uint64
+
+	// The last error of each query, keyed by query.
+	lastErrorsMx sync.Mutex
+	lastErrors   map[string]queryError
+
+	// jitterRand is only used by the run loop, so it doesn't need to be concurrency safe.
+	jitterRand *rand.Rand
+
+	// The gate bounding the number of queries in flight.
+	queryGate *gate.Gate
 
 	// Metrics.
 	queriesTotal         *prometheus.CounterVec
 	resultsComparedTotal *prometheus.CounterVec
+	comparisonDelta      prometheus.Histogram
+	comparisonFailures   *prometheus.CounterVec
+	lastComparisonError  prometheus.Gauge
+	lastSeriesCount      prometheus.Gauge
+	queryDuration        *prometheus.HistogramVec
+	backendMismatches    *prometheus.CounterVec
+	queryResponseBytes   *prometheus.CounterVec
+
+	queryCacheConsistency *prometheus.CounterVec
 }
 
 func NewQueryClient(cfg QueryClientConfig, logger log.Logger, reg prometheus.Registerer) *QueryClient {
-	var rt http.RoundTripper = &http.Transport{}
-	rt = &clientRoundTripper{userID: cfg.UserID, rt: rt}
+	rt := cfg.Transport
+	if rt == nil {
+		rt = &http.Transport{Proxy: http.ProxyFromEnvironment}
+	}
+	rt = &clientRoundTripper{userID: cfg.UserID, headerName: cfg.TenantHeaderName, rt: rt, headers: cfg.QueryHeaders, gzipBody: cfg.QueryGzipRequests}
+
+	responseBytes := promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+		Name:        "cortex_load_generator_query_response_bytes_total",
+		Help:        "Total number of bytes of query responses read.",
+		ConstLabels: map[string]string{"user": cfg.UserID},
+	}, []string{"query"})
+	rt = &responseBytesRoundTripper{rt: rt, bytes: responseBytes}
 
 	apiCfg := api.Config{
 		Address:      cfg.URL,
@@ -73,10 +257,17 @@ func NewQueryClient(cfg QueryClientConfig, logger log.Logger, reg prometheus.Reg
 	}
 
 	c := &QueryClient{
-		cfg:       cfg,
-		client:    v1.NewAPI(client),
-		startTime: time.Now().UTC(),
-		logger:    log.With(logger, "user", cfg.UserID),
+		cfg:        cfg,
+		infoQuery:  fmt.Sprintf("sum(%s)", tenantMetricName(infoMetricName, cfg.UserID, cfg.TenantMetricName)),
+		client:     v1.NewAPI(client),
+		httpClient: &http.Client{Transport: rt},
+		startTime:  time.Now().UTC(),
+		logger:     log.With(logger, "user", cfg.UserID),
+		queryGate:  gate.New(cfg.maxQueriesInFlight()),
+		jitterRand: rand.New(rand.NewSource(time.Now().UnixNano())),
+		lastErrors: map[string]queryError{},
+
+		queryResponseBytes: responseBytes,
 
 		queriesTotal: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
 			Name:        "cortex_load_generator_queries_total",
@@ -88,113 +279,527 @@ func NewQueryClient(cfg QueryClientConfig, logger log.Logger, reg prometheus.Reg
 			Help:        "Total number of query results compared.",
 			ConstLabels: map[string]string{"user": cfg.UserID},
 		}, []string{"result", "query"}),
+		comparisonDelta: promauto.With(reg).NewHistogram(prometheus.HistogramOpts{
+			Name:        "cortex_load_generator_comparison_delta",
+			Help:        "Absolute difference between the actual and expected value of compared samples.",
+			ConstLabels: map[string]string{"user": cfg.UserID},
+			Buckets:     prometheus.ExponentialBuckets(1e-9, 10, 12),
+		}),
+		comparisonFailures: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name:        "cortex_load_generator_comparison_failures_total",
+			Help:        "Total number of failed query result comparisons, by kind of failure.",
+			ConstLabels: map[string]string{"user": cfg.UserID},
+		}, []string{"kind"}),
+		lastComparisonError: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name:        "cortex_load_generator_last_comparison_error_timestamp",
+			Help:        "Unix timestamp (in seconds) of the last failed query result comparison.",
+			ConstLabels: map[string]string{"user": cfg.UserID},
+		}),
+		lastSeriesCount: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name:        "cortex_load_generator_queried_series_count",
+			Help:        "Number of series returned by the latest sample of the last series count query.",
+			ConstLabels: map[string]string{"user": cfg.UserID},
+		}),
+		queryDuration: promauto.With(reg).NewHistogramVec(prometheus.HistogramOpts{
+			Name:        "cortex_load_generator_query_duration_seconds",
+			Help:        "Time spent running queries, by whether the results cache was bypassed.",
+			ConstLabels: map[string]string{"user": cfg.UserID},
+			Buckets:     prometheus.DefBuckets,
+		}, []string{"cache"}),
+		queryCacheConsistency: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name:        "cortex_load_generator_query_cache_consistency_total",
+			Help:        "Total number of results cache consistency checks, running the default query twice in a row, by result.",
+			ConstLabels: map[string]string{"user": cfg.UserID},
+		}, []string{"result"}),
+		backendMismatches: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name:        "cortex_load_generator_backend_mismatches_total",
+			Help:        "Total number of query results differing between the primary and secondary query endpoints.",
+			ConstLabels: map[string]string{"user": cfg.UserID},
+		}, []string{"query"}),
+	}
+
+	c.setQueriedMetricName(c.queriedMetricName(time.Now()))
+
+	if cfg.SecondaryURL != "" {
+		secondary, err := api.NewClient(api.Config{Address: cfg.SecondaryURL, RoundTripper: rt})
+		if err != nil {
+			panic(err)
+		}
+		c.secondaryClient = v1.NewAPI(secondary)
+
+		c.backendMismatches.WithLabelValues(c.defaultQuery).Add(0)
+		for _, query := range cfg.AdditionalQueries {
+			c.backendMismatches.WithLabelValues(query.Query).Add(0)
+		}
 	}
 
 	// Init metrics.
 	c.queriesTotal.WithLabelValues(querySkipped, "").Add(0)
 
+	c.queryResponseBytes.WithLabelValues(c.defaultQuery).Add(0)
+	for _, query := range cfg.AdditionalQueries {
+		c.queryResponseBytes.WithLabelValues(query.Query).Add(0)
+	}
 	for _, result := range []string{querySuccess, queryFailed} {
-		c.queriesTotal.WithLabelValues(result, defaultQuery).Add(0)
+		c.queriesTotal.WithLabelValues(result, c.defaultQuery).Add(0)
 
 		for _, query := range cfg.AdditionalQueries {
-			c.queriesTotal.WithLabelValues(result, query).Add(0)
+			c.queriesTotal.WithLabelValues(result, query.Query).Add(0)
 		}
 	}
 	for _, result := range []string{comparisonSuccess, comparisonFailed} {
-		c.resultsComparedTotal.WithLabelValues(result, defaultQuery).Add(0)
+		c.resultsComparedTotal.WithLabelValues(result, c.defaultQuery).Add(0)
+
+		for _, query := range cfg.AdditionalQueries {
+			if query.Expected != nil {
+				c.resultsComparedTotal.WithLabelValues(result, query.Query).Add(0)
+			}
+		}
+	}
+	for _, kind := range []string{comparisonValueMismatch, comparisonTimestampGap, comparisonDuplicateTimestamp, comparisonOutOfOrder, comparisonOther} {
+		c.comparisonFailures.WithLabelValues(kind).Add(0)
+	}
+	if cfg.VerifyChecksums {
+		for _, result := range []string{querySuccess, queryFailed} {
+			c.queriesTotal.WithLabelValues(result, c.checksumQuery).Add(0)
+		}
+		for _, result := range []string{comparisonSuccess, comparisonFailed} {
+			c.resultsComparedTotal.WithLabelValues(result, c.checksumQuery).Add(0)
+		}
+	}
+	if cfg.VerifyResultsCache {
+		for _, result := range cacheConsistencyResults {
+			c.queryCacheConsistency.WithLabelValues(result).Add(0)
+		}
+	}
+	if cfg.VerifySeriesCount {
+		for _, result := range []string{querySuccess, queryFailed} {
+			c.queriesTotal.WithLabelValues(result, c.countQuery).Add(0)
+		}
+		for _, result := range []string{comparisonSuccess, comparisonFailed} {
+			c.resultsComparedTotal.WithLabelValues(result, c.countQuery).Add(0)
+		}
+	}
+	if cfg.ExpectedInfoSeries > 0 {
+		for _, result := range []string{querySuccess, queryFailed} {
+			c.queriesTotal.WithLabelValues(result, c.infoQuery).Add(0)
+		}
+		for _, result := range []string{comparisonSuccess, comparisonFailed} {
+			c.resultsComparedTotal.WithLabelValues(result, c.infoQuery).Add(0)
+		}
 	}
 
 	return c
 }
 
+// setQueriedMetricName sets the queries targeting the sine wave series to the input metric name.
+func (c *QueryClient) setQueriedMetricName(metricName string) {
+	c.defaultQuery = defaultQuery(c.cfg, metricName)
+	// Only query the first series, to keep the number of series returned bounded.
+	c.checksumQuery = fmt.Sprintf("%s{wave=\"1\"}", metricName)
+	c.countQuery = fmt.Sprintf("count(%s)", metricName)
+}
+
+// queriedMetricName returns the metric name targeted by the queries at t, which is the first one.
+func (c *QueryClient) queriedMetricName(t time.Time) string {
+	return rotatedMetricNames(sineWaveMetricNames(sineWaveBaseMetricName(c.cfg.UserID, c.cfg.TenantMetricName), c.cfg.ExpectedMetricNamesCount), t, c.cfg.NameRotationPeriod)[0]
+}
+
+// defaultQuery returns the default query, targeting the input metric name.
+func defaultQuery(cfg QueryClientConfig, metricName string) string {
+	if cfg.VerifyRawSeries {
+		return metricName
+	}
+
+	return fmt.Sprintf("sum(%s)", metricName)
+}
+
 func (c *QueryClient) Start() {
 	go c.run()
 }
 
 func (c *QueryClient) run() {
-	c.runQueries()
+	next := time.Now()
+	if c.cfg.SkipInitialQuery {
+		next = next.Add(c.cfg.QueryInterval)
+	}
+
+	failures := 0
+
+	for {
+		time.Sleep(time.Until(next))
+		next = time.Now()
+
+		if c.runQueries() {
+			failures = 0
+		} else {
+			failures++
+		}
+
+		// The interval includes the time spent running the queries, like a ticker.
+		next = next.Add(c.queryInterval(failures))
+	}
+}
 
-	ticker := time.NewTicker(c.cfg.QueryInterval)
+// queryInterval returns the interval before running the next queries, given the number of
+// consecutive runs with failed queries.
+func (c *QueryClient) queryInterval(failures int) time.Duration {
+	maxInterval := c.cfg.QueryBackoffMaxInterval
+	if failures == 0 || maxInterval <= c.cfg.QueryInterval {
+		return c.cfg.QueryInterval
+	}
 
-	for range ticker.C {
-		c.runQueries()
+	interval := c.cfg.QueryInterval
+	for i := 0; i < failures && interval < maxInterval; i++ {
+		interval *= 2
+	}
+	if interval > maxInterval {
+		interval = maxInterval
 	}
+
+	if c.cfg.QueryBackoffJitter > 0 {
+		interval -= time.Duration(c.jitterRand.Float64() * c.cfg.QueryBackoffJitter * float64(interval))
+	}
+
+	return interval
 }
 
-func (c *QueryClient) runQueries() {
+// runQueries runs all the queries once and returns whether none of them failed.
+func (c *QueryClient) runQueries() bool {
 	// Compute the query start/end time.
 	start, end, ok := c.getQueryTimeRange(time.Now().UTC())
 	if !ok {
 		level.Debug(c.logger).Log("msg", "skipped querying because no eligible time range to query")
 		c.queriesTotal.WithLabelValues(querySkipped, "").Inc()
-		return
+		return true
+	}
+
+	// Target the metric name active in the query time range.
+	if c.cfg.NameRotationPeriod > 0 {
+		c.setQueriedMetricName(c.queriedMetricName(end))
 	}
 
+	failedBefore := atomic.LoadInt64(&c.failedQueries)
+
 	step := c.getQueryStep(start, end, c.cfg.ExpectedWriteInterval)
 
+	copies := queryConcurrency(c.cfg.QueryConcurrency)
+
 	wg := sync.WaitGroup{}
-	wg.Add(1 + len(c.cfg.AdditionalQueries))
+	wg.Add(copies * (1 + len(c.cfg.AdditionalQueries)))
 
-	go func() {
-		defer wg.Done()
+	for i := 0; i < copies; i++ {
+		go func() {
+			defer wg.Done()
+
+			c.runLimited(func() {
+				switch {
+				case c.secondaryClient != nil:
+					c.runBackendsComparison(start, end, step, c.defaultQuery, c.cfg.QueryTimeout)
+				case c.cfg.VerifyChecksums:
+					c.runChecksumQuery(start, end, step)
+				default:
+					c.runDefaultQuery(start, end, step)
+				}
+			})
+		}()
+
+		for _, query := range c.cfg.AdditionalQueries {
+			query := query
 
-		c.runDefaultQuery(start, end, step)
-	}()
+			go func() {
+				defer wg.Done()
 
-	for _, query := range c.cfg.AdditionalQueries {
-		query := query
+				c.runLimited(func() {
+					if c.secondaryClient != nil {
+						c.runBackendsComparison(start, end, step, query.Query, query.timeout(c.cfg.QueryTimeout))
+						return
+					}
+
+					c.runAdditionalQuery(start, end, step, query)
+				})
+			}()
+		}
+	}
+
+	if c.cfg.VerifyResultsCache {
+		wg.Add(1)
 
 		go func() {
 			defer wg.Done()
 
-			c.runAdditionalQuery(start, end, step, query)
+			c.runLimited(func() {
+				c.runCacheConsistencyCheck(start, end, step)
+			})
+		}()
+	}
+
+	if c.cfg.VerifySeriesCount {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			c.runLimited(func() {
+				c.runCountQuery(start, end, step)
+			})
+		}()
+	}
+
+	if c.cfg.ExpectedInfoSeries > 0 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			c.runLimited(func() {
+				c.runInfoQuery(start, end, step)
+			})
 		}()
 	}
 
 	wg.Wait()
+
+	return atomic.LoadInt64(&c.failedQueries) == failedBefore
+}
+
+// runLimited runs the input function once the query gate allows it.
+func (c *QueryClient) runLimited(f func()) {
+	if err := c.queryGate.Start(context.Background()); err != nil {
+		return
+	}
+	defer c.queryGate.Done()
+
+	f()
 }
 
 func (c *QueryClient) runDefaultQuery(start, end time.Time, step time.Duration) {
-	samples, err := c.runQueryAndCollectStats(start, end, step, defaultQuery)
+	if c.cfg.StreamDefaultQuery && !c.cfg.VerifyRawSeries && c.cfg.Recorder == nil {
+		c.runStreamingDefaultQuery(start, end, step)
+		return
+	}
+
+	matrix, err := c.runQueryAndCollectStats(start, end, step, c.defaultQuery, c.cfg.QueryTimeout)
 	if err != nil {
 		return
 	}
 
-	err = verifySineWaveSamples(samples, c.cfg.ExpectedSeries, step)
+	c.recordComparison(c.defaultQuery, c.verifyDefaultQueryResult(matrix, step))
+}
+
+// runStreamingDefaultQuery runs the default query, verifying the samples while they're read.
+func (c *QueryClient) runStreamingDefaultQuery(start, end time.Time, step time.Duration) {
+	expected := expectedSineWaveSum(c.cfg.ExpectedSeries, c.cfg.Schedule, c.cfg.Values)
+	if c.cfg.ExpectStepAverage {
+		expected = expectedAveragedSineWaveSum(c.cfg.ExpectedSeries, c.cfg.Schedule, step, c.cfg.Values)
+	}
+
+	var (
+		v           = sampleVerifier{expectedStep: step, expected: expected, deltas: c.comparisonDelta}
+		seriesCount int
+		verifyErr   error
+	)
+
+	err := c.runStreamingQuery(start, end, step, c.defaultQuery, c.cfg.QueryTimeout, func(model.Metric) error {
+		seriesCount++
+		return nil
+	}, func(sample model.SamplePair) error {
+		// Don't verify the samples of other series, since the result is wrong anyway.
+		if seriesCount > 1 {
+			return nil
+		}
+
+		if verifyErr = v.add(sample); verifyErr != nil {
+			return errStopStreaming
+		}
+		return nil
+	})
+	c.recordQuery(c.defaultQuery, err)
 	if err != nil {
-		level.Warn(c.logger).Log("msg", "query result comparison failed", "err", err, "query", defaultQuery)
-		c.resultsComparedTotal.WithLabelValues(comparisonFailed, defaultQuery).Inc()
 		return
 	}
 
-	c.resultsComparedTotal.WithLabelValues(comparisonSuccess, defaultQuery).Inc()
+	if verifyErr == nil && seriesCount != 1 {
+		verifyErr = fmt.Errorf("expected 1 series in the result but got %d", seriesCount)
+	}
+	c.recordComparison(c.defaultQuery, verifyErr)
 }
 
-func (c *QueryClient) runAdditionalQuery(start, end time.Time, step time.Duration, query string) {
-	_, _ = c.runQueryAndCollectStats(start, end, step, query)
+func (c *QueryClient) verifyDefaultQueryResult(matrix model.Matrix, step time.Duration) error {
+	if c.cfg.VerifyRawSeries {
+		return verifySineWaveMatrix(matrix, c.cfg.ExpectedSeries, c.cfg.Schedule, step, c.cfg.Values, c.comparisonDelta)
+	}
+
+	samples, err := singleSeriesSamples(matrix)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case c.cfg.Recorder != nil:
+		return verifyRecordedSamples(samples, step, c.cfg.Recorder, c.comparisonDelta)
+	case c.cfg.ExpectStepAverage:
+		return verifyAveragedSineWaveSamples(samples, c.cfg.ExpectedSeries, c.cfg.Schedule, step, c.cfg.Values, c.comparisonDelta)
+	default:
+		return verifySineWaveSamples(samples, c.cfg.ExpectedSeries, c.cfg.Schedule, step, c.cfg.Values, c.comparisonDelta)
+	}
 }
 
-func (c *QueryClient) runQueryAndCollectStats(start, end time.Time, step time.Duration, query string) ([]model.SamplePair, error) {
-	samples, err := c.runQuery(start, end, step, query)
+func (c *QueryClient) runChecksumQuery(start, end time.Time, step time.Duration) {
+	matrix, err := c.runQueryAndCollectStats(start, end, step, c.checksumQuery, c.cfg.QueryTimeout)
+	if err != nil {
+		return
+	}
+
+	err = verifyChecksums(matrix)
+	c.recordComparison(c.checksumQuery, err)
+}
+
+func (c *QueryClient) runInfoQuery(start, end time.Time, step time.Duration) {
+	matrix, err := c.runQueryAndCollectStats(start, end, step, c.infoQuery, c.cfg.QueryTimeout)
+	if err != nil {
+		return
+	}
+
+	// Each info series has a constant value of 1.
+	samples, err := singleSeriesSamples(matrix)
+	if err == nil {
+		err = verifySamples(samples, step, func(time.Time) float64 {
+			return float64(c.cfg.ExpectedInfoSeries)
+		}, c.comparisonDelta)
+	}
+	c.recordComparison(c.infoQuery, err)
+}
+
+func (c *QueryClient) runCountQuery(start, end time.Time, step time.Duration) {
+	matrix, err := c.runQueryAndCollectStats(start, end, step, c.countQuery, c.cfg.QueryTimeout)
+	if err != nil {
+		return
+	}
+
+	samples, err := singleSeriesSamples(matrix)
+	if err == nil {
+		if len(samples) > 0 {
+			c.lastSeriesCount.Set(float64(samples[len(samples)-1].Value))
+		}
+
+		// The number of series changes over time according to the schedule.
+		err = verifySamples(samples, step, func(ts time.Time) float64 {
+			return float64(c.cfg.Schedule.seriesCount(ts, c.cfg.ExpectedSeries))
+		}, c.comparisonDelta)
+	}
+	c.recordComparison(c.countQuery, err)
+}
+
+// recordComparison tracks the result of comparing the query results with the expected ones.
+func (c *QueryClient) recordComparison(query string, err error) {
+	if err != nil {
+		level.Warn(c.logger).Log("msg", "query result comparison failed", "err", err, "query", query)
+		c.resultsComparedTotal.WithLabelValues(comparisonFailed, query).Inc()
+		c.comparisonFailures.WithLabelValues(comparisonFailureKind(err)).Inc()
+		c.lastComparisonError.SetToCurrentTime()
+		c.setLastError(query, err)
+
+		if c.cfg.ComparisonFailures != nil {
+			select {
+			case c.cfg.ComparisonFailures <- fmt.Errorf("query %s: %w", query, err):
+			default:
+			}
+		}
+		return
+	}
+
+	c.resultsComparedTotal.WithLabelValues(comparisonSuccess, query).Inc()
+}
+
+func (c *QueryClient) runAdditionalQuery(start, end time.Time, step time.Duration, query AdditionalQuery) {
+	// Additional queries can return any number of series, unless their value is verified.
+	matrix, err := c.runQueryAndCollectStats(start, end, step, query.Query, query.timeout(c.cfg.QueryTimeout))
+	if err != nil || query.Expected == nil {
+		return
+	}
+
+	expected := func(ts time.Time) float64 {
+		return query.Expected.value(c.cfg.Values.sum(ts, c.cfg.Schedule.seriesCount(ts, c.cfg.ExpectedSeries)))
+	}
+	if query.Window > 0 {
+		expected = expectedAverageOverWindow(expected, query.Window, c.cfg.ExpectedWriteInterval)
+	}
+
+	samples, err := singleSeriesSamples(matrix)
+	if err == nil {
+		err = verifySamples(samples, step, expected, c.comparisonDelta)
+	}
+	c.recordComparison(query.Query, err)
+}
+
+// expectedAverageOverWindow returns the function computing the average of the values returned by
+// expected at the write timestamps within the window ending at each timestamp. Both ends of the
+// window are included, like PromQL range selectors do in Cortex.
+func expectedAverageOverWindow(expected func(ts time.Time) float64, window, writeInterval time.Duration) func(ts time.Time) float64 {
+	return func(ts time.Time) float64 {
+		last := alignTimestampToInterval(ts, writeInterval)
+
+		sum, count := 0.0, 0
+		for t := last; !t.Before(ts.Add(-window)); t = t.Add(-writeInterval) {
+			sum += expected(t)
+			count++
+		}
+
+		// The window is shorter than the write interval and contains no write timestamp.
+		if count == 0 {
+			return expected(last)
+		}
+
+		return sum / float64(count)
+	}
+}
+
+func (c *QueryClient) runQueryAndCollectStats(start, end time.Time, step time.Duration, query string, timeout time.Duration) (model.Matrix, error) {
+	matrix, err := c.runQuery(start, end, step, query, timeout)
+	c.recordQuery(query, err)
+
+	return matrix, err
+}
+
+// recordQuery tracks the result of executing a query.
+func (c *QueryClient) recordQuery(query string, err error) {
 	if err != nil {
 		level.Error(c.logger).Log("msg", "failed to execute query", "err", err, "query", query)
 		c.queriesTotal.WithLabelValues(queryFailed, query).Inc()
+		atomic.AddInt64(&c.failedQueries, 1)
+		c.setLastError(query, err)
+		return
 	}
 
 	c.queriesTotal.WithLabelValues(querySuccess, query).Inc()
+}
 
-	return samples, err
+// singleSeriesSamples returns the samples of the only series in the matrix, or an error if
+// the matrix doesn't contain exactly 1 series.
+func singleSeriesSamples(matrix model.Matrix) ([]model.SamplePair, error) {
+	if len(matrix) != 1 {
+		return nil, fmt.Errorf("expected 1 series in the result but got %d", len(matrix))
+	}
+
+	return matrix[0].Values, nil
 }
 
-func (c *QueryClient) runQuery(start, end time.Time, step time.Duration, query string) ([]model.SamplePair, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.QueryTimeout)
+func (c *QueryClient) runQuery(start, end time.Time, step time.Duration, query string, timeout time.Duration) (model.Matrix, error) {
+	return c.runQueryOn(c.client, start, end, step, query, timeout)
+}
+
+// runQueryOn runs the range query against the query endpoint of the input client.
+func (c *QueryClient) runQueryOn(client v1.API, start, end time.Time, step time.Duration, query string, timeout time.Duration) (model.Matrix, error) {
+	ctx, cancel, done := c.queryContext(query, timeout)
 	defer cancel()
 
-	value, _, err := c.client.QueryRange(ctx, query, v1.Range{
+	value, _, err := client.QueryRange(ctx, query, v1.Range{
 		Start: start,
 		End:   end,
 		Step:  step,
 	})
+	done()
 	if err != nil {
 		return nil, err
 	}
@@ -208,16 +813,52 @@ func (c *QueryClient) runQuery(start, end time.Time, step time.Duration, query s
 		return nil, errors.New("failed to cast type to Matrix")
 	}
 
-	if len(matrix) != 1 {
-		return nil, fmt.Errorf("expected 1 series in the result but got %d", len(matrix))
+	return matrix, nil
+}
+
+// queryContext returns the context to run the input query with, bypassing the results cache if
+// configured, and the function to call once the query completed to track its duration.
+func (c *QueryClient) queryContext(query string, timeout time.Duration) (context.Context, context.CancelFunc, func()) {
+	ctx, cancel := context.WithTimeout(withQuery(context.Background(), query), timeout)
+
+	cache := queryCacheDefault
+	if c.shouldBypassCache() {
+		ctx = withCacheBypass(ctx)
+		cache = queryCacheBypassed
 	}
 
-	var result []model.SamplePair
-	for _, stream := range matrix {
-		result = append(result, stream.Values...)
+	start := time.Now()
+	return ctx, cancel, func() {
+		c.queryDuration.WithLabelValues(cache).Observe(time.Since(start).Seconds())
+	}
+}
+
+// shouldBypassCache returns whether the next query should bypass the results cache.
+func (c *QueryClient) shouldBypassCache() bool {
+	switch {
+	case c.cfg.QueryNoCache:
+		return true
+	case c.cfg.QueryNoCacheAlternate:
+		return atomic.AddUint64(&c.queriesCount, 1)%2 == 0
+	default:
+		return false
 	}
+}
+
+// queryConcurrency returns the number of concurrent copies of each query to run.
+func queryConcurrency(concurrency int) int {
+	if concurrency < 1 {
+		return 1
+	}
+	return concurrency
+}
 
-	return result, nil
+// maxQueriesInFlight returns the max number of queries in flight at the same time.
+func (cfg QueryClientConfig) maxQueriesInFlight() int {
+	if cfg.QueryConcurrencyLimit > 0 {
+		return cfg.QueryConcurrencyLimit
+	}
+	return queryConcurrency(cfg.QueryConcurrency)
 }
 
 func (c *QueryClient) getQueryTimeRange(now time.Time) (start, end time.Time, ok bool) {
@@ -228,11 +869,19 @@ func (c *QueryClient) getQueryTimeRange(now time.Time) (start, end time.Time, ok
 	// Do not query before the start time because the config may have been different (eg. number of series).
 	// Also give a 2 write intervals grace period to let the initial writes to succeed and honor the configured max age.
 	start = now.Add(-c.cfg.QueryMaxAge)
-	if startTimeWithGrace := c.startTime.Add(2 * c.cfg.ExpectedWriteInterval); startTimeWithGrace.After(start) {
+	if startTimeWithGrace := c.startTime.Add(2 * c.cfg.ExpectedWriteInterval); startTimeWithGrace.After(start) && !c.cfg.ExternalWrites {
 		start = startTimeWithGrace
 	}
 	start = alignTimestampToInterval(start, c.cfg.ExpectedWriteInterval)
 
+	// Do not query before the metric names active at the end have been rotated, since they
+	// didn't exist yet.
+	if c.cfg.NameRotationPeriod > 0 {
+		if rotationStart := metricNameRotationStart(end, c.cfg.NameRotationPeriod); rotationStart.After(start) {
+			start = rotationStart
+		}
+	}
+
 	// The query should run only if we have a valid range to query.
 	ok = end.After(start)
 
@@ -240,48 +889,258 @@ func (c *QueryClient) getQueryTimeRange(now time.Time) (start, end time.Time, ok
 }
 
 func (c *QueryClient) getQueryStep(start, end time.Time, writeInterval time.Duration) time.Duration {
-	const maxSamples = 1000
+	maxSamples := c.cfg.QueryMaxSamples
+	if maxSamples <= 0 {
+		maxSamples = defaultQueryMaxSamples
+	}
 
 	// Compute the number of samples that we would have if we every single sample.
+	step := writeInterval
 	actualSamples := end.Sub(start) / writeInterval
-	if actualSamples <= maxSamples {
-		return writeInterval
+	if actualSamples > time.Duration(maxSamples) {
+		// Adjust the query step based on the max steps spread over the query time range,
+		// rounding it to write interval.
+		step = end.Sub(start) / time.Duration(maxSamples)
+		step = ((step / writeInterval) + 1) * writeInterval
 	}
 
-	// Adjust the query step based on the max steps spread over the query time range,
-	// rounding it to write interval.
-	step := end.Sub(start) / time.Duration(maxSamples)
-	step = ((step / writeInterval) + 1) * writeInterval
+	// Honor the min step, rounding it up to write interval.
+	if c.cfg.QueryMinStep > step {
+		step = ((c.cfg.QueryMinStep + writeInterval - 1) / writeInterval) * writeInterval
+	}
 
 	return step
 }
 
-func verifySineWaveSamples(samples []model.SamplePair, expectedSeries int, expectedStep time.Duration) error {
-	for idx, sample := range samples {
-		ts := time.UnixMilli(int64(sample.Timestamp)).UTC()
+// verifySineWaveSamples verifies the samples against the expected sum of sine wave series, where
+// the number of series changes over time according to the schedule, up to expectedSeries. The
+// absolute difference between each actual and expected value is observed in deltas, if not nil.
+func verifySineWaveSamples(samples []model.SamplePair, expectedSeries int, schedule SeriesSchedule, expectedStep time.Duration, values ValueConfig, deltas prometheus.Observer) error {
+	return verifySamples(samples, expectedStep, expectedSineWaveSum(expectedSeries, schedule, values), deltas)
+}
+
+// expectedSineWaveSum returns the function computing the expected sum of sine wave series at each
+// timestamp, where the number of series changes over time according to the schedule.
+func expectedSineWaveSum(expectedSeries int, schedule SeriesSchedule, values ValueConfig) func(ts time.Time) float64 {
+	return func(ts time.Time) float64 {
+		return values.sum(ts, schedule.seriesCount(ts, expectedSeries))
+	}
+}
 
-		// Assert on value.
-		expectedValue := generateSineWaveValue(ts)
-		if !compareSampleValues(float64(sample.Value), expectedValue*float64(expectedSeries)) {
-			return fmt.Errorf("sample at timestamp %d (%s) has value %f while was expecting %f", sample.Timestamp, ts.String(), sample.Value, expectedValue)
+// verifySineWaveMatrix verifies each sine wave series in the matrix individually, given the series
+// ID in its wave label. The number of series is expected to be the one at the last sample timestamp,
+// according to the schedule, up to expectedSeries.
+func verifySineWaveMatrix(matrix model.Matrix, expectedSeries int, schedule SeriesSchedule, expectedStep time.Duration, values ValueConfig, deltas prometheus.Observer) error {
+	var lastTimestamp model.Time
+
+	for _, stream := range matrix {
+		seriesID, err := strconv.Atoi(string(stream.Metric["wave"]))
+		if err != nil {
+			return fmt.Errorf("series %s has an invalid wave label: %w", stream.Metric, err)
 		}
 
-		// Assert on sample timestamp. We expect no gaps.
-		if idx > 0 {
-			prevTs := time.UnixMilli(int64(samples[idx-1].Timestamp)).UTC()
-			expectedTs := prevTs.Add(expectedStep)
+		if err := verifySamples(stream.Values, expectedStep, func(ts time.Time) float64 {
+			return values.value(ts, seriesID)
+		}, deltas); err != nil {
+			return fmt.Errorf("series %s: %w", stream.Metric, err)
+		}
 
-			if ts.UnixMilli() != expectedTs.UnixMilli() {
-				return fmt.Errorf("sample at timestamp %d (%s) was expected to have timestamp %d (%s) because previous sample had timestamp %d (%s)",
-					sample.Timestamp, ts.String(), expectedTs.UnixMilli(), expectedTs.String(), prevTs.UnixMilli(), prevTs.String())
-			}
+		if n := len(stream.Values); n > 0 && stream.Values[n-1].Timestamp > lastTimestamp {
+			lastTimestamp = stream.Values[n-1].Timestamp
 		}
 	}
 
+	if expected := schedule.seriesCount(lastTimestamp.Time(), expectedSeries); len(matrix) != expected {
+		return fmt.Errorf("expected %d series in the result but got %d", expected, len(matrix))
+	}
+
+	return nil
+}
+
+// verifyRecordedSamples verifies the samples against the values recorded by the write client.
+func verifyRecordedSamples(samples []model.SamplePair, expectedStep time.Duration, recorder *Recorder, deltas prometheus.Observer) error {
+	for _, sample := range samples {
+		if _, ok := recorder.Value(sample.Timestamp.Time()); !ok {
+			return fmt.Errorf("sample at timestamp %d (%s) has no recorded value", sample.Timestamp, sample.Timestamp.Time().UTC().String())
+		}
+	}
+
+	return verifySamples(samples, expectedStep, func(ts time.Time) float64 {
+		value, _ := recorder.Value(ts)
+		return value
+	}, deltas)
+}
+
+// verifyAveragedSineWaveSamples is like verifySineWaveSamples, but expects each sample to be the
+// average of the sum of sine wave series over the step ending at its timestamp, like the
+// samples returned by backends serving downsampled (rolled-up) data.
+func verifyAveragedSineWaveSamples(samples []model.SamplePair, expectedSeries int, schedule SeriesSchedule, expectedStep time.Duration, values ValueConfig, deltas prometheus.Observer) error {
+	return verifySamples(samples, expectedStep, expectedAveragedSineWaveSum(expectedSeries, schedule, expectedStep, values), deltas)
+}
+
+// expectedAveragedSineWaveSum returns the function computing the expected average of the sum of
+// sine wave series over the step ending at each timestamp.
+func expectedAveragedSineWaveSum(expectedSeries int, schedule SeriesSchedule, expectedStep time.Duration, values ValueConfig) func(ts time.Time) float64 {
+	sum := expectedSineWaveSum(expectedSeries, schedule, values)
+
+	return func(ts time.Time) float64 {
+		return averageOverInterval(ts.Add(-expectedStep), ts, sum)
+	}
+}
+
+// averageOverInterval returns the average of fn over the [start, end] interval, integrating
+// it with the Simpson's rule.
+func averageOverInterval(start, end time.Time, fn func(t time.Time) float64) float64 {
+	const intervals = 100 // Must be even.
+
+	width := end.Sub(start)
+	if width <= 0 {
+		return fn(end)
+	}
+
+	h := width / intervals
+	sum := fn(start) + fn(end)
+	for i := 1; i < intervals; i++ {
+		weight := 2.0
+		if i%2 == 1 {
+			weight = 4.0
+		}
+		sum += weight * fn(start.Add(time.Duration(i)*h))
+	}
+
+	// The average is the integral divided by the interval width, so the width cancels out.
+	return sum / (3 * intervals)
+}
+
+// verifySamples verifies the samples have the value returned by expected at their timestamp,
+// and they're spaced by expectedStep. The absolute difference between each actual and expected
+// value is observed in deltas, if not nil.
+func verifySamples(samples []model.SamplePair, expectedStep time.Duration, expected func(ts time.Time) float64, deltas prometheus.Observer) error {
+	// Samples out of order would otherwise be reported as a gap.
+	if err := verifySamplesOrder(samples); err != nil {
+		return err
+	}
+
+	v := sampleVerifier{expectedStep: expectedStep, expected: expected, deltas: deltas}
+	for _, sample := range samples {
+		if err := v.add(sample); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sampleVerifier verifies samples one at a time, in the order they're read, so that they
+// don't need to be buffered. The checks are the same of verifySamples.
+type sampleVerifier struct {
+	expectedStep time.Duration
+	expected     func(ts time.Time) float64
+	deltas       prometheus.Observer
+
+	// The previous sample timestamp, if any.
+	prev    model.Time
+	hasPrev bool
+}
+
+func (v *sampleVerifier) add(sample model.SamplePair) error {
+	ts := time.UnixMilli(int64(sample.Timestamp)).UTC()
+
+	// Assert on value.
+	expectedValue := v.expected(ts)
+	if v.deltas != nil {
+		v.deltas.Observe(math.Abs(float64(sample.Value) - expectedValue))
+	}
+	if !compareSampleValues(float64(sample.Value), expectedValue) {
+		return comparisonError{kind: comparisonValueMismatch, msg: fmt.Sprintf("sample at timestamp %d (%s) has value %f while was expecting %f", sample.Timestamp, ts.String(), sample.Value, expectedValue)}
+	}
+
+	// Assert on sample timestamp. We expect samples in order, no duplicates and no gaps.
+	if v.hasPrev {
+		prevTs := time.UnixMilli(int64(v.prev)).UTC()
+		expectedTs := prevTs.Add(v.expectedStep)
+
+		if ts.UnixMilli() == prevTs.UnixMilli() {
+			return comparisonError{kind: comparisonDuplicateTimestamp, msg: fmt.Sprintf("sample at timestamp %d (%s) has a duplicated timestamp of the previous sample", sample.Timestamp, ts.String())}
+		}
+
+		if ts.Before(prevTs) {
+			return outOfOrderError(sample.Timestamp, v.prev)
+		}
+
+		if ts.UnixMilli() != expectedTs.UnixMilli() {
+			return comparisonError{kind: comparisonTimestampGap, msg: fmt.Sprintf("sample at timestamp %d (%s) was expected to have timestamp %d (%s) because previous sample had timestamp %d (%s)",
+				sample.Timestamp, ts.String(), expectedTs.UnixMilli(), expectedTs.String(), prevTs.UnixMilli(), prevTs.String())}
+		}
+	}
+
+	v.prev = sample.Timestamp
+	v.hasPrev = true
+
 	return nil
 }
 
+// verifySamplesOrder verifies the samples timestamps are not decreasing. Duplicated timestamps
+// are reported by verifySamples.
+func verifySamplesOrder(samples []model.SamplePair) error {
+	for idx := 1; idx < len(samples); idx++ {
+		if samples[idx].Timestamp < samples[idx-1].Timestamp {
+			return outOfOrderError(samples[idx].Timestamp, samples[idx-1].Timestamp)
+		}
+	}
+
+	return nil
+}
+
+func outOfOrderError(ts, prevTs model.Time) error {
+	return comparisonError{kind: comparisonOutOfOrder, msg: fmt.Sprintf("sample at timestamp %d (%s) is out of order because previous sample had timestamp %d (%s)",
+		ts, ts.Time().UTC().String(), prevTs, prevTs.Time().UTC().String())}
+}
+
+// comparisonError is returned when the query results don't match the expected ones.
+type comparisonError struct {
+	kind string
+	msg  string
+}
+
+func (e comparisonError) Error() string {
+	return e.msg
+}
+
+// comparisonFailureKind returns the kind label value for a comparison which failed with err.
+func comparisonFailureKind(err error) string {
+	var compErr comparisonError
+	if errors.As(err, &compErr) {
+		return compErr.kind
+	}
+
+	return comparisonOther
+}
+
 func compareSampleValues(actual, expected float64) bool {
 	delta := math.Abs((actual - expected) / maxComparisonDelta)
 	return delta < maxComparisonDelta
 }
+
+// verifyChecksums verifies the value of each sample matches the checksum label of its series.
+func verifyChecksums(matrix model.Matrix) error {
+	if len(matrix) == 0 {
+		return errors.New("expected at least 1 series in the result but got 0")
+	}
+
+	for _, stream := range matrix {
+		checksum, ok := stream.Metric[checksumLabelName]
+		if !ok {
+			return fmt.Errorf("series %s has no %s label", stream.Metric, checksumLabelName)
+		}
+
+		for _, sample := range stream.Values {
+			if actual := valueChecksum(float64(sample.Value)); actual != string(checksum) {
+				return comparisonError{kind: comparisonValueMismatch, msg: fmt.Sprintf("sample at timestamp %d of series %s has value %f with checksum %s not matching the expected %s",
+					sample.Timestamp, stream.Metric, sample.Value, actual, checksum)}
+			}
+		}
+	}
+
+	return nil
+}
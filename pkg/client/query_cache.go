@@ -0,0 +1,49 @@
+package client
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log/level"
+)
+
+// Results of the results cache consistency check.
+const (
+	cacheConsistencySuccess   = "success"
+	cacheConsistencyMismatch  = "mismatch"
+	cacheConsistencyNotFaster = "not_faster"
+)
+
+var cacheConsistencyResults = []string{cacheConsistencySuccess, cacheConsistencyMismatch, cacheConsistencyNotFaster}
+
+// runCacheConsistencyCheck runs the default query twice in a row, and verifies the second run,
+// expected to be served from the query-frontend results cache, is faster and returns the same
+// result of the first one.
+func (c *QueryClient) runCacheConsistencyCheck(start, end time.Time, step time.Duration) {
+	query := c.defaultQuery
+
+	firstStart := time.Now()
+	first, err := c.runQueryAndCollectStats(start, end, step, query, c.cfg.QueryTimeout)
+	if err != nil {
+		return
+	}
+	firstDuration := time.Since(firstStart)
+
+	secondStart := time.Now()
+	second, err := c.runQueryAndCollectStats(start, end, step, query, c.cfg.QueryTimeout)
+	if err != nil {
+		return
+	}
+	secondDuration := time.Since(secondStart)
+
+	result := cacheConsistencySuccess
+	if err := compareMatrices(first, second); err != nil {
+		result = cacheConsistencyMismatch
+		level.Warn(c.logger).Log("msg", "query results cache consistency check failed", "err", fmt.Errorf("the cached result differs from the original one: %w", err), "query", query)
+	} else if secondDuration >= firstDuration {
+		result = cacheConsistencyNotFaster
+		level.Warn(c.logger).Log("msg", "query results cache consistency check failed because the cached query wasn't faster", "query", query, "first_duration", firstDuration, "second_duration", secondDuration)
+	}
+
+	c.queryCacheConsistency.WithLabelValues(result).Inc()
+}
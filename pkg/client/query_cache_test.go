@@ -0,0 +1,85 @@
+package client
+
+import (
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryClient_ShouldVerifyResultsCacheConsistency(t *testing.T) {
+	const numSeries = 2
+
+	tests := map[string]struct {
+		// The latency and value offset of the response to the cached (second) query.
+		cachedLatency  time.Duration
+		cachedOffset   float64
+		expectedResult string
+	}{
+		"cached result identical and faster": {
+			cachedLatency:  0,
+			expectedResult: cacheConsistencySuccess,
+		},
+		"cached result differs": {
+			cachedLatency:  0,
+			cachedOffset:   1,
+			expectedResult: cacheConsistencyMismatch,
+		},
+		"cached query not faster": {
+			cachedLatency:  200 * time.Millisecond,
+			expectedResult: cacheConsistencyNotFaster,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var requests int64
+
+			// The mock serves the first query slowly, like a cache miss, and the second one from the cache.
+			server := httptest.NewServer(newQueryRangeHandler(func(query string, start, end time.Time, step time.Duration) model.Matrix {
+				latency, offset := 100*time.Millisecond, 0.0
+				if atomic.AddInt64(&requests, 1) == 2 {
+					latency, offset = testData.cachedLatency, testData.cachedOffset
+				}
+				time.Sleep(latency)
+
+				stream := &model.SampleStream{Metric: model.Metric{}}
+				for ts := start; !ts.After(end); ts = ts.Add(step) {
+					stream.Values = append(stream.Values, newSamplePair(ts, numSeries*generateSineWaveValue(ts)+offset))
+				}
+				return model.Matrix{stream}
+			}))
+			t.Cleanup(server.Close)
+
+			client := NewQueryClient(QueryClientConfig{
+				URL:                   server.URL,
+				UserID:                "user-1",
+				QueryTimeout:          time.Second,
+				QueryMaxAge:           time.Hour,
+				ExpectedSeries:        numSeries,
+				ExpectedWriteInterval: 10 * time.Second,
+				VerifyResultsCache:    true,
+			}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+			client.startTime = time.Now().Add(-time.Hour)
+
+			start, end, _ := client.getQueryTimeRange(time.Now())
+			client.runCacheConsistencyCheck(start, end, client.getQueryStep(start, end, 10*time.Second))
+
+			assert.Equal(t, int64(2), atomic.LoadInt64(&requests))
+			for _, result := range cacheConsistencyResults {
+				expected := 0.0
+				if result == testData.expectedResult {
+					expected = 1
+				}
+				assert.Equal(t, expected, testutil.ToFloat64(client.queryCacheConsistency.WithLabelValues(result)), result)
+			}
+			assert.Equal(t, 2.0, testutil.ToFloat64(client.queriesTotal.WithLabelValues(querySuccess, client.defaultQuery)))
+		})
+	}
+}
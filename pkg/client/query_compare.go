@@ -0,0 +1,70 @@
+package client
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// runBackendsComparison runs the query against both the primary and secondary query endpoints,
+// and verifies their results match.
+func (c *QueryClient) runBackendsComparison(start, end time.Time, step time.Duration, query string, timeout time.Duration) {
+	primary, err := c.runQueryAndCollectStats(start, end, step, query, timeout)
+	if err != nil {
+		return
+	}
+
+	secondary, err := c.runQueryOn(c.secondaryClient, start, end, step, query, timeout)
+	if err != nil {
+		err = fmt.Errorf("secondary query endpoint: %w", err)
+	}
+	c.recordQuery(query, err)
+	if err != nil {
+		return
+	}
+
+	err = compareMatrices(primary, secondary)
+	if err != nil {
+		c.backendMismatches.WithLabelValues(query).Inc()
+	}
+	c.recordComparison(query, err)
+}
+
+// compareMatrices verifies the secondary matrix has the same series of the primary one, with
+// the same sample timestamps and values within the comparison tolerance. Series are matched by
+// their labels, regardless of their order in the matrices.
+func compareMatrices(primary, secondary model.Matrix) error {
+	if len(primary) != len(secondary) {
+		return fmt.Errorf("primary query endpoint returned %d series but secondary returned %d", len(primary), len(secondary))
+	}
+
+	secondaryByMetric := make(map[model.Fingerprint]*model.SampleStream, len(secondary))
+	for _, stream := range secondary {
+		secondaryByMetric[stream.Metric.Fingerprint()] = stream
+	}
+
+	for _, expected := range primary {
+		actual, ok := secondaryByMetric[expected.Metric.Fingerprint()]
+		if !ok || !actual.Metric.Equal(expected.Metric) {
+			return fmt.Errorf("series %s returned by the primary query endpoint is missing in the secondary one", expected.Metric)
+		}
+
+		if len(actual.Values) != len(expected.Values) {
+			return fmt.Errorf("series %s has %d samples in the primary query endpoint but %d in the secondary one", expected.Metric, len(expected.Values), len(actual.Values))
+		}
+
+		for i, expectedSample := range expected.Values {
+			actualSample := actual.Values[i]
+
+			if actualSample.Timestamp != expectedSample.Timestamp {
+				return comparisonError{kind: comparisonTimestampGap, msg: fmt.Sprintf("series %s has a sample at timestamp %d in the secondary query endpoint while was expecting timestamp %d", expected.Metric, actualSample.Timestamp, expectedSample.Timestamp)}
+			}
+			if !compareSampleValues(float64(actualSample.Value), float64(expectedSample.Value)) {
+				return comparisonError{kind: comparisonValueMismatch, msg: fmt.Sprintf("series %s has value %f at timestamp %d in the secondary query endpoint while was expecting %f", expected.Metric, actualSample.Value, actualSample.Timestamp, expectedSample.Value)}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package client
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareMatrices(t *testing.T) {
+	ts := time.Unix(3600, 0)
+
+	matrix := func(values ...float64) model.Matrix {
+		out := model.Matrix{}
+		for idx, value := range values {
+			out = append(out, &model.SampleStream{
+				Metric: model.Metric{"wave": model.LabelValue(strconv.Itoa(idx + 1))},
+				Values: []model.SamplePair{newSamplePair(ts, value), newSamplePair(ts.Add(time.Minute), value)},
+			})
+		}
+		return out
+	}
+
+	tests := map[string]struct {
+		primary     model.Matrix
+		secondary   model.Matrix
+		expectedErr string
+	}{
+		"matching matrices": {
+			primary:   matrix(1, 2),
+			secondary: matrix(1, 2),
+		},
+		"matching matrices with series in different order": {
+			primary:   matrix(1, 2),
+			secondary: model.Matrix{matrix(1, 2)[1], matrix(1, 2)[0]},
+		},
+		"values within tolerance": {
+			primary:   matrix(1, 2),
+			secondary: matrix(1+1e-12, 2),
+		},
+		"different number of series": {
+			primary:     matrix(1, 2),
+			secondary:   matrix(1),
+			expectedErr: "primary query endpoint returned 2 series but secondary returned 1",
+		},
+		"different series": {
+			primary:     matrix(1),
+			secondary:   model.Matrix{{Metric: model.Metric{"wave": "other"}, Values: matrix(1)[0].Values}},
+			expectedErr: `series {wave="1"} returned by the primary query endpoint is missing in the secondary one`,
+		},
+		"different number of samples": {
+			primary:     matrix(1),
+			secondary:   model.Matrix{{Metric: matrix(1)[0].Metric, Values: matrix(1)[0].Values[:1]}},
+			expectedErr: `series {wave="1"} has 2 samples in the primary query endpoint but 1 in the secondary one`,
+		},
+		"different values": {
+			primary:     matrix(1, 2),
+			secondary:   matrix(1, 3),
+			expectedErr: `series {wave="2"} has value 3.000000 at timestamp 3600000 in the secondary query endpoint while was expecting 2.000000`,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			err := compareMatrices(testData.primary, testData.secondary)
+			if testData.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, testData.expectedErr)
+			}
+		})
+	}
+}
+
+func TestQueryClient_ShouldCompareQueryResultsOfTwoBackends(t *testing.T) {
+	const (
+		matchingQuery  = "sum(cortex_load_generator_sine_wave)"
+		divergingQuery = "avg(cortex_load_generator_sine_wave)"
+	)
+
+	// Both backends return a sine wave, but the secondary one returns a different value for one query.
+	newBackend := func(divergingFactor float64) *httptest.Server {
+		return httptest.NewServer(newQueryRangeHandler(func(query string, start, end time.Time, step time.Duration) model.Matrix {
+			factor := 1.0
+			if query == divergingQuery {
+				factor = divergingFactor
+			}
+
+			stream := &model.SampleStream{Metric: model.Metric{}}
+			for ts := start; !ts.After(end); ts = ts.Add(step) {
+				stream.Values = append(stream.Values, newSamplePair(ts, factor*generateSineWaveValue(ts)))
+			}
+			return model.Matrix{stream}
+		}))
+	}
+
+	primary := newBackend(1)
+	t.Cleanup(primary.Close)
+	secondary := newBackend(2)
+	t.Cleanup(secondary.Close)
+
+	client := NewQueryClient(QueryClientConfig{
+		URL:                   primary.URL,
+		SecondaryURL:          secondary.URL,
+		UserID:                "user-1",
+		QueryTimeout:          time.Second,
+		QueryMaxAge:           time.Hour,
+		ExpectedSeries:        4,
+		ExpectedWriteInterval: 10 * time.Second,
+		AdditionalQueries:     []AdditionalQuery{{Query: divergingQuery}},
+	}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	client.startTime = time.Now().Add(-time.Hour)
+
+	client.runQueries()
+
+	// The default query returns the same results on both backends, while it would fail the
+	// verification against the expected sum of 4 series.
+	assert.Equal(t, matchingQuery, client.defaultQuery)
+	assert.Equal(t, 1.0, testutil.ToFloat64(client.resultsComparedTotal.WithLabelValues(comparisonSuccess, matchingQuery)))
+	assert.Equal(t, 0.0, testutil.ToFloat64(client.backendMismatches.WithLabelValues(matchingQuery)))
+
+	// The additional query returns different results.
+	assert.Equal(t, 1.0, testutil.ToFloat64(client.resultsComparedTotal.WithLabelValues(comparisonFailed, divergingQuery)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(client.backendMismatches.WithLabelValues(divergingQuery)))
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// queryError is the last error of a query.
+type queryError struct {
+	User      string    `json:"user"`
+	Query     string    `json:"query"`
+	Error     string    `json:"error"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type queryErrorsResponse struct {
+	Errors []queryError `json:"errors"`
+}
+
+// setLastError stores the input error as the last one of the query.
+func (c *QueryClient) setLastError(query string, err error) {
+	c.lastErrorsMx.Lock()
+	defer c.lastErrorsMx.Unlock()
+
+	c.lastErrors[query] = queryError{
+		User:      c.cfg.UserID,
+		Query:     query,
+		Error:     err.Error(),
+		Timestamp: time.Now().UTC(),
+	}
+}
+
+// getLastErrors returns the last error of each query which failed at least once.
+func (c *QueryClient) getLastErrors() []queryError {
+	c.lastErrorsMx.Lock()
+	defer c.lastErrorsMx.Unlock()
+
+	out := make([]queryError, 0, len(c.lastErrors))
+	for _, err := range c.lastErrors {
+		out = append(out, err)
+	}
+
+	return out
+}
+
+// QueryErrorsHandler returns an HTTP handler responding with the last error, either a failed
+// query or a failed result comparison, of each query of all the input clients.
+func QueryErrorsHandler(clients []*QueryClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := queryErrorsResponse{Errors: []queryError{}}
+		for _, c := range clients {
+			res.Errors = append(res.Errors, c.getLastErrors()...)
+		}
+
+		sort.Slice(res.Errors, func(i, j int) bool {
+			if res.Errors[i].User != res.Errors[j].User {
+				return res.Errors[i].User < res.Errors[j].User
+			}
+			return res.Errors[i].Query < res.Errors[j].Query
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(res); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryErrorsHandler(t *testing.T) {
+	const numSeries = 3
+
+	// Return a wrong sum, so that the comparison fails.
+	server := httptest.NewServer(newQueryRangeHandler(func(query string, start, end time.Time, step time.Duration) model.Matrix {
+		stream := &model.SampleStream{Metric: model.Metric{}}
+		for ts := start; !ts.After(end); ts = ts.Add(step) {
+			stream.Values = append(stream.Values, newSamplePair(ts, numSeries*generateSineWaveValue(ts)+1))
+		}
+		return model.Matrix{stream}
+	}))
+	t.Cleanup(server.Close)
+
+	newClient := func(userID string) *QueryClient {
+		c := NewQueryClient(QueryClientConfig{
+			URL:                   server.URL,
+			UserID:                userID,
+			QueryTimeout:          time.Second,
+			QueryMaxAge:           time.Hour,
+			ExpectedSeries:        numSeries,
+			ExpectedWriteInterval: 10 * time.Second,
+		}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+		c.startTime = time.Now().Add(-time.Hour)
+		return c
+	}
+
+	failing, idle := newClient("user-1"), newClient("user-2")
+	clients := []*QueryClient{failing, idle}
+
+	getErrors := func() []queryError {
+		rec := httptest.NewRecorder()
+		QueryErrorsHandler(clients).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/query-errors", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+		var res queryErrorsResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		return res.Errors
+	}
+
+	// No errors before running any query.
+	assert.Empty(t, getErrors())
+
+	before := time.Now().UTC()
+	failing.runQueries()
+
+	errs := getErrors()
+	require.Len(t, errs, 1)
+	assert.Equal(t, "user-1", errs[0].User)
+	assert.Equal(t, failing.defaultQuery, errs[0].Query)
+	assert.Contains(t, errs[0].Error, "while was expecting")
+	assert.False(t, errs[0].Timestamp.Before(before))
+}
@@ -0,0 +1,189 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// errStopStreaming is returned by streaming callbacks to stop reading the query response.
+var errStopStreaming = errors.New("stop streaming")
+
+// runStreamingQuery runs a range query and decodes the response while it's read, calling
+// onSeries for each series in the result and onSample for each of its samples, so that the
+// result is never fully buffered in memory. If a callback returns errStopStreaming, the
+// response is not read further and no error is returned.
+func (c *QueryClient) runStreamingQuery(start, end time.Time, step time.Duration, query string, timeout time.Duration, onSeries func(model.Metric) error, onSample func(model.SamplePair) error) error {
+	ctx, cancel, done := c.queryContext(query, timeout)
+	defer cancel()
+	defer done()
+
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("start", formatQueryTime(start))
+	params.Set("end", formatQueryTime(end))
+	params.Set("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(c.cfg.URL, "/")+"/api/v1/query_range", strings.NewReader(params.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	err = decodeMatrixStream(resp.Body, onSeries, onSample)
+	if errors.Is(err, errStopStreaming) {
+		return nil
+	}
+	if err != nil && resp.StatusCode/100 != 2 {
+		return fmt.Errorf("query failed with status code %d: %w", resp.StatusCode, err)
+	}
+
+	return err
+}
+
+func formatQueryTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixMilli())/1000, 'f', -1, 64)
+}
+
+// decodeMatrixStream decodes a range query response from r, calling onSeries for each series
+// and onSample for each of its samples, as they're read.
+func decodeMatrixStream(r io.Reader, onSeries func(model.Metric) error, onSample func(model.SamplePair) error) error {
+	dec := json.NewDecoder(r)
+
+	var status, errMsg, resultType string
+
+	err := decodeObject(dec, func(key string) error {
+		switch key {
+		case "status":
+			return dec.Decode(&status)
+		case "error":
+			return dec.Decode(&errMsg)
+		case "data":
+			return decodeObject(dec, func(key string) error {
+				switch key {
+				case "resultType":
+					if err := dec.Decode(&resultType); err != nil {
+						return err
+					}
+					if resultType != model.ValMatrix.String() {
+						return errors.New("was expecting to get a Matrix")
+					}
+					return nil
+				case "result":
+					return decodeMatrixResult(dec, onSeries, onSample)
+				default:
+					return skipValue(dec)
+				}
+			})
+		default:
+			return skipValue(dec)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if status != "success" {
+		return fmt.Errorf("query failed with status %q: %s", status, errMsg)
+	}
+
+	return nil
+}
+
+// decodeMatrixResult decodes the array of series of a matrix result.
+func decodeMatrixResult(dec *json.Decoder, onSeries func(model.Metric) error, onSample func(model.SamplePair) error) error {
+	return decodeArray(dec, func() error {
+		return decodeObject(dec, func(key string) error {
+			switch key {
+			case "metric":
+				var metric model.Metric
+				if err := dec.Decode(&metric); err != nil {
+					return err
+				}
+				return onSeries(metric)
+			case "values":
+				return decodeArray(dec, func() error {
+					var sample model.SamplePair
+					if err := dec.Decode(&sample); err != nil {
+						return err
+					}
+					return onSample(sample)
+				})
+			default:
+				return skipValue(dec)
+			}
+		})
+	})
+}
+
+// decodeObject reads a JSON object, calling fn with each key. fn must read the key value.
+func decodeObject(dec *json.Decoder, fn func(key string) error) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		key, ok := token.(string)
+		if !ok {
+			return fmt.Errorf("unexpected JSON token %v while was expecting an object key", token)
+		}
+
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+
+	return expectDelim(dec, '}')
+}
+
+// decodeArray reads a JSON array, calling fn for each item. fn must read the item.
+func decodeArray(dec *json.Decoder, fn func() error) error {
+	if err := expectDelim(dec, '['); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+
+	return expectDelim(dec, ']')
+}
+
+func expectDelim(dec *json.Decoder, expected json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	if delim, ok := token.(json.Delim); !ok || delim != expected {
+		return fmt.Errorf("unexpected JSON token %v while was expecting %v", token, expected)
+	}
+
+	return nil
+}
+
+func skipValue(dec *json.Decoder) error {
+	var skip json.RawMessage
+	return dec.Decode(&skip)
+}
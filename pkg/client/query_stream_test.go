@@ -0,0 +1,220 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryClient_StreamDefaultQuery(t *testing.T) {
+	const numSeries = 3
+
+	sumSeries := func(wave string, offset float64) func(string, time.Time, time.Time, time.Duration) *model.SampleStream {
+		return func(_ string, start, end time.Time, step time.Duration) *model.SampleStream {
+			stream := &model.SampleStream{Metric: model.Metric{"wave": model.LabelValue(wave)}}
+			for ts := start; !ts.After(end); ts = ts.Add(step) {
+				stream.Values = append(stream.Values, newSamplePair(ts, numSeries*generateSineWaveValue(ts)+offset))
+			}
+			return stream
+		}
+	}
+
+	tests := map[string]struct {
+		handler            http.Handler
+		expectedQueries    map[string]float64
+		expectedComparison map[string]float64
+	}{
+		"should succeed if the result matches the expected one": {
+			handler: newQueryRangeHandler(func(query string, start, end time.Time, step time.Duration) model.Matrix {
+				return model.Matrix{sumSeries("", 0)(query, start, end, step)}
+			}),
+			expectedQueries:    map[string]float64{querySuccess: 1, queryFailed: 0},
+			expectedComparison: map[string]float64{comparisonSuccess: 1, comparisonFailed: 0},
+		},
+		"should fail the comparison if the values don't match": {
+			handler: newQueryRangeHandler(func(query string, start, end time.Time, step time.Duration) model.Matrix {
+				return model.Matrix{sumSeries("", 1)(query, start, end, step)}
+			}),
+			expectedQueries:    map[string]float64{querySuccess: 1, queryFailed: 0},
+			expectedComparison: map[string]float64{comparisonSuccess: 0, comparisonFailed: 1},
+		},
+		"should fail the comparison if the result has more than 1 series": {
+			handler: newQueryRangeHandler(func(query string, start, end time.Time, step time.Duration) model.Matrix {
+				return model.Matrix{sumSeries("1", 0)(query, start, end, step), sumSeries("2", 0)(query, start, end, step)}
+			}),
+			expectedQueries:    map[string]float64{querySuccess: 1, queryFailed: 0},
+			expectedComparison: map[string]float64{comparisonSuccess: 0, comparisonFailed: 1},
+		},
+		"should fail the query if the response is an error": {
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				_, _ = w.Write([]byte(`{"status":"error","errorType":"execution","error":"query timed out"}`))
+			}),
+			expectedQueries:    map[string]float64{querySuccess: 0, queryFailed: 1},
+			expectedComparison: map[string]float64{comparisonSuccess: 0, comparisonFailed: 0},
+		},
+		"should fail the query if the response is truncated": {
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[{"metric":{},"values":[`))
+			}),
+			expectedQueries:    map[string]float64{querySuccess: 0, queryFailed: 1},
+			expectedComparison: map[string]float64{comparisonSuccess: 0, comparisonFailed: 0},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			server := httptest.NewServer(testData.handler)
+			t.Cleanup(server.Close)
+
+			client := NewQueryClient(QueryClientConfig{
+				URL:                   server.URL,
+				UserID:                "user-1",
+				QueryTimeout:          time.Second,
+				QueryMaxAge:           time.Hour,
+				ExpectedSeries:        numSeries,
+				ExpectedWriteInterval: 10 * time.Second,
+				StreamDefaultQuery:    true,
+			}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+			client.startTime = time.Now().Add(-time.Hour)
+
+			client.runQueries()
+
+			for result, expected := range testData.expectedQueries {
+				assert.Equal(t, expected, testutil.ToFloat64(client.queriesTotal.WithLabelValues(result, client.defaultQuery)), result)
+			}
+			for result, expected := range testData.expectedComparison {
+				assert.Equal(t, expected, testutil.ToFloat64(client.resultsComparedTotal.WithLabelValues(result, client.defaultQuery)), result)
+			}
+		})
+	}
+}
+
+func TestDecodeMatrixStream(t *testing.T) {
+	t.Run("should call the callbacks for each series and sample", func(t *testing.T) {
+		var actual []string
+
+		err := decodeMatrixStream(strings.NewReader(`{"status":"success","data":{"resultType":"matrix","result":[
+			{"metric":{"wave":"1"},"values":[[10,"1"],[20,"2"]]},
+			{"metric":{"wave":"2"},"values":[[10,"3"]]}
+		]}}`), func(metric model.Metric) error {
+			actual = append(actual, metric.String())
+			return nil
+		}, func(sample model.SamplePair) error {
+			actual = append(actual, sample.String())
+			return nil
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{`{wave="1"}`, "1 @[10]", "2 @[20]", `{wave="2"}`, "3 @[10]"}, actual)
+	})
+
+	t.Run("should return error if the result is not a matrix", func(t *testing.T) {
+		err := decodeMatrixStream(strings.NewReader(`{"status":"success","data":{"resultType":"vector","result":[]}}`), nil, nil)
+		assert.EqualError(t, err, "was expecting to get a Matrix")
+	})
+}
+
+func BenchmarkQueryClient_DefaultQuery(b *testing.B) {
+	const numSeries = 3
+
+	// Serve a large precomputed result, covering a week with a 10s step.
+	end := alignTimestampToInterval(time.Now(), 10*time.Second)
+	start := end.Add(-7 * 24 * time.Hour)
+
+	stream := &model.SampleStream{Metric: model.Metric{}}
+	for ts := start; !ts.After(end); ts = ts.Add(10 * time.Second) {
+		stream.Values = append(stream.Values, newSamplePair(ts, numSeries*generateSineWaveValue(ts)))
+	}
+
+	body := bytes.Buffer{}
+	require.NoError(b, json.NewEncoder(&body).Encode(map[string]interface{}{
+		"status": "success",
+		"data":   map[string]interface{}{"resultType": "matrix", "result": model.Matrix{stream}},
+	}))
+	stream = nil
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body.Bytes())
+	}))
+	b.Cleanup(server.Close)
+
+	for _, streaming := range []bool{false, true} {
+		b.Run("streaming="+strconv.FormatBool(streaming), func(b *testing.B) {
+			client := NewQueryClient(QueryClientConfig{
+				URL:                   server.URL,
+				UserID:                "user-1",
+				QueryTimeout:          time.Minute,
+				ExpectedSeries:        numSeries,
+				ExpectedWriteInterval: 10 * time.Second,
+				StreamDefaultQuery:    streaming,
+			}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			peak := trackPeakHeap(func() {
+				for n := 0; n < b.N; n++ {
+					client.runDefaultQuery(start, end, 10*time.Second)
+				}
+			})
+
+			b.ReportMetric(float64(peak), "peak-heap-B")
+
+			if testutil.ToFloat64(client.resultsComparedTotal.WithLabelValues(comparisonSuccess, client.defaultQuery)) != float64(b.N) {
+				b.Fatal("unexpected comparison failure")
+			}
+		})
+	}
+}
+
+// trackPeakHeap runs fn and returns the peak heap size growth observed while running it.
+func trackPeakHeap(fn func()) uint64 {
+	var stats runtime.MemStats
+
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	baseline := stats.HeapAlloc
+
+	var (
+		peak uint64
+		done = make(chan struct{})
+		stop int32
+	)
+
+	go func() {
+		defer close(done)
+
+		var stats runtime.MemStats
+		for atomic.LoadInt32(&stop) == 0 {
+			runtime.ReadMemStats(&stats)
+			if stats.HeapAlloc > baseline && stats.HeapAlloc-baseline > peak {
+				peak = stats.HeapAlloc - baseline
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	fn()
+	atomic.StoreInt32(&stop, 1)
+	<-done
+
+	return peak
+}
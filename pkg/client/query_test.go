@@ -1,13 +1,25 @@
 package client
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/go-kit/log"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestQueryClient_GetQueryTimeRange(t *testing.T) {
@@ -35,6 +47,22 @@ func TestQueryClient_GetQueryTimeRange(t *testing.T) {
 			expectedStart: alignTimestampToInterval(now.Add(-1*time.Hour).Add(2*10*time.Second), 10*time.Second),
 			expectedEnd:   alignTimestampToInterval(now.Add(-2*10*time.Second), 10*time.Second),
 		},
+		"should not bound the time range to the start time if series are written externally": {
+			cfg:           QueryClientConfig{ExpectedWriteInterval: 10 * time.Second, QueryMaxAge: 2 * time.Hour, ExternalWrites: true},
+			now:           now,
+			startTime:     now,
+			expectedOK:    true,
+			expectedStart: alignTimestampToInterval(now.Add(-2*time.Hour), 10*time.Second),
+			expectedEnd:   alignTimestampToInterval(now.Add(-2*10*time.Second), 10*time.Second),
+		},
+		"should not query before the metric names have been rotated": {
+			cfg:           QueryClientConfig{ExpectedWriteInterval: 10 * time.Second, QueryMaxAge: 2 * time.Hour, NameRotationPeriod: time.Hour},
+			now:           time.Date(2023, 6, 29, 10, 30, 0, 0, time.UTC),
+			startTime:     time.Date(2023, 6, 29, 8, 0, 0, 0, time.UTC),
+			expectedOK:    true,
+			expectedStart: time.Date(2023, 6, 29, 10, 0, 0, 0, time.UTC).Local(),
+			expectedEnd:   time.Date(2023, 6, 29, 10, 29, 40, 0, time.UTC).Local(),
+		},
 	}
 
 	for testName, testData := range tests {
@@ -58,6 +86,8 @@ func TestQueryClient_GetQueryStep(t *testing.T) {
 		start         time.Time
 		end           time.Time
 		writeInterval time.Duration
+		minStep       time.Duration
+		maxSamples    int
 		expectedStep  time.Duration
 	}{
 		"should return write interval if expected number of samples is < 1000": {
@@ -72,11 +102,41 @@ func TestQueryClient_GetQueryStep(t *testing.T) {
 			writeInterval: 10 * time.Second,
 			expectedStep:  90 * time.Second,
 		},
+		"should honor the configured max samples": {
+			start:         time.UnixMilli(0),
+			end:           time.UnixMilli(3600 * 1000),
+			writeInterval: 10 * time.Second,
+			maxSamples:    100,
+			expectedStep:  40 * time.Second,
+		},
+		"should honor the min step, rounding it up to the write interval": {
+			start:         time.UnixMilli(0),
+			end:           time.UnixMilli(3600 * 1000),
+			writeInterval: 10 * time.Second,
+			minStep:       25 * time.Second,
+			expectedStep:  30 * time.Second,
+		},
+		"should honor the min step over the max samples": {
+			start:         time.UnixMilli(0),
+			end:           time.UnixMilli(86400 * 1000),
+			writeInterval: 10 * time.Second,
+			minStep:       5 * time.Minute,
+			maxSamples:    1000,
+			expectedStep:  5 * time.Minute,
+		},
+		"should ignore the min step if lower than the step honoring the max samples": {
+			start:         time.UnixMilli(0),
+			end:           time.UnixMilli(86400 * 1000),
+			writeInterval: 10 * time.Second,
+			minStep:       time.Minute,
+			maxSamples:    1000,
+			expectedStep:  90 * time.Second,
+		},
 	}
 
 	for testName, testData := range tests {
 		t.Run(testName, func(t *testing.T) {
-			client := NewQueryClient(QueryClientConfig{}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+			client := NewQueryClient(QueryClientConfig{QueryMinStep: testData.minStep, QueryMaxSamples: testData.maxSamples}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
 
 			actualStep := client.getQueryStep(testData.start, testData.end, testData.writeInterval)
 			assert.Equal(t, testData.expectedStep, actualStep)
@@ -133,11 +193,107 @@ func TestVerifySineWaveSamples(t *testing.T) {
 			expectedStep:   10 * time.Second,
 			expectedErr:    "sample at timestamp .* was expected to have timestamp .*",
 		},
+		"should return error if there's a duplicated timestamp": {
+			samples: []model.SamplePair{
+				newSamplePair(now.Add(10*time.Second), 5*generateSineWaveValue(now.Add(10*time.Second))),
+				newSamplePair(now.Add(20*time.Second), 5*generateSineWaveValue(now.Add(20*time.Second))),
+				newSamplePair(now.Add(20*time.Second), 5*generateSineWaveValue(now.Add(20*time.Second))),
+				newSamplePair(now.Add(30*time.Second), 5*generateSineWaveValue(now.Add(30*time.Second))),
+			},
+			expectedSeries: 5,
+			expectedStep:   10 * time.Second,
+			expectedErr:    "sample at timestamp .* has a duplicated timestamp of the previous sample",
+		},
+		"should return error if samples are out of order": {
+			samples: []model.SamplePair{
+				newSamplePair(now.Add(10*time.Second), 5*generateSineWaveValue(now.Add(10*time.Second))),
+				newSamplePair(now.Add(30*time.Second), 5*generateSineWaveValue(now.Add(30*time.Second))),
+				newSamplePair(now.Add(20*time.Second), 5*generateSineWaveValue(now.Add(20*time.Second))),
+			},
+			expectedSeries: 5,
+			expectedStep:   10 * time.Second,
+			expectedErr:    "sample at timestamp .* is out of order because previous sample had timestamp .*",
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			actual := verifySineWaveSamples(testData.samples, testData.expectedSeries, SeriesSchedule{}, testData.expectedStep, ValueConfig{}, nil)
+			if testData.expectedErr == "" {
+				assert.NoError(t, actual)
+			} else {
+				assert.Error(t, actual)
+				assert.Regexp(t, testData.expectedErr, actual.Error())
+			}
+		})
+	}
+}
+
+func TestVerifySineWaveMatrix(t *testing.T) {
+	// Round to millis since that's the precision of Prometheus timestamps.
+	now := time.UnixMilli(time.Now().UnixMilli()).UTC()
+	values := ValueConfig{Decorrelation: 10}
+
+	newStream := func(seriesID int, offsets ...time.Duration) *model.SampleStream {
+		stream := &model.SampleStream{Metric: model.Metric{"__name__": sineWaveMetricName, "wave": model.LabelValue(strconv.Itoa(seriesID))}}
+		for _, offset := range offsets {
+			stream.Values = append(stream.Values, newSamplePair(now.Add(offset), values.value(now.Add(offset), seriesID)))
+		}
+		return stream
+	}
+
+	tests := map[string]struct {
+		matrix         model.Matrix
+		expectedSeries int
+		expectedErr    string
+	}{
+		"should return no error if all series values and timestamps match the expected ones": {
+			matrix: model.Matrix{
+				newStream(1, 10*time.Second, 20*time.Second, 30*time.Second),
+				newStream(2, 10*time.Second, 20*time.Second, 30*time.Second),
+				newStream(3, 10*time.Second, 20*time.Second, 30*time.Second),
+			},
+			expectedSeries: 3,
+		},
+		"should return error if there's a missing series": {
+			matrix: model.Matrix{
+				newStream(1, 10*time.Second, 20*time.Second, 30*time.Second),
+				newStream(3, 10*time.Second, 20*time.Second, 30*time.Second),
+			},
+			expectedSeries: 3,
+			expectedErr:    "expected 3 series in the result but got 2",
+		},
+		"should return error if a series has a value of another series": {
+			matrix: model.Matrix{
+				newStream(1, 10*time.Second, 20*time.Second, 30*time.Second),
+				{
+					Metric: model.Metric{"__name__": sineWaveMetricName, "wave": "2"},
+					Values: newStream(1, 10*time.Second, 20*time.Second, 30*time.Second).Values,
+				},
+			},
+			expectedSeries: 2,
+			expectedErr:    `series .*wave="2".*: sample at timestamp .* has value .* while was expecting .*`,
+		},
+		"should return error if a series has a missing sample": {
+			matrix: model.Matrix{
+				newStream(1, 10*time.Second, 20*time.Second, 30*time.Second),
+				newStream(2, 10*time.Second, 30*time.Second),
+			},
+			expectedSeries: 2,
+			expectedErr:    `series .*wave="2".*: sample at timestamp .* was expected to have timestamp .*`,
+		},
+		"should return error if a series has an invalid wave label": {
+			matrix: model.Matrix{
+				{Metric: model.Metric{"__name__": sineWaveMetricName, "wave": "invalid"}},
+			},
+			expectedSeries: 1,
+			expectedErr:    "series .* has an invalid wave label",
+		},
 	}
 
 	for testName, testData := range tests {
 		t.Run(testName, func(t *testing.T) {
-			actual := verifySineWaveSamples(testData.samples, testData.expectedSeries, testData.expectedStep)
+			actual := verifySineWaveMatrix(testData.matrix, testData.expectedSeries, SeriesSchedule{}, 10*time.Second, values, nil)
 			if testData.expectedErr == "" {
 				assert.NoError(t, actual)
 			} else {
@@ -148,9 +304,809 @@ func TestVerifySineWaveSamples(t *testing.T) {
 	}
 }
 
+func TestVerifyChecksums(t *testing.T) {
+	now := time.UnixMilli(time.Now().UnixMilli()).UTC()
+	value := generateSineWaveValue(now)
+
+	newStream := func(checksum string, values ...float64) *model.SampleStream {
+		stream := &model.SampleStream{Metric: model.Metric{"__name__": sineWaveMetricName, "wave": "1"}}
+		if checksum != "" {
+			stream.Metric[checksumLabelName] = model.LabelValue(checksum)
+		}
+		for i, v := range values {
+			stream.Values = append(stream.Values, newSamplePair(now.Add(time.Duration(i)*10*time.Second), v))
+		}
+		return stream
+	}
+
+	tests := map[string]struct {
+		matrix      model.Matrix
+		expectedErr string
+	}{
+		"should return no error if all samples match the checksum of their series": {
+			matrix: model.Matrix{
+				newStream(valueChecksum(value), value, value),
+				newStream(valueChecksum(-value), -value),
+			},
+		},
+		"should return error if a sample value has been corrupted": {
+			matrix: model.Matrix{
+				newStream(valueChecksum(value), value, value+0.0001),
+			},
+			expectedErr: "sample at timestamp .* has value .* with checksum .* not matching the expected .*",
+		},
+		"should return error if a series has no checksum label": {
+			matrix: model.Matrix{
+				newStream("", value),
+			},
+			expectedErr: "series .* has no checksum label",
+		},
+		"should return error if there are no series": {
+			matrix:      model.Matrix{},
+			expectedErr: "expected at least 1 series in the result but got 0",
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			actual := verifyChecksums(testData.matrix)
+			if testData.expectedErr == "" {
+				assert.NoError(t, actual)
+			} else {
+				assert.Error(t, actual)
+				assert.Regexp(t, testData.expectedErr, actual.Error())
+			}
+		})
+	}
+}
+
+func TestQueryClient_ShouldVerifyAdditionalQueriesWithExpectedValue(t *testing.T) {
+	const numSeries = 4
+
+	// The server returns half of the sum of the sine wave series, like an avg recording rule would do.
+	server := httptest.NewServer(newQueryRangeHandler(func(query string, start, end time.Time, step time.Duration) model.Matrix {
+		stream := &model.SampleStream{Metric: model.Metric{"__name__": "avg:cortex_load_generator_sine_wave"}}
+		for ts := start; !ts.After(end); ts = ts.Add(step) {
+			stream.Values = append(stream.Values, newSamplePair(ts, numSeries*generateSineWaveValue(ts)/2))
+		}
+		return model.Matrix{stream}
+	}))
+	t.Cleanup(server.Close)
+
+	const (
+		matchingQuery    = "avg:cortex_load_generator_sine_wave"
+		mismatchingQuery = "avg:cortex_load_generator_sine_wave offset 0s"
+		unverifiedQuery  = "avg:cortex_load_generator_sine_wave offset 1s"
+	)
+
+	client := NewQueryClient(QueryClientConfig{
+		URL:                   server.URL,
+		UserID:                "user-1",
+		QueryTimeout:          time.Second,
+		QueryMaxAge:           time.Hour,
+		ExpectedSeries:        numSeries,
+		ExpectedWriteInterval: 10 * time.Second,
+		AdditionalQueries: []AdditionalQuery{
+			{Query: matchingQuery, Expected: &ExpectedValue{SumFactor: 0.5}},
+			{Query: mismatchingQuery, Expected: &ExpectedValue{SumFactor: 1}},
+			{Query: unverifiedQuery},
+		},
+	}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	client.startTime = time.Now().Add(-time.Hour)
+
+	client.runQueries()
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(client.resultsComparedTotal.WithLabelValues(comparisonSuccess, matchingQuery)))
+	assert.Equal(t, 0.0, testutil.ToFloat64(client.resultsComparedTotal.WithLabelValues(comparisonFailed, matchingQuery)))
+	assert.Equal(t, 0.0, testutil.ToFloat64(client.resultsComparedTotal.WithLabelValues(comparisonSuccess, mismatchingQuery)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(client.resultsComparedTotal.WithLabelValues(comparisonFailed, mismatchingQuery)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(client.queriesTotal.WithLabelValues(querySuccess, unverifiedQuery)))
+	assert.Equal(t, 0.0, testutil.ToFloat64(client.resultsComparedTotal.WithLabelValues(comparisonSuccess, unverifiedQuery)))
+}
+
+func TestQueryClient_ShouldQueryPrePopulatedBackendWithoutWriting(t *testing.T) {
+	const writeInterval = 10 * time.Second
+
+	var queries, pushes int64
+
+	// The backend has been populated before the query client started.
+	handler := newQueryRangeHandler(func(query string, start, end time.Time, step time.Duration) model.Matrix {
+		stream := &model.SampleStream{Metric: model.Metric{}}
+		for ts := start; !ts.After(end); ts = ts.Add(step) {
+			stream.Values = append(stream.Values, newSamplePair(ts, generateSineWaveValue(ts)))
+		}
+		return model.Matrix{stream}
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/push" {
+			atomic.AddInt64(&pushes, 1)
+			return
+		}
+
+		atomic.AddInt64(&queries, 1)
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewQueryClient(QueryClientConfig{
+		URL:                   server.URL,
+		UserID:                "user-1",
+		QueryTimeout:          time.Second,
+		QueryMaxAge:           time.Hour,
+		ExpectedSeries:        1,
+		ExpectedWriteInterval: writeInterval,
+		ExternalWrites:        true,
+	}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+	// The queries should run and succeed as soon as the client starts, without any write.
+	assert.True(t, client.runQueries())
+	assert.Equal(t, int64(1), atomic.LoadInt64(&queries))
+	assert.Equal(t, int64(0), atomic.LoadInt64(&pushes))
+	assert.Equal(t, 1.0, testutil.ToFloat64(client.resultsComparedTotal.WithLabelValues(comparisonSuccess, client.defaultQuery)))
+}
+
+func TestQueryClient_ShouldHonorQueryConcurrencyLimit(t *testing.T) {
+	const (
+		concurrencyLimit = 3
+		numQueries       = 20
+	)
+
+	var inflight, maxInflight, requests int64
+
+	server := httptest.NewServer(newQueryRangeHandler(func(query string, start, end time.Time, step time.Duration) model.Matrix {
+		atomic.AddInt64(&requests, 1)
+
+		current := atomic.AddInt64(&inflight, 1)
+		defer atomic.AddInt64(&inflight, -1)
+
+		for {
+			max := atomic.LoadInt64(&maxInflight)
+			if current <= max || atomic.CompareAndSwapInt64(&maxInflight, max, current) {
+				break
+			}
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		return model.Matrix{}
+	}))
+	t.Cleanup(server.Close)
+
+	var queries []AdditionalQuery
+	for i := 0; i < numQueries; i++ {
+		queries = append(queries, AdditionalQuery{Query: fmt.Sprintf("cortex_load_generator_sine_wave offset %ds", i)})
+	}
+
+	client := NewQueryClient(QueryClientConfig{
+		URL:                   server.URL,
+		UserID:                "user-1",
+		QueryTimeout:          time.Second,
+		QueryMaxAge:           time.Hour,
+		ExpectedSeries:        1,
+		ExpectedWriteInterval: 10 * time.Second,
+		AdditionalQueries:     queries,
+		QueryConcurrency:      2,
+		QueryConcurrencyLimit: concurrencyLimit,
+	}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	client.startTime = time.Now().Add(-time.Hour)
+
+	client.runQueries()
+
+	// All queries should have run, without exceeding the limit.
+	assert.Equal(t, int64(2*(1+numQueries)), atomic.LoadInt64(&requests))
+	assert.LessOrEqual(t, atomic.LoadInt64(&maxInflight), int64(concurrencyLimit))
+	assert.Greater(t, atomic.LoadInt64(&maxInflight), int64(1))
+}
+
+func TestQueryClient_ShouldVerifyAdditionalQueriesAveragedOverWindowAtCoarseStep(t *testing.T) {
+	const (
+		numSeries     = 4
+		writeInterval = 10 * time.Second
+		window        = time.Minute
+	)
+
+	// The server returns the average of the sum of the sine wave series written within the window,
+	// like an avg_over_time() query would do.
+	server := httptest.NewServer(newQueryRangeHandler(func(query string, start, end time.Time, step time.Duration) model.Matrix {
+		stream := &model.SampleStream{Metric: model.Metric{}}
+		for ts := start; !ts.After(end); ts = ts.Add(step) {
+			sum, count := 0.0, 0
+			for t := ts.Add(-window); !t.After(ts); t = t.Add(writeInterval) {
+				sum += numSeries * generateSineWaveValue(t)
+				count++
+			}
+			stream.Values = append(stream.Values, newSamplePair(ts, sum/float64(count)))
+		}
+		return model.Matrix{stream}
+	}))
+	t.Cleanup(server.Close)
+
+	const (
+		windowQuery = "sum(avg_over_time(cortex_load_generator_sine_wave[1m]))"
+		pointQuery  = "sum(avg_over_time(cortex_load_generator_sine_wave[1m])) offset 0s"
+	)
+
+	client := NewQueryClient(QueryClientConfig{
+		URL:                   server.URL,
+		UserID:                "user-1",
+		QueryTimeout:          time.Second,
+		QueryMaxAge:           time.Hour,
+		QueryMinStep:          5 * time.Minute,
+		ExpectedSeries:        numSeries,
+		ExpectedWriteInterval: writeInterval,
+		AdditionalQueries: []AdditionalQuery{
+			{Query: windowQuery, Expected: &ExpectedValue{SumFactor: 1}, Window: window},
+			{Query: pointQuery, Expected: &ExpectedValue{SumFactor: 1}},
+		},
+	}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	client.startTime = time.Now().Add(-time.Hour)
+
+	client.runQueries()
+
+	// The step is larger than the write interval, but the window verification takes into account
+	// the samples skipped by the step.
+	assert.Equal(t, 1.0, testutil.ToFloat64(client.resultsComparedTotal.WithLabelValues(comparisonSuccess, windowQuery)))
+	assert.Equal(t, 0.0, testutil.ToFloat64(client.resultsComparedTotal.WithLabelValues(comparisonFailed, windowQuery)))
+
+	// The point verification expects the value at each step, which is different.
+	assert.Equal(t, 0.0, testutil.ToFloat64(client.resultsComparedTotal.WithLabelValues(comparisonSuccess, pointQuery)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(client.resultsComparedTotal.WithLabelValues(comparisonFailed, pointQuery)))
+}
+
+func TestQueryClient_ShouldAcceptAdditionalQueriesReturningMultipleSeries(t *testing.T) {
+	const additionalQuery = "cortex_load_generator_sine_wave"
+
+	// The server returns 5 series for any query.
+	server := httptest.NewServer(newQueryRangeHandler(func(query string, start, end time.Time, step time.Duration) model.Matrix {
+		var matrix model.Matrix
+		for i := 1; i <= 5; i++ {
+			stream := &model.SampleStream{Metric: model.Metric{"__name__": sineWaveMetricName, "wave": model.LabelValue(strconv.Itoa(i))}}
+			for ts := start; !ts.After(end); ts = ts.Add(step) {
+				stream.Values = append(stream.Values, newSamplePair(ts, generateSineWaveValue(ts)))
+			}
+			matrix = append(matrix, stream)
+		}
+		return matrix
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewQueryClient(QueryClientConfig{
+		URL:                   server.URL,
+		UserID:                "user-1",
+		QueryTimeout:          time.Second,
+		QueryMaxAge:           time.Hour,
+		ExpectedSeries:        5,
+		ExpectedWriteInterval: 10 * time.Second,
+		AdditionalQueries:     []AdditionalQuery{{Query: additionalQuery}},
+	}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	client.startTime = time.Now().Add(-time.Hour)
+
+	client.runQueries()
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(client.queriesTotal.WithLabelValues(querySuccess, additionalQuery)))
+	assert.Equal(t, 0.0, testutil.ToFloat64(client.queriesTotal.WithLabelValues(queryFailed, additionalQuery)))
+
+	// The default query should still expect exactly 1 series.
+	assert.Equal(t, 1.0, testutil.ToFloat64(client.queriesTotal.WithLabelValues(querySuccess, client.defaultQuery)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(client.resultsComparedTotal.WithLabelValues(comparisonFailed, client.defaultQuery)))
+}
+
+func TestQueryClient_AdditionalQueryTimeout(t *testing.T) {
+	const additionalQuery = "count(cortex_load_generator_sine_wave)"
+
+	var (
+		cancelledMx sync.Mutex
+		cancelled   = map[string]bool{}
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.FormValue("query")
+
+		select {
+		case <-time.After(500 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+
+		cancelledMx.Lock()
+		cancelled[query] = r.Context().Err() != nil
+		cancelledMx.Unlock()
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewQueryClient(QueryClientConfig{
+		URL:                   server.URL,
+		UserID:                "user-1",
+		QueryTimeout:          5 * time.Second,
+		QueryMaxAge:           time.Hour,
+		ExpectedSeries:        1,
+		ExpectedWriteInterval: 10 * time.Second,
+		AdditionalQueries:     []AdditionalQuery{{Query: additionalQuery, Timeout: 50 * time.Millisecond}},
+	}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	client.startTime = time.Now().Add(-time.Hour)
+
+	client.runQueries()
+
+	// Wait until the server has processed both requests.
+	assert.Eventually(t, func() bool {
+		cancelledMx.Lock()
+		defer cancelledMx.Unlock()
+		return len(cancelled) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	cancelledMx.Lock()
+	defer cancelledMx.Unlock()
+	assert.Equal(t, map[string]bool{client.defaultQuery: false, additionalQuery: true}, cancelled)
+}
+
+func TestVerifySineWaveSamples_ShouldObserveComparisonDeltas(t *testing.T) {
+	now := time.UnixMilli(time.Now().UnixMilli()).UTC()
+
+	samples := []model.SamplePair{
+		newSamplePair(now.Add(10*time.Second), 2*generateSineWaveValue(now.Add(10*time.Second))),
+		newSamplePair(now.Add(20*time.Second), 2*generateSineWaveValue(now.Add(20*time.Second))+0.5),
+		newSamplePair(now.Add(30*time.Second), 2*generateSineWaveValue(now.Add(30*time.Second))),
+	}
+
+	deltas := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "test",
+		Buckets: []float64{0.001, 1},
+	})
+
+	// Deltas should be observed for both matching and mismatching samples, until the first mismatch.
+	assert.Error(t, verifySineWaveSamples(samples, 2, SeriesSchedule{}, 10*time.Second, ValueConfig{}, deltas))
+
+	metric := &dto.Metric{}
+	require.NoError(t, deltas.Write(metric))
+	assert.Equal(t, uint64(2), metric.GetHistogram().GetSampleCount())
+	assert.InDelta(t, 0.5, metric.GetHistogram().GetSampleSum(), 1e-9)
+	assert.Equal(t, uint64(1), metric.GetHistogram().GetBucket()[0].GetCumulativeCount())
+	assert.Equal(t, uint64(2), metric.GetHistogram().GetBucket()[1].GetCumulativeCount())
+}
+
+func TestVerifyAveragedSineWaveSamples(t *testing.T) {
+	const numSeries = 3
+
+	// Use timestamps close to the epoch, so that the analytic average is accurate.
+	start := time.Unix(3600, 0).UTC()
+	step := 2 * time.Minute
+
+	// The average of sin(wt) over [a, b] is (cos(wa) - cos(wb)) / (w * (b - a)).
+	analyticAverage := func(a, b time.Time) float64 {
+		w := 2 * math.Pi / float64(sineWavePeriod)
+		return (math.Cos(w*float64(a.UnixNano())) - math.Cos(w*float64(b.UnixNano()))) / (w * float64(b.Sub(a)))
+	}
+
+	var samples []model.SamplePair
+	for ts := start; ts.Before(start.Add(sineWavePeriod)); ts = ts.Add(step) {
+		samples = append(samples, newSamplePair(ts, numSeries*analyticAverage(ts.Add(-step), ts)))
+	}
+
+	assert.NoError(t, verifyAveragedSineWaveSamples(samples, numSeries, SeriesSchedule{}, step, ValueConfig{}, nil))
+
+	// The point values are different than the averages over the step.
+	assert.Error(t, verifySineWaveSamples(samples, numSeries, SeriesSchedule{}, step, ValueConfig{}, nil))
+}
+
+func TestQueryClient_ShouldTrackComparisonFailuresByKind(t *testing.T) {
+	now := time.UnixMilli(time.Now().UnixMilli()).UTC()
+	expected := func(time.Time) float64 { return 1 }
+
+	tests := map[string]struct {
+		samples      []model.SamplePair
+		err          error
+		expectedKind string
+	}{
+		"value mismatch": {
+			samples:      []model.SamplePair{newSamplePair(now, 1), newSamplePair(now.Add(10*time.Second), 2)},
+			expectedKind: comparisonValueMismatch,
+		},
+		"timestamp gap": {
+			samples:      []model.SamplePair{newSamplePair(now, 1), newSamplePair(now.Add(20*time.Second), 1)},
+			expectedKind: comparisonTimestampGap,
+		},
+		"duplicate timestamp": {
+			samples:      []model.SamplePair{newSamplePair(now, 1), newSamplePair(now, 1)},
+			expectedKind: comparisonDuplicateTimestamp,
+		},
+		"out of order": {
+			samples:      []model.SamplePair{newSamplePair(now, 1), newSamplePair(now.Add(-10*time.Second), 1)},
+			expectedKind: comparisonOutOfOrder,
+		},
+		"other": {
+			err:          errors.New("expected at least 1 series in the result but got 0"),
+			expectedKind: comparisonOther,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			reg := prometheus.NewPedanticRegistry()
+			client := NewQueryClient(QueryClientConfig{UserID: "user-1"}, log.NewNopLogger(), reg)
+
+			err := testData.err
+			if err == nil {
+				err = verifySamples(testData.samples, 10*time.Second, expected, nil)
+			}
+			require.Error(t, err)
+
+			client.recordComparison(client.defaultQuery, err)
+
+			for _, kind := range []string{comparisonValueMismatch, comparisonTimestampGap, comparisonDuplicateTimestamp, comparisonOutOfOrder, comparisonOther} {
+				expectedCount := 0.0
+				if kind == testData.expectedKind {
+					expectedCount = 1
+				}
+				assert.Equal(t, expectedCount, testutil.ToFloat64(client.comparisonFailures.WithLabelValues(kind)), kind)
+			}
+
+			assert.Greater(t, testutil.ToFloat64(client.lastComparisonError), 0.0)
+		})
+	}
+}
+
+func TestQueryClient_VerifySeriesCount(t *testing.T) {
+	const numSeries = 3
+
+	tests := map[string]struct {
+		countValue         float64
+		expectedComparison map[string]float64
+	}{
+		"should succeed if the count matches the expected series": {
+			countValue:         numSeries,
+			expectedComparison: map[string]float64{comparisonSuccess: 1, comparisonFailed: 0},
+		},
+		"should fail if there are missing series": {
+			countValue:         numSeries - 1,
+			expectedComparison: map[string]float64{comparisonSuccess: 0, comparisonFailed: 1},
+		},
+		"should fail if there are extra series": {
+			countValue:         numSeries + 1,
+			expectedComparison: map[string]float64{comparisonSuccess: 0, comparisonFailed: 1},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			server := httptest.NewServer(newQueryRangeHandler(func(query string, start, end time.Time, step time.Duration) model.Matrix {
+				stream := &model.SampleStream{Metric: model.Metric{}}
+				for ts := start; !ts.After(end); ts = ts.Add(step) {
+					if query == "count(cortex_load_generator_sine_wave)" {
+						stream.Values = append(stream.Values, newSamplePair(ts, testData.countValue))
+					} else {
+						stream.Values = append(stream.Values, newSamplePair(ts, numSeries*generateSineWaveValue(ts)))
+					}
+				}
+				return model.Matrix{stream}
+			}))
+			t.Cleanup(server.Close)
+
+			client := NewQueryClient(QueryClientConfig{
+				URL:                   server.URL,
+				UserID:                "user-1",
+				QueryTimeout:          time.Second,
+				QueryMaxAge:           time.Hour,
+				ExpectedSeries:        numSeries,
+				ExpectedWriteInterval: 10 * time.Second,
+				VerifySeriesCount:     true,
+			}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+			client.startTime = time.Now().Add(-time.Hour)
+
+			require.True(t, client.runQueries())
+
+			for result, expected := range testData.expectedComparison {
+				assert.Equal(t, expected, testutil.ToFloat64(client.resultsComparedTotal.WithLabelValues(result, client.countQuery)), result)
+			}
+			assert.Equal(t, testData.countValue, testutil.ToFloat64(client.lastSeriesCount))
+
+			// The default query is verified regardless of the count.
+			assert.Equal(t, float64(1), testutil.ToFloat64(client.resultsComparedTotal.WithLabelValues(comparisonSuccess, client.defaultQuery)))
+		})
+	}
+}
+
+func TestQueryClient_ShouldSignalComparisonFailures(t *testing.T) {
+	const numSeries = 3
+
+	tests := map[string]struct {
+		offset          float64
+		expectedFailure bool
+	}{
+		"should not signal if the comparison succeeds": {
+			offset:          0,
+			expectedFailure: false,
+		},
+		"should signal if the comparison fails": {
+			offset:          1,
+			expectedFailure: true,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			server := httptest.NewServer(newQueryRangeHandler(func(query string, start, end time.Time, step time.Duration) model.Matrix {
+				stream := &model.SampleStream{Metric: model.Metric{}}
+				for ts := start; !ts.After(end); ts = ts.Add(step) {
+					stream.Values = append(stream.Values, newSamplePair(ts, numSeries*generateSineWaveValue(ts)+testData.offset))
+				}
+				return model.Matrix{stream}
+			}))
+			t.Cleanup(server.Close)
+
+			failures := make(chan error, 1)
+
+			client := NewQueryClient(QueryClientConfig{
+				URL:                   server.URL,
+				UserID:                "user-1",
+				QueryTimeout:          time.Second,
+				QueryMaxAge:           time.Hour,
+				ExpectedSeries:        numSeries,
+				ExpectedWriteInterval: 10 * time.Second,
+				QueryConcurrency:      2,
+				ComparisonFailures:    failures,
+			}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+			client.startTime = time.Now().Add(-time.Hour)
+
+			// Running the queries should not block, even if failures are not received.
+			client.runQueries()
+			client.runQueries()
+
+			select {
+			case err := <-failures:
+				require.True(t, testData.expectedFailure, "unexpected failure: %v", err)
+				assert.Equal(t, comparisonValueMismatch, comparisonFailureKind(err))
+				assert.Contains(t, err.Error(), client.defaultQuery)
+			default:
+				require.False(t, testData.expectedFailure, "expected a comparison failure")
+			}
+		})
+	}
+}
+
+func TestVerifySamples_WithConstantInfoSeries(t *testing.T) {
+	now := time.UnixMilli(time.Now().UnixMilli()).UTC()
+	expected := func(time.Time) float64 { return 3 }
+
+	assert.NoError(t, verifySamples([]model.SamplePair{
+		newSamplePair(now.Add(10*time.Second), 3),
+		newSamplePair(now.Add(20*time.Second), 3),
+	}, 10*time.Second, expected, nil))
+
+	assert.Error(t, verifySamples([]model.SamplePair{
+		newSamplePair(now.Add(10*time.Second), 3),
+		newSamplePair(now.Add(20*time.Second), 2),
+	}, 10*time.Second, expected, nil))
+}
+
+// newQueryRangeHandler returns an HTTP handler responding to range queries with the
+// matrix returned by fn.
+func newQueryRangeHandler(fn func(query string, start, end time.Time, step time.Duration) model.Matrix) http.Handler {
+	parseTime := func(s string) time.Time {
+		seconds, _ := strconv.ParseFloat(s, 64)
+		return time.UnixMilli(int64(seconds * 1000)).UTC()
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		step, _ := strconv.ParseFloat(r.FormValue("step"), 64)
+		matrix := fn(r.FormValue("query"), parseTime(r.FormValue("start")), parseTime(r.FormValue("end")), time.Duration(step*float64(time.Second)))
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "success",
+			"data": map[string]interface{}{
+				"resultType": "matrix",
+				"result":     matrix,
+			},
+		})
+	})
+}
+
 func newSamplePair(ts time.Time, value float64) model.SamplePair {
 	return model.SamplePair{
 		Timestamp: model.Time(ts.UnixMilli()),
 		Value:     model.SampleValue(value),
 	}
 }
+
+func TestQueryClient_SkipInitialQuery(t *testing.T) {
+	const queryInterval = 500 * time.Millisecond
+
+	tests := map[string]struct {
+		skipInitialQuery bool
+		expectedFirstMin time.Duration
+		expectedFirstMax time.Duration
+	}{
+		"should query immediately at startup by default": {
+			skipInitialQuery: false,
+			expectedFirstMin: 0,
+			expectedFirstMax: queryInterval / 2,
+		},
+		"should query only after one interval if the initial query is skipped": {
+			skipInitialQuery: true,
+			expectedFirstMin: queryInterval,
+			expectedFirstMax: 2 * queryInterval,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			firstQuery := make(chan time.Time, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case firstQuery <- time.Now():
+				default:
+				}
+			}))
+			t.Cleanup(server.Close)
+
+			client := NewQueryClient(QueryClientConfig{
+				URL:                   server.URL,
+				UserID:                "user-1",
+				QueryInterval:         queryInterval,
+				QueryTimeout:          time.Second,
+				QueryMaxAge:           time.Hour,
+				ExpectedSeries:        1,
+				ExpectedWriteInterval: 10 * time.Second,
+				SkipInitialQuery:      testData.skipInitialQuery,
+			}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+			client.startTime = time.Now().Add(-time.Hour)
+
+			startTime := time.Now()
+			client.Start()
+
+			select {
+			case ts := <-firstQuery:
+				elapsed := ts.Sub(startTime)
+				assert.GreaterOrEqual(t, elapsed, testData.expectedFirstMin)
+				assert.Less(t, elapsed, testData.expectedFirstMax)
+			case <-time.After(2 * queryInterval):
+				t.Fatal("no query received")
+			}
+		})
+	}
+}
+
+func TestQueryClient_QueryInterval(t *testing.T) {
+	t.Run("should double the interval after each consecutive failure up to the max", func(t *testing.T) {
+		client := NewQueryClient(QueryClientConfig{
+			QueryInterval:           10 * time.Second,
+			QueryBackoffMaxInterval: time.Minute,
+		}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+		expected := []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute, time.Minute}
+		for failures, interval := range expected {
+			assert.Equal(t, interval, client.queryInterval(failures), "failures: %d", failures)
+		}
+	})
+
+	t.Run("should not back off if disabled", func(t *testing.T) {
+		client := NewQueryClient(QueryClientConfig{QueryInterval: 10 * time.Second}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+		assert.Equal(t, 10*time.Second, client.queryInterval(5))
+	})
+
+	t.Run("should add jitter within bounds", func(t *testing.T) {
+		client := NewQueryClient(QueryClientConfig{
+			QueryInterval:           10 * time.Second,
+			QueryBackoffMaxInterval: time.Minute,
+			QueryBackoffJitter:      0.5,
+		}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+		for i := 0; i < 100; i++ {
+			interval := client.queryInterval(10)
+			assert.GreaterOrEqual(t, interval, 30*time.Second)
+			assert.LessOrEqual(t, interval, time.Minute)
+		}
+	})
+}
+
+func TestQueryClient_RunQueriesShouldReportFailures(t *testing.T) {
+	var failing atomic.Value
+	failing.Store(true)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load().(bool) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		newQueryRangeHandler(func(string, time.Time, time.Time, time.Duration) model.Matrix {
+			return model.Matrix{}
+		}).ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewQueryClient(QueryClientConfig{
+		URL:                   server.URL,
+		UserID:                "user-1",
+		QueryTimeout:          time.Second,
+		QueryMaxAge:           time.Hour,
+		ExpectedSeries:        1,
+		ExpectedWriteInterval: 10 * time.Second,
+	}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	client.startTime = time.Now().Add(-time.Hour)
+
+	assert.False(t, client.runQueries())
+
+	// A failed comparison isn't a query failure.
+	failing.Store(false)
+	assert.True(t, client.runQueries())
+}
+
+func TestQueryClient_DefaultQuery(t *testing.T) {
+	tests := map[string]struct {
+		metricNamesCount int
+		verifyRawSeries  bool
+		expected         string
+	}{
+		"single metric name": {
+			metricNamesCount: 1,
+			expected:         "sum(cortex_load_generator_sine_wave)",
+		},
+		"multiple metric names": {
+			metricNamesCount: 3,
+			expected:         "sum(cortex_load_generator_sine_wave_0)",
+		},
+		"raw series": {
+			metricNamesCount: 1,
+			verifyRawSeries:  true,
+			expected:         "cortex_load_generator_sine_wave",
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			client := NewQueryClient(QueryClientConfig{ExpectedMetricNamesCount: testData.metricNamesCount, VerifyRawSeries: testData.verifyRawSeries}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+			assert.Equal(t, testData.expected, client.defaultQuery)
+		})
+	}
+}
+
+func TestQueryClient_QueryConcurrency(t *testing.T) {
+	const additionalQuery = "count(cortex_load_generator_sine_wave)"
+
+	for _, concurrency := range []int{1, 3} {
+		t.Run(fmt.Sprintf("concurrency: %d", concurrency), func(t *testing.T) {
+			var (
+				queriesMx   sync.Mutex
+				queries     = map[string]int{}
+				inflight    int
+				maxInflight int
+			)
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				queriesMx.Lock()
+				queries[r.FormValue("query")]++
+				inflight++
+				if inflight > maxInflight {
+					maxInflight = inflight
+				}
+				queriesMx.Unlock()
+
+				time.Sleep(10 * time.Millisecond)
+
+				queriesMx.Lock()
+				inflight--
+				queriesMx.Unlock()
+			}))
+			t.Cleanup(server.Close)
+
+			client := NewQueryClient(QueryClientConfig{
+				URL:                   server.URL,
+				UserID:                "user-1",
+				QueryTimeout:          time.Second,
+				QueryMaxAge:           time.Hour,
+				ExpectedSeries:        1,
+				ExpectedWriteInterval: 10 * time.Second,
+				AdditionalQueries:     []AdditionalQuery{{Query: additionalQuery}},
+				QueryConcurrency:      concurrency,
+			}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+			client.startTime = time.Now().Add(-time.Hour)
+
+			client.runQueries()
+
+			queriesMx.Lock()
+			defer queriesMx.Unlock()
+			assert.Equal(t, map[string]int{client.defaultQuery: concurrency, additionalQuery: concurrency}, queries)
+			assert.LessOrEqual(t, maxInflight, concurrency)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Recorder keeps the sum of the values successfully pushed by the write client for the series
+// targeted by the default query, so that the query client can verify query results against what
+// has actually been written. It's bounded and only keeps the most recent timestamps.
+type Recorder struct {
+	mx sync.Mutex
+
+	// Ring buffer of recorded timestamps (in milliseconds) and values. Timestamps are recorded
+	// in increasing order, so the oldest one is at next once the buffer is full.
+	timestamps []int64
+	values     []float64
+	series     []int
+	next       int
+	full       bool
+}
+
+// NewRecorder returns a Recorder keeping the values of the most recent size timestamps.
+func NewRecorder(size int) *Recorder {
+	if size < 1 {
+		size = 1
+	}
+
+	return &Recorder{
+		timestamps: make([]int64, size),
+		values:     make([]float64, size),
+		series:     make([]int, size),
+	}
+}
+
+// Add records the sum of the values pushed at t by a write of the input number of series.
+// Timestamps must be added in increasing order. A write at an already recorded timestamp (e.g.
+// on flush) re-pushes the same samples, so it replaces the recorded sum instead of adding to it,
+// unless it pushed fewer series than the recorded one (e.g. because some requests failed).
+func (r *Recorder) Add(t time.Time, value float64, series int) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	ts := t.UnixMilli()
+
+	if last := r.last(); last >= 0 && r.timestamps[last] == ts {
+		if series >= r.series[last] {
+			r.values[last] = value
+			r.series[last] = series
+		}
+		return
+	}
+
+	r.timestamps[r.next] = ts
+	r.values[r.next] = value
+	r.series[r.next] = series
+	r.next = (r.next + 1) % len(r.timestamps)
+	r.full = r.full || r.next == 0
+}
+
+// Value returns the sum recorded at t, and whether any value has been recorded at t.
+func (r *Recorder) Value(t time.Time) (float64, bool) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	ts := t.UnixMilli()
+	count := r.count()
+
+	// Search the timestamp in the ring buffer, from the oldest one.
+	oldest := (r.next - count + len(r.timestamps)) % len(r.timestamps)
+	idx := sort.Search(count, func(i int) bool {
+		return r.timestamps[(oldest+i)%len(r.timestamps)] >= ts
+	})
+	if idx == count || r.timestamps[(oldest+idx)%len(r.timestamps)] != ts {
+		return 0, false
+	}
+
+	return r.values[(oldest+idx)%len(r.timestamps)], true
+}
+
+func (r *Recorder) count() int {
+	if r.full {
+		return len(r.timestamps)
+	}
+	return r.next
+}
+
+// last returns the index of the most recently recorded timestamp, or -1 if none.
+func (r *Recorder) last() int {
+	if r.count() == 0 {
+		return -1
+	}
+	return (r.next - 1 + len(r.timestamps)) % len(r.timestamps)
+}
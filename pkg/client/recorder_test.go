@@ -0,0 +1,86 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder(t *testing.T) {
+	start := time.UnixMilli(time.Now().UnixMilli())
+	r := NewRecorder(3)
+
+	_, ok := r.Value(start)
+	assert.False(t, ok)
+
+	// A write at the same timestamp should replace the recorded value, unless it pushed fewer series.
+	r.Add(start, 1, 2)
+	r.Add(start, 3, 3)
+	r.Add(start, 2, 1)
+	r.Add(start.Add(10*time.Second), 4, 3)
+
+	value, ok := r.Value(start)
+	assert.True(t, ok)
+	assert.Equal(t, 3.0, value)
+
+	value, ok = r.Value(start.Add(10 * time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, 4.0, value)
+
+	_, ok = r.Value(start.Add(5 * time.Second))
+	assert.False(t, ok)
+
+	// The oldest timestamps should be evicted once full.
+	r.Add(start.Add(20*time.Second), 5, 3)
+	r.Add(start.Add(30*time.Second), 6, 3)
+
+	_, ok = r.Value(start)
+	assert.False(t, ok)
+
+	for i, expected := range []float64{4, 5, 6} {
+		value, ok = r.Value(start.Add(time.Duration(i+1) * 10 * time.Second))
+		assert.True(t, ok)
+		assert.Equal(t, expected, value)
+	}
+}
+
+func TestVerifyRecordedSamples(t *testing.T) {
+	const numSeries = 5
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	recorder := NewRecorder(10)
+	client := NewWriteClient(WriteClientConfig{
+		URL:              *serverURL,
+		UserID:           "user-1",
+		SeriesCount:      numSeries,
+		MetricNamesCount: 2,
+		WriteInterval:    time.Hour,
+		WriteTimeout:     time.Second,
+		WriteConcurrency: 2,
+		WriteBatchSize:   2,
+		Recorder:         recorder,
+	}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+	client.writeSeries()
+
+	// Only the series of the first metric name should have been recorded.
+	ts := alignTimestampToInterval(time.Now(), time.Hour)
+	expected := numSeries * generateSineWaveValue(ts)
+
+	assert.NoError(t, verifyRecordedSamples([]model.SamplePair{newSamplePair(ts, expected)}, time.Hour, recorder, nil))
+	assert.Error(t, verifyRecordedSamples([]model.SamplePair{newSamplePair(ts, expected+1)}, time.Hour, recorder, nil))
+	assert.Error(t, verifyRecordedSamples([]model.SamplePair{newSamplePair(ts.Add(-time.Hour), expected)}, time.Hour, recorder, nil))
+}
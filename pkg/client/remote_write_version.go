@@ -0,0 +1,41 @@
+package client
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+const (
+	// defaultRemoteWriteVersion is the X-Prometheus-Remote-Write-Version header value of the
+	// remote write 1.0 protocol.
+	defaultRemoteWriteVersion = "0.1.0"
+)
+
+var remoteWriteVersionRegexp = regexp.MustCompile(`^(\d+)\.\d+\.\d+$`)
+
+// ValidateRemoteWriteVersion validates the input X-Prometheus-Remote-Write-Version header value
+// against the wire format of the write requests. Write requests are always encoded with the
+// remote write 1.0 wire format, so 2.x versions are rejected: a receiver branching on the header
+// would decode them as remote write 2.0 requests.
+func ValidateRemoteWriteVersion(version string) error {
+	matches := remoteWriteVersionRegexp.FindStringSubmatch(version)
+	if matches == nil {
+		return fmt.Errorf("invalid remote write version %q: expected the format <major>.<minor>.<patch>", version)
+	}
+
+	if major, err := strconv.Atoi(matches[1]); err != nil || major >= 2 {
+		return fmt.Errorf("remote write version %q requires the remote write 2.0 wire format, but write requests are encoded with the 1.0 wire format", version)
+	}
+
+	return nil
+}
+
+// remoteWriteVersion returns the X-Prometheus-Remote-Write-Version header value of write requests.
+func (c *WriteClient) remoteWriteVersion() string {
+	if c.cfg.RemoteWriteVersion == "" {
+		return defaultRemoteWriteVersion
+	}
+
+	return c.cfg.RemoteWriteVersion
+}
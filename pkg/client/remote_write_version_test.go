@@ -0,0 +1,106 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateRemoteWriteVersion(t *testing.T) {
+	tests := map[string]struct {
+		version     string
+		expectedErr string
+	}{
+		"remote write 1.0": {
+			version: "0.1.0",
+		},
+		"1.x": {
+			version: "1.0.0",
+		},
+		"remote write 2.0": {
+			version:     "2.0.0",
+			expectedErr: "requires the remote write 2.0 wire format",
+		},
+		"empty": {
+			version:     "",
+			expectedErr: "invalid remote write version",
+		},
+		"missing patch": {
+			version:     "0.1",
+			expectedErr: "invalid remote write version",
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			err := ValidateRemoteWriteVersion(testData.version)
+			if testData.expectedErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), testData.expectedErr)
+		})
+	}
+}
+
+func TestWriteClient_ShouldSendRemoteWriteVersionHeader(t *testing.T) {
+	tests := map[string]struct {
+		version  string
+		expected string
+	}{
+		"default": {
+			version:  "",
+			expected: "0.1.0",
+		},
+		"configured": {
+			version:  "1.0.0",
+			expected: "1.0.0",
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var (
+				receivedMx sync.Mutex
+				received   []string
+			)
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				receivedMx.Lock()
+				received = append(received, r.Header.Get("X-Prometheus-Remote-Write-Version"))
+				receivedMx.Unlock()
+			}))
+			t.Cleanup(server.Close)
+
+			serverURL, err := url.Parse(server.URL)
+			require.NoError(t, err)
+
+			client := NewWriteClient(WriteClientConfig{
+				URL:                *serverURL,
+				UserID:             "user-1",
+				RemoteWriteVersion: testData.version,
+				SeriesCount:        2,
+				WriteInterval:      time.Second,
+				WriteTimeout:       time.Second,
+				WriteConcurrency:   1,
+				WriteBatchSize:     1,
+			}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+			assert.Equal(t, 2, client.writeSeries())
+
+			receivedMx.Lock()
+			defer receivedMx.Unlock()
+			assert.Equal(t, []string{testData.expected, testData.expected}, received)
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package client
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Replay holds a recorded sequence of samples to replay instead of the sine wave.
+type Replay struct {
+	// Sample timestamps are stored as offsets (in milliseconds) from the first recorded sample.
+	offsets []int64
+	values  []float64
+
+	// The duration (in milliseconds) after which the replay loops.
+	period int64
+
+	// The time (in milliseconds) at which the first recorded sample is replayed.
+	start int64
+}
+
+// LoadReplayFile reads the recorded samples from the file at path. Each line of the file
+// contains a timestamp (Unix milliseconds) and a value, separated by whitespace. Empty lines
+// and lines starting with # are ignored. Timestamps must be strictly increasing. The replay
+// starts from the first recorded sample at start.
+func LoadReplayFile(path string, start time.Time) (*Replay, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		timestamps []int64
+		values     []float64
+	)
+
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected timestamp and value but got %q", path, lineNum, line)
+		}
+
+		ts, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid timestamp: %w", path, lineNum, err)
+		}
+
+		value, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid value: %w", path, lineNum, err)
+		}
+
+		if len(timestamps) > 0 && ts <= timestamps[len(timestamps)-1] {
+			return nil, fmt.Errorf("%s:%d: timestamp %d is not after the previous one", path, lineNum, ts)
+		}
+
+		timestamps = append(timestamps, ts)
+		values = append(values, value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return newReplay(timestamps, values, start)
+}
+
+func newReplay(timestamps []int64, values []float64, start time.Time) (*Replay, error) {
+	if len(timestamps) == 0 {
+		return nil, fmt.Errorf("no samples to replay")
+	}
+
+	r := &Replay{
+		offsets: make([]int64, 0, len(timestamps)),
+		values:  values,
+		period:  1,
+		start:   start.UnixMilli(),
+	}
+
+	for _, ts := range timestamps {
+		r.offsets = append(r.offsets, ts-timestamps[0])
+	}
+
+	// The last sample is held for as long as the gap from its previous sample,
+	// then the replay starts over.
+	if last := len(r.offsets) - 1; last > 0 {
+		r.period = r.offsets[last] + (r.offsets[last] - r.offsets[last-1])
+	}
+
+	return r, nil
+}
+
+// Value returns the replayed value at t. Each recorded value is held until the next
+// recorded timestamp, and the recording loops once exhausted. The replay position is
+// computed from the time elapsed since the replay start, so the same value is returned
+// for the same t. The first recorded value is held before the replay start.
+func (r *Replay) Value(t time.Time) float64 {
+	offset := t.UnixMilli() - r.start
+	if offset < 0 {
+		offset = 0
+	}
+	offset %= r.period
+
+	idx := sort.Search(len(r.offsets), func(i int) bool {
+		return r.offsets[i] > offset
+	})
+
+	return r.values[idx-1]
+}
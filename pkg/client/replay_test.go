@@ -0,0 +1,98 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadReplayFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "replay.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# recorded samples\n1000 1.5\n11000 -2\n\n21000 4.25\n"), 0644))
+
+	start, err := time.Parse(time.RFC3339, "2023-06-29T00:00:07Z")
+	require.NoError(t, err)
+
+	replay, err := LoadReplayFile(path, start)
+	require.NoError(t, err)
+
+	// The replay should start from the first recorded sample, and loop every 30s (the last sample
+	// is held as long as the previous gap).
+	for _, loop := range []int64{start.UnixMilli(), start.UnixMilli() + 30000, start.UnixMilli() + 60000} {
+		assert.Equal(t, 1.5, replay.Value(time.UnixMilli(loop)))
+		assert.Equal(t, 1.5, replay.Value(time.UnixMilli(loop+9999)))
+		assert.Equal(t, -2.0, replay.Value(time.UnixMilli(loop+10000)))
+		assert.Equal(t, 4.25, replay.Value(time.UnixMilli(loop+20000)))
+		assert.Equal(t, 4.25, replay.Value(time.UnixMilli(loop+29999)))
+	}
+
+	// The first recorded value should be held before the replay start.
+	assert.Equal(t, 1.5, replay.Value(start.Add(-time.Millisecond)))
+	assert.Equal(t, 1.5, replay.Value(start.Add(-time.Hour)))
+}
+
+func TestLoadReplayFile_Invalid(t *testing.T) {
+	tests := map[string]struct {
+		content     string
+		expectedErr string
+	}{
+		"empty file": {
+			content:     "# nothing\n",
+			expectedErr: "no samples to replay",
+		},
+		"missing value": {
+			content:     "1000\n",
+			expectedErr: "expected timestamp and value",
+		},
+		"invalid value": {
+			content:     "1000 abc\n",
+			expectedErr: "invalid value",
+		},
+		"timestamps not increasing": {
+			content:     "1000 1\n1000 2\n",
+			expectedErr: "is not after the previous one",
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "replay.txt")
+			require.NoError(t, os.WriteFile(path, []byte(testData.content), 0644))
+
+			_, err := LoadReplayFile(path, time.Now())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), testData.expectedErr)
+		})
+	}
+}
+
+func TestGenerateSineWaveSeries_WithReplay(t *testing.T) {
+	const numSeries = 3
+
+	replay, err := newReplay([]int64{0, 10000, 20000}, []float64{1, 2, 3}, time.UnixMilli(0))
+	require.NoError(t, err)
+
+	cfg := WriteClientConfig{SeriesCount: numSeries, Values: ValueConfig{Replay: replay}}
+
+	var samples []model.SamplePair
+	for i, expected := range []float64{1, 2, 3, 1, 2, 3} {
+		ts := time.UnixMilli(int64(i) * 10000)
+
+		series := generateSineWaveSeries(ts, cfg)
+		require.Len(t, series, numSeries)
+		for _, s := range series {
+			assert.Equal(t, expected, s.Samples[0].Value)
+		}
+
+		samples = append(samples, newSamplePair(ts, expected*numSeries))
+	}
+
+	// The verifier should compare against the same replay.
+	assert.NoError(t, verifySineWaveSamples(samples, numSeries, SeriesSchedule{}, 10*time.Second, cfg.Values, nil))
+	assert.Error(t, verifySineWaveSamples(samples, numSeries, SeriesSchedule{}, 10*time.Second, ValueConfig{}, nil))
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// queryKey is the context key of the query run by a request.
+type queryKey struct{}
+
+// withQuery returns a context marking the requests made with it as running the input query.
+func withQuery(ctx context.Context, query string) context.Context {
+	return context.WithValue(ctx, queryKey{}, query)
+}
+
+// responseBytesRoundTripper counts the bytes of the response bodies, by the query run by
+// each request. Bytes are counted while the response body is read, once decompressed by the
+// transport, if it transparently requested a compressed response.
+type responseBytesRoundTripper struct {
+	rt    http.RoundTripper
+	bytes *prometheus.CounterVec
+}
+
+func (rt *responseBytesRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := rt.rt.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	query, _ := req.Context().Value(queryKey{}).(string)
+	resp.Body = &countingReadCloser{ReadCloser: resp.Body, bytes: rt.bytes.WithLabelValues(query)}
+
+	return resp, nil
+}
+
+// countingReadCloser counts the bytes read through it.
+type countingReadCloser struct {
+	io.ReadCloser
+	bytes prometheus.Counter
+}
+
+func (r *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.bytes.Add(float64(n))
+	return n, err
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryClient_ShouldTrackQueryResponseBytes(t *testing.T) {
+	const additionalQuery = "cortex_load_generator_sine_wave"
+
+	var (
+		writtenMx sync.Mutex
+		written   = map[string]int{}
+	)
+
+	// The server returns more series for the additional query, so the responses have different sizes.
+	handler := newQueryRangeHandler(func(query string, start, end time.Time, step time.Duration) model.Matrix {
+		numSeries := 1
+		if query == additionalQuery {
+			numSeries = 5
+		}
+
+		matrix := model.Matrix{}
+		for i := 0; i < numSeries; i++ {
+			stream := &model.SampleStream{Metric: model.Metric{"series": model.LabelValue(rune('a' + i))}}
+			for ts := start; !ts.After(end); ts = ts.Add(step) {
+				stream.Values = append(stream.Values, newSamplePair(ts, 1))
+			}
+			matrix = append(matrix, stream)
+		}
+		return matrix
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+
+		writtenMx.Lock()
+		written[r.FormValue("query")] += rec.Body.Len()
+		writtenMx.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(rec.Body.Bytes())
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewQueryClient(QueryClientConfig{
+		URL:                   server.URL,
+		UserID:                "user-1",
+		QueryTimeout:          time.Second,
+		QueryMaxAge:           time.Hour,
+		ExpectedSeries:        1,
+		ExpectedWriteInterval: 10 * time.Second,
+		AdditionalQueries:     []AdditionalQuery{{Query: additionalQuery}},
+	}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	client.startTime = time.Now().Add(-time.Hour)
+
+	for i := 0; i < 2; i++ {
+		client.runQueries()
+	}
+
+	writtenMx.Lock()
+	defer writtenMx.Unlock()
+
+	// The counter should advance by the size of the responses of each query.
+	assert.Len(t, written, 2)
+	for query, bytes := range written {
+		assert.Greater(t, bytes, 0)
+		assert.Equal(t, float64(bytes), testutil.ToFloat64(client.queryResponseBytes.WithLabelValues(query)), query)
+	}
+	assert.Greater(t, written[additionalQuery], written[client.defaultQuery])
+}
@@ -1,19 +1,104 @@
 package client
 
-import "net/http"
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+const defaultTenantHeaderName = "X-Scope-OrgID"
+
+// cacheBypassKey is the context key marking requests which should bypass the results cache.
+type cacheBypassKey struct{}
+
+// withCacheBypass returns a context marking the requests made with it to bypass the results cache.
+func withCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+// tenantKey is the context key of the tenant a request should be sent to.
+type tenantKey struct{}
+
+// withTenant returns a context marking the requests made with it to be sent to the input tenant,
+// instead of the tenant of the client.
+func withTenant(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, userID)
+}
 
 type clientRoundTripper struct {
-	userID string
-	rt     http.RoundTripper
+	userID     string
+	headerName string
+	rt         http.RoundTripper
+
+	// Additional headers set on each request.
+	headers map[string]string
+
+	// Whether the body of POST requests should be gzip-compressed.
+	gzipBody bool
 }
 
 // Add the tenant ID header required by Cortex
 func (rt *clientRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	headerName := rt.headerName
+	if headerName == "" {
+		headerName = defaultTenantHeaderName
+	}
+
 	req = cloneRequest(req)
-	req.Header.Set("X-Scope-OrgID", rt.userID)
+	for name, value := range rt.headers {
+		req.Header.Set(name, value)
+	}
+	userID := rt.userID
+	if tenant, ok := req.Context().Value(tenantKey{}).(string); ok {
+		userID = tenant
+	}
+	req.Header.Set(headerName, userID)
+
+	// Ask the query-frontend to not serve the response from the results cache.
+	if bypass, _ := req.Context().Value(cacheBypassKey{}).(bool); bypass {
+		req.Header.Set("Cache-Control", "no-store")
+	}
+
+	if rt.gzipBody && req.Method == http.MethodPost && req.Body != nil && req.Body != http.NoBody {
+		if err := gzipRequestBody(req); err != nil {
+			return nil, err
+		}
+	}
+
 	return rt.rt.RoundTrip(req)
 }
 
+// gzipRequestBody replaces the body of req with its gzip-compressed version.
+func gzipRequestBody(req *http.Request) error {
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	if _, err := gz.Write(body); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	compressed := buf.Bytes()
+	req.Body = io.NopCloser(bytes.NewReader(compressed))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(compressed)), nil
+	}
+	req.ContentLength = int64(len(compressed))
+	req.Header.Set("Content-Encoding", "gzip")
+
+	return nil
+}
+
 // cloneRequest returns a clone of the provided *http.Request.
 // The clone is a shallow copy of the struct and its Header map.
 func cloneRequest(r *http.Request) *http.Request {
@@ -29,3 +114,13 @@ func cloneRequest(r *http.Request) *http.Request {
 
 	return r2
 }
+
+// ProxyFunc returns the function used by the HTTP transport to select the proxy for each
+// request. It uses proxyURL if set, otherwise falls back to the environment proxy settings.
+func ProxyFunc(proxyURL *url.URL) func(*http.Request) (*url.URL, error) {
+	if proxyURL == nil {
+		return http.ProxyFromEnvironment
+	}
+
+	return http.ProxyURL(proxyURL)
+}
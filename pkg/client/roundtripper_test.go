@@ -0,0 +1,272 @@
+package client
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientRoundTripper_ShouldSetTenantHeader(t *testing.T) {
+	tests := map[string]struct {
+		headerName         string
+		expectedHeaderName string
+	}{
+		"should default to X-Scope-OrgID": {
+			headerName:         "",
+			expectedHeaderName: "X-Scope-OrgID",
+		},
+		"should use the configured header name": {
+			headerName:         "THANOS-TENANT",
+			expectedHeaderName: "THANOS-TENANT",
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var received http.Header
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = r.Header.Clone()
+			}))
+			t.Cleanup(server.Close)
+
+			rt := &clientRoundTripper{userID: "user-1", headerName: testData.headerName, rt: http.DefaultTransport}
+			req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+			require.NoError(t, err)
+
+			res, err := rt.RoundTrip(req)
+			require.NoError(t, err)
+			require.NoError(t, res.Body.Close())
+
+			assert.Equal(t, "user-1", received.Get(testData.expectedHeaderName))
+			if testData.expectedHeaderName != defaultTenantHeaderName {
+				assert.Empty(t, received.Get(defaultTenantHeaderName))
+			}
+		})
+	}
+}
+
+func TestQueryClient_ShouldSendConfiguredHeaders(t *testing.T) {
+	var (
+		receivedMx sync.Mutex
+		received   http.Header
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedMx.Lock()
+		received = r.Header.Clone()
+		receivedMx.Unlock()
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewQueryClient(QueryClientConfig{
+		URL:          server.URL,
+		UserID:       "user-1",
+		QueryTimeout: time.Second,
+		QueryHeaders: map[string]string{"Accept": "application/x-protobuf"},
+	}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	_, _ = client.runQuery(time.Now().Add(-time.Minute), time.Now(), time.Second, "up", time.Second)
+
+	receivedMx.Lock()
+	defer receivedMx.Unlock()
+	require.NotNil(t, received)
+	assert.Equal(t, "application/x-protobuf", received.Get("Accept"))
+	assert.Equal(t, "user-1", received.Get("X-Scope-OrgID"))
+}
+
+func TestQueryClient_ShouldBypassCacheIfConfigured(t *testing.T) {
+	tests := map[string]struct {
+		noCache          bool
+		noCacheAlternate bool
+		expected         []string
+	}{
+		"should not bypass the cache by default": {
+			expected: []string{"", "", "", ""},
+		},
+		"should bypass the cache for all queries": {
+			noCache:  true,
+			expected: []string{"no-store", "no-store", "no-store", "no-store"},
+		},
+		"should bypass the cache for every other query": {
+			noCacheAlternate: true,
+			expected:         []string{"", "no-store", "", "no-store"},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var (
+				receivedMx sync.Mutex
+				received   []string
+			)
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				receivedMx.Lock()
+				received = append(received, r.Header.Get("Cache-Control"))
+				receivedMx.Unlock()
+			}))
+			t.Cleanup(server.Close)
+
+			reg := prometheus.NewPedanticRegistry()
+			client := NewQueryClient(QueryClientConfig{
+				URL:                   server.URL,
+				UserID:                "user-1",
+				QueryTimeout:          time.Second,
+				QueryNoCache:          testData.noCache,
+				QueryNoCacheAlternate: testData.noCacheAlternate,
+			}, log.NewNopLogger(), reg)
+
+			for range testData.expected {
+				_, _ = client.runQuery(time.Now().Add(-time.Minute), time.Now(), time.Second, "up", time.Second)
+			}
+
+			receivedMx.Lock()
+			defer receivedMx.Unlock()
+			assert.Equal(t, testData.expected, received)
+
+			// The query duration should be tracked by whether the cache was bypassed.
+			bypassed := 0
+			for _, value := range testData.expected {
+				if value != "" {
+					bypassed++
+				}
+			}
+
+			for cache, expectedCount := range map[string]int{queryCacheBypassed: bypassed, queryCacheDefault: len(testData.expected) - bypassed} {
+				metric := &dto.Metric{}
+				require.NoError(t, client.queryDuration.WithLabelValues(cache).(prometheus.Metric).Write(metric))
+				assert.Equal(t, uint64(expectedCount), metric.GetHistogram().GetSampleCount(), cache)
+			}
+		})
+	}
+}
+
+func TestProxyFunc_ShouldProxyWriteAndQueryRequests(t *testing.T) {
+	var (
+		receivedMx sync.Mutex
+		received   []string
+	)
+
+	// The proxy receives requests for the target host, which doesn't exist.
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedMx.Lock()
+		received = append(received, r.Method+" "+r.URL.String())
+		receivedMx.Unlock()
+	}))
+	t.Cleanup(proxy.Close)
+
+	proxyURL, err := url.Parse(proxy.URL)
+	require.NoError(t, err)
+
+	targetURL, err := url.Parse("http://cortex.invalid/api/v1/push")
+	require.NoError(t, err)
+
+	transport := &http.Transport{Proxy: ProxyFunc(proxyURL)}
+
+	writeClient := NewWriteClient(WriteClientConfig{
+		URL:              *targetURL,
+		Transport:        transport,
+		UserID:           "user-1",
+		SeriesCount:      1,
+		WriteInterval:    time.Second,
+		WriteTimeout:     time.Second,
+		WriteConcurrency: 1,
+		WriteBatchSize:   1,
+	}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	writeClient.writeSeries()
+
+	queryClient := NewQueryClient(QueryClientConfig{
+		URL:                   "http://cortex.invalid/prometheus",
+		Transport:             transport,
+		UserID:                "user-1",
+		QueryTimeout:          time.Second,
+		ExpectedWriteInterval: time.Second,
+	}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	_, _ = queryClient.runQuery(time.Now().Add(-time.Minute), time.Now(), time.Second, "up", time.Second)
+
+	receivedMx.Lock()
+	defer receivedMx.Unlock()
+	require.Len(t, received, 2)
+	assert.Equal(t, "POST http://cortex.invalid/api/v1/push", received[0])
+	assert.Regexp(t, "^POST http://cortex.invalid/prometheus/api/v1/query_range", received[1])
+}
+
+func TestProxyFunc_ShouldFallbackToEnvironmentWhenUnset(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "http://cortex.invalid/", nil)
+	require.NoError(t, err)
+
+	proxyURL, err := url.Parse("http://proxy.invalid:3128")
+	require.NoError(t, err)
+
+	actual, err := ProxyFunc(proxyURL)(req)
+	require.NoError(t, err)
+	assert.Equal(t, proxyURL, actual)
+
+	// Without a configured proxy, the environment settings are honored.
+	actual, err = ProxyFunc(nil)(req)
+	require.NoError(t, err)
+	expected, err := http.ProxyFromEnvironment(req)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func TestQueryClient_ShouldGzipRequestBodyIfConfigured(t *testing.T) {
+	for _, gzipRequests := range []bool{false, true} {
+		t.Run(fmt.Sprintf("gzip requests: %t", gzipRequests), func(t *testing.T) {
+			var (
+				receivedMx       sync.Mutex
+				receivedEncoding string
+				receivedQuery    string
+			)
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body := io.Reader(r.Body)
+				if r.Header.Get("Content-Encoding") == "gzip" {
+					gz, err := gzip.NewReader(r.Body)
+					require.NoError(t, err)
+					body = gz
+				}
+
+				data, err := io.ReadAll(body)
+				require.NoError(t, err)
+				values, err := url.ParseQuery(string(data))
+				require.NoError(t, err)
+
+				receivedMx.Lock()
+				receivedEncoding = r.Header.Get("Content-Encoding")
+				receivedQuery = values.Get("query")
+				receivedMx.Unlock()
+			}))
+			t.Cleanup(server.Close)
+
+			client := NewQueryClient(QueryClientConfig{
+				URL:               server.URL,
+				UserID:            "user-1",
+				QueryTimeout:      time.Second,
+				QueryGzipRequests: gzipRequests,
+			}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+			_, _ = client.runQuery(time.Now().Add(-time.Minute), time.Now(), time.Second, "sum(up)", time.Second)
+
+			receivedMx.Lock()
+			defer receivedMx.Unlock()
+			assert.Equal(t, "sum(up)", receivedQuery)
+			if gzipRequests {
+				assert.Equal(t, "gzip", receivedEncoding)
+			} else {
+				assert.Empty(t, receivedEncoding)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"math"
+	"time"
+)
+
+// SeriesSchedule configures how the number of series changes over time. The write and
+// query clients must be configured with the same SeriesSchedule, so that query results
+// can be verified.
+type SeriesSchedule struct {
+	// StartTime is the reference time of the schedule.
+	StartTime time.Time
+
+	// RampStartCount is the number of series at StartTime. The number of series linearly
+	// grows to the target count over RampDuration, then holds. 0 to disable the ramp-up.
+	RampStartCount int
+	RampDuration   time.Duration
+
+	// The number of series is multiplied by BurstMultiplier for BurstDuration at the end of
+	// every BurstInterval since StartTime. 0 to disable bursts.
+	BurstInterval   time.Duration
+	BurstDuration   time.Duration
+	BurstMultiplier float64
+
+	// MaxCount caps the number of series, e.g. to honor a per-tenant rate limit. 0 for no limit.
+	MaxCount int
+}
+
+// seriesCount returns the number of series at t, given the target number of series.
+func (s SeriesSchedule) seriesCount(t time.Time, target int) int {
+	count := s.rampSeriesCount(t, target)
+
+	if s.inBurst(t) {
+		count = int(float64(count) * s.BurstMultiplier)
+	}
+
+	if s.MaxCount > 0 && count > s.MaxCount {
+		count = s.MaxCount
+	}
+
+	return count
+}
+
+// SeriesCountForRate returns the max number of series that can be written every write interval
+// by a client with the input config, without exceeding the input samples per second. It's at
+// least 1 series. The cap is approximate: samples are pushed in bursts every write interval rather
+// than at a steady rate, and the backfill samples of churning series are averaged over the churn
+// period, so the rate is exceeded in the intervals where many series churn at once.
+func SeriesCountForRate(samplesPerSecond float64, cfg WriteClientConfig) int {
+	// The info series are written regardless of the number of series.
+	budget := samplesPerSecond*cfg.WriteInterval.Seconds() - float64(cfg.InfoSeriesCount)
+
+	count := int(math.Floor(budget / cfg.samplesPerSeries()))
+	if count < 1 {
+		return 1
+	}
+
+	return count
+}
+
+// samplesPerSeries returns the average number of samples written every write interval for each
+// series ID, across all metric names, HA replicas, summary quantiles and churn backfills.
+func (cfg WriteClientConfig) samplesPerSeries() float64 {
+	samples := float64(len(sineWaveMetricNames("", cfg.MetricNamesCount)) * len(haReplicaNames(cfg.HAReplicas)))
+
+	// Each sine wave series churns once per churn period, sending its backfill samples.
+	if cfg.ChurnBackfillSamples > 0 && cfg.SeriesChurnPeriod > 0 {
+		samples += samples * float64(cfg.ChurnBackfillSamples) * math.Min(1, cfg.WriteInterval.Seconds()/cfg.SeriesChurnPeriod.Seconds())
+	}
+
+	// The summary quantile series are only written once, regardless of the HA replicas.
+	return samples + float64(len(cfg.SummaryQuantiles))
+}
+
+func (s SeriesSchedule) rampSeriesCount(t time.Time, target int) int {
+	if s.RampDuration <= 0 {
+		return target
+	}
+
+	elapsed := t.Sub(s.StartTime)
+	switch {
+	case elapsed <= 0:
+		return s.RampStartCount
+	case elapsed >= s.RampDuration:
+		return target
+	default:
+		return s.RampStartCount + int(float64(target-s.RampStartCount)*float64(elapsed)/float64(s.RampDuration))
+	}
+}
+
+// inBurst returns whether t is within a burst window.
+func (s SeriesSchedule) inBurst(t time.Time) bool {
+	if s.BurstInterval <= 0 || s.BurstDuration <= 0 || s.BurstMultiplier <= 0 {
+		return false
+	}
+
+	elapsed := t.Sub(s.StartTime)
+	if elapsed < 0 {
+		return false
+	}
+
+	return elapsed%s.BurstInterval >= s.BurstInterval-s.BurstDuration
+}
@@ -0,0 +1,270 @@
+package client
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeriesSchedule_SeriesCount_WithRamp(t *testing.T) {
+	start, err := time.Parse(time.RFC3339, "2023-06-29T00:00:00Z")
+	require.NoError(t, err)
+
+	schedule := SeriesSchedule{StartTime: start, RampStartCount: 100, RampDuration: 10 * time.Minute}
+
+	assert.Equal(t, 100, schedule.seriesCount(start.Add(-time.Minute), 1100))
+	assert.Equal(t, 100, schedule.seriesCount(start, 1100))
+	assert.Equal(t, 200, schedule.seriesCount(start.Add(time.Minute), 1100))
+	assert.Equal(t, 600, schedule.seriesCount(start.Add(5*time.Minute), 1100))
+	assert.Equal(t, 1000, schedule.seriesCount(start.Add(9*time.Minute), 1100))
+	assert.Equal(t, 1100, schedule.seriesCount(start.Add(10*time.Minute), 1100))
+	assert.Equal(t, 1100, schedule.seriesCount(start.Add(time.Hour), 1100))
+
+	// Without a ramp-up, the target count is always returned.
+	assert.Equal(t, 1100, SeriesSchedule{}.seriesCount(start, 1100))
+}
+
+func TestSeriesSchedule_SeriesCount_WithBursts(t *testing.T) {
+	start, err := time.Parse(time.RFC3339, "2023-06-29T00:00:00Z")
+	require.NoError(t, err)
+
+	schedule := SeriesSchedule{StartTime: start, BurstInterval: 10 * time.Minute, BurstDuration: time.Minute, BurstMultiplier: 3}
+
+	for _, elapsed := range []time.Duration{-time.Minute, 0, time.Minute, 8*time.Minute + 59*time.Second, 10 * time.Minute, 15 * time.Minute} {
+		assert.Equal(t, 100, schedule.seriesCount(start.Add(elapsed), 100), elapsed)
+	}
+
+	// The series count should spike during bursts.
+	for _, elapsed := range []time.Duration{9 * time.Minute, 9*time.Minute + 59*time.Second, 19 * time.Minute, 99*time.Minute + 30*time.Second} {
+		assert.Equal(t, 300, schedule.seriesCount(start.Add(elapsed), 100), elapsed)
+	}
+
+	// Bursts should apply on top of the ramp-up.
+	schedule.RampStartCount = 10
+	schedule.RampDuration = 20 * time.Minute
+	assert.Equal(t, 150, schedule.seriesCount(start.Add(9*time.Minute), 100))
+	assert.Equal(t, 100, schedule.seriesCount(start.Add(time.Hour), 100))
+	assert.Equal(t, 300, schedule.seriesCount(start.Add(time.Hour+9*time.Minute), 100))
+}
+
+func TestSeriesSchedule_SeriesCount_WithMaxCount(t *testing.T) {
+	start, err := time.Parse(time.RFC3339, "2023-06-29T00:00:00Z")
+	require.NoError(t, err)
+
+	schedule := SeriesSchedule{StartTime: start, MaxCount: 50}
+	assert.Equal(t, 50, schedule.seriesCount(start, 100))
+	assert.Equal(t, 30, schedule.seriesCount(start, 30))
+
+	// The max count should apply on top of bursts.
+	schedule.BurstInterval = 10 * time.Minute
+	schedule.BurstDuration = time.Minute
+	schedule.BurstMultiplier = 3
+	assert.Equal(t, 50, schedule.seriesCount(start.Add(9*time.Minute), 30))
+}
+
+func TestSeriesCountForRate(t *testing.T) {
+	tests := map[string]struct {
+		rate     float64
+		cfg      WriteClientConfig
+		expected int
+	}{
+		"single metric name": {
+			rate:     10,
+			cfg:      WriteClientConfig{WriteInterval: 10 * time.Second},
+			expected: 100,
+		},
+		"multiple metric names": {
+			rate:     10,
+			cfg:      WriteClientConfig{WriteInterval: 10 * time.Second, MetricNamesCount: 2},
+			expected: 50,
+		},
+		"multiple metric names and HA replicas": {
+			rate:     10,
+			cfg:      WriteClientConfig{WriteInterval: 10 * time.Second, MetricNamesCount: 3, HAReplicas: 2},
+			expected: 16,
+		},
+		"info series": {
+			rate:     10,
+			cfg:      WriteClientConfig{WriteInterval: 10 * time.Second, InfoSeriesCount: 40},
+			expected: 60,
+		},
+		"summary quantiles": {
+			rate:     10,
+			cfg:      WriteClientConfig{WriteInterval: 10 * time.Second, HAReplicas: 2, SummaryQuantiles: []float64{0.5, 0.9, 0.99}},
+			expected: 20,
+		},
+		"churn backfill samples": {
+			rate:     10,
+			cfg:      WriteClientConfig{WriteInterval: 10 * time.Second, SeriesChurnPeriod: time.Minute, ChurnBackfillSamples: 6},
+			expected: 50,
+		},
+		"churn backfill samples with a churn period shorter than the write interval": {
+			rate:     10,
+			cfg:      WriteClientConfig{WriteInterval: 10 * time.Second, SeriesChurnPeriod: time.Second, ChurnBackfillSamples: 4},
+			expected: 20,
+		},
+		"rate lower than the samples of a single series": {
+			rate:     0.01,
+			cfg:      WriteClientConfig{WriteInterval: 10 * time.Second},
+			expected: 1,
+		},
+		"rate lower than the samples of the info series": {
+			rate:     1,
+			cfg:      WriteClientConfig{WriteInterval: 10 * time.Second, InfoSeriesCount: 20},
+			expected: 1,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, testData.expected, SeriesCountForRate(testData.rate, testData.cfg))
+		})
+	}
+}
+
+func TestWriteClient_ShouldHonorTenantRates(t *testing.T) {
+	const (
+		seriesCount   = 1000
+		writeInterval = 10 * time.Second
+	)
+
+	var (
+		samplesMx sync.Mutex
+		samples   = map[string]int{}
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		req, err := decodeWriteRequest(body)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		samplesMx.Lock()
+		samples[r.Header.Get("X-Scope-OrgID")] += countSamples(req)
+		samplesMx.Unlock()
+	}))
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	// The noisy tenant is allowed to push 10x more than the quiet one.
+	rates := map[string]float64{"noisy": 50, "quiet": 5}
+
+	for tenant, rate := range rates {
+		cfg := WriteClientConfig{
+			URL:              *serverURL,
+			UserID:           tenant,
+			SeriesCount:      seriesCount,
+			MetricNamesCount: 2,
+			WriteInterval:    writeInterval,
+			WriteTimeout:     time.Second,
+			WriteConcurrency: 1,
+			WriteBatchSize:   100,
+		}
+		cfg.Schedule.MaxCount = SeriesCountForRate(rate, cfg)
+		client := NewWriteClient(cfg, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+		// Write a few intervals.
+		for i := 0; i < 3; i++ {
+			client.writeSeries()
+		}
+	}
+
+	samplesMx.Lock()
+	defer samplesMx.Unlock()
+
+	assert.Equal(t, 3*int(rates["noisy"]*writeInterval.Seconds()), samples["noisy"])
+	assert.Equal(t, 3*int(rates["quiet"]*writeInterval.Seconds()), samples["quiet"])
+	assert.Equal(t, 10*samples["quiet"], samples["noisy"])
+}
+
+func TestWriteClient_ShouldHonorTenantRateIncludingAllSampleSources(t *testing.T) {
+	const (
+		rate          = 50
+		writeInterval = 10 * time.Second
+	)
+
+	var samples int64
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		req, err := decodeWriteRequest(body)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		atomic.AddInt64(&samples, int64(countSamples(req)))
+	}))
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	cfg := WriteClientConfig{
+		URL:              *serverURL,
+		UserID:           "user-1",
+		SeriesCount:      1000,
+		MetricNamesCount: 2,
+		HAReplicas:       2,
+		InfoSeriesCount:  10,
+		SummaryQuantiles: []float64{0.5, 0.9},
+		WriteInterval:    writeInterval,
+		WriteTimeout:     time.Second,
+		WriteConcurrency: 1,
+		WriteBatchSize:   100,
+	}
+	cfg.Schedule.MaxCount = SeriesCountForRate(rate, cfg)
+	client := NewWriteClient(cfg, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	client.writeSeries()
+
+	// Each series gets 4 sine wave samples (2 metric names by 2 HA replicas) and 2 summary quantile
+	// samples, on top of the info series samples.
+	budget := int64(rate * writeInterval.Seconds())
+	assert.LessOrEqual(t, atomic.LoadInt64(&samples), budget)
+	assert.Greater(t, atomic.LoadInt64(&samples), budget-6)
+}
+
+func TestVerifySineWaveSamples_WithRamp(t *testing.T) {
+	const step = 10 * time.Second
+
+	start, err := time.Parse(time.RFC3339, "2023-06-29T00:00:00Z")
+	require.NoError(t, err)
+
+	schedule := SeriesSchedule{StartTime: start, RampStartCount: 1, RampDuration: time.Minute}
+
+	var samples []model.SamplePair
+	for ts := start; ts.Before(start.Add(2 * time.Minute)); ts = ts.Add(step) {
+		series := generateSineWaveSeries(ts, WriteClientConfig{SeriesCount: schedule.seriesCount(ts, 7)})
+
+		sum := 0.0
+		for _, s := range series {
+			sum += s.Samples[0].Value
+		}
+		samples = append(samples, newSamplePair(ts, sum))
+	}
+
+	// The verifier should use the expected count at each sample timestamp.
+	assert.NoError(t, verifySineWaveSamples(samples, 7, schedule, step, ValueConfig{}, nil))
+	assert.Error(t, verifySineWaveSamples(samples, 7, SeriesSchedule{}, step, ValueConfig{}, nil))
+}
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Generated seed values have a binary exponent in [-seedValuesMaxExponent, seedValuesMaxExponent],
+	// so that their sum can still be verified within the comparison tolerance.
+	seedValuesMaxExponent = 20
+)
+
+// SeedValues generates adversarial values for fuzzing the float handling of the backend: each
+// value has a random sign, exponent and full-width mantissa, so that consecutive values share
+// almost no bits. The sequence of values of each series is driven by a seed read from a file,
+// and is a function of the timestamp and seed alone, so it can be reproduced to verify it.
+type SeedValues struct {
+	seeds []uint64
+}
+
+// LoadSeedValuesFile reads the value seeds from the file at path. Each line of the file contains
+// a seed (a signed 64 bit integer). Empty lines and lines starting with # are ignored. Series are
+// assigned the seeds in order, looping over them if there are more series than seeds.
+func LoadSeedValuesFile(path string) (*SeedValues, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var seeds []uint64
+
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		seed, err := strconv.ParseInt(line, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid seed: %w", path, lineNum, err)
+		}
+
+		seeds = append(seeds, uint64(seed))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(seeds) == 0 {
+		return nil, fmt.Errorf("no value seeds in %s", path)
+	}
+
+	return &SeedValues{seeds: seeds}, nil
+}
+
+// Value returns the value of the series with the input ID at t.
+func (v *SeedValues) Value(t time.Time, seriesID int) float64 {
+	seed := v.seeds[(seriesID-1)%len(v.seeds)]
+	bits := splitmix64(seed ^ splitmix64(uint64(t.UnixMilli())))
+
+	// The lowest 52 bits are the mantissa, the next ones pick the exponent and the top one the sign.
+	mantissa := 1 + float64(bits&(1<<52-1))/(1<<52)
+	exponent := int((bits>>52)&0x3f) % (2*seedValuesMaxExponent + 1)
+	value := math.Ldexp(mantissa, exponent-seedValuesMaxExponent)
+
+	if bits>>63 == 1 {
+		value = -value
+	}
+
+	return value
+}
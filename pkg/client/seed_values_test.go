@@ -0,0 +1,87 @@
+package client
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeedValues_ShouldBeReproducibleFromSeedFile(t *testing.T) {
+	const (
+		interval  = 10 * time.Second
+		numSeries = 5
+	)
+
+	path := filepath.Join(t.TempDir(), "seeds.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# fuzzing seeds\n1\n-42\n\n9223372036854775807\n"), 0644))
+
+	seeds, err := LoadSeedValuesFile(path)
+	require.NoError(t, err)
+
+	// The writer and the verifier load the seed file independently.
+	verifierSeeds, err := LoadSeedValuesFile(path)
+	require.NoError(t, err)
+
+	cfg := WriteClientConfig{SeriesCount: numSeries, Values: ValueConfig{Seeds: seeds}}
+	verifierCfg := ValueConfig{Seeds: verifierSeeds}
+
+	var samples []model.SamplePair
+	for ts := time.UnixMilli(0); len(samples) < 100; ts = ts.Add(interval) {
+		series := generateSineWaveSeries(ts, cfg)
+		require.Len(t, series, numSeries)
+
+		sum := 0.0
+		for i, s := range series {
+			value := s.Samples[0].Value
+			assert.Equal(t, verifierCfg.value(ts, i+1), value)
+
+			exponent := math.Ilogb(value)
+			assert.GreaterOrEqual(t, exponent, -seedValuesMaxExponent)
+			assert.LessOrEqual(t, exponent, seedValuesMaxExponent)
+
+			sum += value
+		}
+
+		// Series loop over the seeds.
+		assert.Equal(t, series[0].Samples[0].Value, series[3].Samples[0].Value)
+		assert.NotEqual(t, series[0].Samples[0].Value, series[1].Samples[0].Value)
+
+		samples = append(samples, newSamplePair(ts, sum))
+	}
+
+	assert.NoError(t, verifySineWaveSamples(samples, numSeries, SeriesSchedule{}, interval, verifierCfg, nil))
+	assert.Error(t, verifySineWaveSamples(samples, numSeries, SeriesSchedule{}, interval, ValueConfig{Seeds: &SeedValues{seeds: []uint64{2}}}, nil))
+}
+
+func TestLoadSeedValuesFile_Invalid(t *testing.T) {
+	tests := map[string]struct {
+		content     string
+		expectedErr string
+	}{
+		"empty file": {
+			content:     "# nothing\n",
+			expectedErr: "no value seeds",
+		},
+		"invalid seed": {
+			content:     "1\nabc\n",
+			expectedErr: "seeds.txt:2: invalid seed",
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "seeds.txt")
+			require.NoError(t, os.WriteFile(path, []byte(testData.content), 0644))
+
+			_, err := LoadSeedValuesFile(path)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), testData.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// seriesPool holds the label sets of the generated sine wave series. With a static config the
+// label sets don't change across write intervals, so they're built once and only the samples
+// are generated each interval.
+type seriesPool struct {
+	// seriesCount is the number of series per metric name and HA replica the pool was built for.
+	seriesCount int
+
+	labels    [][]*prompb.Label
+	seriesIDs []int
+}
+
+// newSeriesPool builds the series pool for the input config, or returns nil if the series
+// labels change over time with the input config.
+func newSeriesPool(cfg WriteClientConfig) *seriesPool {
+	if !canPoolSeries(cfg) {
+		return nil
+	}
+
+	series := generateSineWaveSeries(time.Unix(0, 0), cfg)
+	pool := &seriesPool{
+		seriesCount: cfg.SeriesCount,
+		labels:      make([][]*prompb.Label, 0, len(series)),
+		seriesIDs:   make([]int, 0, len(series)),
+	}
+
+	// Series are generated grouped by HA replica and metric name, each one with series IDs from 1 to SeriesCount.
+	for idx, s := range series {
+		pool.labels = append(pool.labels, s.Labels)
+		pool.seriesIDs = append(pool.seriesIDs, idx%cfg.SeriesCount+1)
+	}
+
+	return pool
+}
+
+// canPoolSeries returns whether the series labels never change over time with the input config.
+func canPoolSeries(cfg WriteClientConfig) bool {
+	switch {
+	case cfg.SeriesCount <= 0:
+		return false
+	case cfg.SeriesChurnPeriod > 0, cfg.NameRotationPeriod > 0, cfg.FullChurn:
+		return false
+	case cfg.ValueChurnLabel != "" && cfg.ValueChurnPeriod > 0:
+		return false
+	case cfg.ChecksumLabel:
+		// The checksum label depends on the value.
+		return false
+	default:
+		return true
+	}
+}
+
+// generate returns the series at t, sharing the pooled label sets. The returned labels must
+// not be modified. The output is the same as generateSineWaveSeries.
+func (p *seriesPool) generate(t time.Time, cfg WriteClientConfig) []*prompb.TimeSeries {
+	out := make([]*prompb.TimeSeries, 0, len(p.labels))
+	baseValue := cfg.Values.baseValue(t)
+
+	for idx, labels := range p.labels {
+		seriesID := p.seriesIDs[idx]
+
+		out = append(out, &prompb.TimeSeries{
+			Labels: labels,
+			Samples: []prompb.Sample{{
+				Value:     cfg.Values.seriesValue(t, baseValue, seriesID),
+				Timestamp: t.Add(-seriesClockSkew(seriesID, cfg.ClockSkewStdDev, cfg.WriteInterval)).UnixMilli(),
+			}},
+		})
+	}
+
+	return out
+}
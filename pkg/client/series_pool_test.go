@@ -0,0 +1,150 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeriesPool_ShouldGenerateTheSameSeriesOfThePerIntervalPath(t *testing.T) {
+	tests := map[string]WriteClientConfig{
+		"default": {
+			SeriesCount: 10,
+		},
+		"extra and distinct labels": {
+			SeriesCount:        10,
+			ExtraLabels:        3,
+			DistinctLabelNames: 4,
+		},
+		"dimensions and fake instances": {
+			SeriesCount:   12,
+			Dimensions:    []Dimension{{Name: "region", Size: 3}, {Name: "host", Size: 4}},
+			FakeInstances: 5,
+		},
+		"multiple metric names and HA replicas": {
+			SeriesCount:      5,
+			MetricNamesCount: 3,
+			HAReplicas:       2,
+		},
+		"series shards and unsorted labels": {
+			SeriesCount:        10,
+			SeriesShards:       3,
+			SendUnsortedLabels: true,
+		},
+		"decorrelated, inverse and quantized values": {
+			SeriesCount: 10,
+			Values:      ValueConfig{Decorrelation: 0.5, InverseRatio: 0.3, Quantization: 2},
+		},
+		"clock skew": {
+			SeriesCount:     10,
+			WriteInterval:   10 * time.Second,
+			ClockSkewStdDev: time.Second,
+		},
+	}
+
+	start, err := time.Parse(time.RFC3339, "2023-06-29T00:00:00Z")
+	require.NoError(t, err)
+
+	for testName, cfg := range tests {
+		t.Run(testName, func(t *testing.T) {
+			pool := newSeriesPool(cfg)
+			require.NotNil(t, pool)
+
+			for ts := start; ts.Before(start.Add(time.Minute)); ts = ts.Add(10 * time.Second) {
+				assert.Equal(t, generateSineWaveSeries(ts, cfg), pool.generate(ts, cfg), ts.String())
+			}
+		})
+	}
+}
+
+func TestSeriesPool_ShouldOnlyPoolSeriesWhoseLabelsDontChangeOverTime(t *testing.T) {
+	tests := map[string]struct {
+		cfg      WriteClientConfig
+		expected bool
+	}{
+		"static series": {
+			cfg:      WriteClientConfig{SeriesCount: 10},
+			expected: true,
+		},
+		"value churn label without period": {
+			cfg:      WriteClientConfig{SeriesCount: 10, ValueChurnLabel: "pod"},
+			expected: true,
+		},
+		"churning series": {
+			cfg:      WriteClientConfig{SeriesCount: 10, SeriesChurnPeriod: time.Minute},
+			expected: false,
+		},
+		"value churn label": {
+			cfg:      WriteClientConfig{SeriesCount: 10, ValueChurnLabel: "pod", ValueChurnPeriod: time.Minute},
+			expected: false,
+		},
+		"checksum label": {
+			cfg:      WriteClientConfig{SeriesCount: 10, ChecksumLabel: true},
+			expected: false,
+		},
+		"no series": {
+			cfg:      WriteClientConfig{SeriesCount: 0},
+			expected: false,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, testData.expected, newSeriesPool(testData.cfg) != nil)
+		})
+	}
+}
+
+func TestWriteClient_ShouldNotUseSeriesPoolIfTheSeriesCountChanges(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	client := NewWriteClient(WriteClientConfig{
+		URL:              *serverURL,
+		UserID:           "user-1",
+		SeriesCount:      10,
+		Schedule:         SeriesSchedule{MaxCount: 5},
+		SeriesPool:       true,
+		WriteInterval:    10 * time.Second,
+		WriteTimeout:     time.Second,
+		WriteConcurrency: 1,
+		WriteBatchSize:   100,
+	}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	require.NotNil(t, client.pool)
+
+	assert.Equal(t, 5, client.writeSeries())
+}
+
+func BenchmarkGenerateSineWaveSeries(b *testing.B) {
+	cfg := WriteClientConfig{SeriesCount: 10000, ExtraLabels: 5, WriteInterval: 10 * time.Second}
+	ts := time.Now()
+
+	b.Run("per-interval", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for n := 0; n < b.N; n++ {
+			generateSineWaveSeries(ts, cfg)
+		}
+	})
+
+	b.Run("pool", func(b *testing.B) {
+		pool := newSeriesPool(cfg)
+
+		b.ReportAllocs()
+		b.ResetTimer()
+
+		for n := 0; n < b.N; n++ {
+			pool.generate(ts, cfg)
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const defaultSeriesPreviewLimit = 10
+
+type seriesPreview struct {
+	Labels    map[string]string `json:"labels"`
+	Value     float64           `json:"value"`
+	Timestamp int64             `json:"timestamp"`
+}
+
+// SeriesPreviewHandler returns an HTTP handler responding with the first N series (where N
+// is the "limit" query parameter) the client of a tenant will generate at the next write interval.
+// The tenant is the "tenant" query parameter, defaulting to the tenant of the first client, since
+// the series of each tenant may differ (e.g. in number or metric name).
+func SeriesPreviewHandler(clients []*WriteClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var c *WriteClient
+		for _, candidate := range clients {
+			if tenant := r.URL.Query().Get("tenant"); tenant == "" || tenant == candidate.cfg.UserID {
+				c = candidate
+				break
+			}
+		}
+		if c == nil {
+			http.Error(w, "unknown tenant", http.StatusNotFound)
+			return
+		}
+
+		limit := defaultSeriesPreviewLimit
+		if value := r.URL.Query().Get("limit"); value != "" {
+			var err error
+			if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+				http.Error(w, "invalid limit", http.StatusBadRequest)
+				return
+			}
+		}
+
+		ts := alignTimestampToInterval(time.Now(), c.cfg.WriteInterval).Add(c.cfg.WriteInterval)
+		series := generateSineWaveSeries(ts, c.cfg)
+		if len(series) > limit {
+			series = series[:limit]
+		}
+
+		out := make([]seriesPreview, 0, len(series))
+		for _, s := range series {
+			// Preview the latest sample, ignoring the backfilled ones.
+			sample := s.Samples[len(s.Samples)-1]
+			preview := seriesPreview{
+				Labels:    make(map[string]string, len(s.Labels)),
+				Value:     sample.Value,
+				Timestamp: sample.Timestamp,
+			}
+			for _, l := range s.Labels {
+				preview.Labels[l.Name] = l.Value
+			}
+			out = append(out, preview)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(out); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteClient_SeriesPreviewHandler(t *testing.T) {
+	client := NewWriteClient(WriteClientConfig{
+		UserID:           "user-1",
+		SeriesCount:      10,
+		ExtraLabels:      1,
+		WriteInterval:    10 * time.Second,
+		WriteConcurrency: 1,
+		WriteBatchSize:   10,
+	}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+	t.Run("should return the first N series", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		SeriesPreviewHandler([]*WriteClient{client}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/series-preview?limit=2", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+		var actual []struct {
+			Labels    map[string]string `json:"labels"`
+			Value     *float64          `json:"value"`
+			Timestamp int64             `json:"timestamp"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &actual))
+		require.Len(t, actual, 2)
+
+		for idx, series := range actual {
+			assert.Equal(t, map[string]string{
+				"__name__":    "cortex_load_generator_sine_wave",
+				"extraLabel0": "default",
+				"wave":        []string{"1", "2"}[idx],
+			}, series.Labels)
+			require.NotNil(t, series.Value)
+			assert.Equal(t, generateSineWaveValue(time.UnixMilli(series.Timestamp)), *series.Value)
+
+			// The preview should be about the next write interval.
+			assert.Zero(t, series.Timestamp%10000)
+			assert.True(t, time.UnixMilli(series.Timestamp).After(time.Now()))
+		}
+	})
+
+	t.Run("should return 400 on invalid limit", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		SeriesPreviewHandler([]*WriteClient{client}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/series-preview?limit=abc", nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("should return 404 on unknown tenant", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		SeriesPreviewHandler([]*WriteClient{client}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/series-preview?tenant=user-2", nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestSeriesPreviewHandler_ShouldPreviewTheSeriesOfTheInputTenant(t *testing.T) {
+	var clients []*WriteClient
+	for _, tenant := range []string{"user-1", "user-2"} {
+		clients = append(clients, NewWriteClient(WriteClientConfig{
+			UserID:           tenant,
+			SeriesCount:      3,
+			TenantMetricName: true,
+			WriteInterval:    10 * time.Second,
+			WriteConcurrency: 1,
+			WriteBatchSize:   10,
+		}, log.NewNopLogger(), prometheus.NewPedanticRegistry()))
+	}
+
+	tests := map[string]struct {
+		query        string
+		expectedName string
+	}{
+		"should default to the first tenant": {
+			query:        "",
+			expectedName: "cortex_load_generator_sine_wave_user_1",
+		},
+		"should preview the first tenant": {
+			query:        "?tenant=user-1",
+			expectedName: "cortex_load_generator_sine_wave_user_1",
+		},
+		"should preview another tenant": {
+			query:        "?tenant=user-2",
+			expectedName: "cortex_load_generator_sine_wave_user_2",
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			SeriesPreviewHandler(clients).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/series-preview"+testData.query, nil))
+			require.Equal(t, http.StatusOK, rec.Code)
+
+			var actual []struct {
+				Labels map[string]string `json:"labels"`
+			}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &actual))
+			require.Len(t, actual, 3)
+
+			for _, series := range actual {
+				assert.Equal(t, testData.expectedName, series.Labels["__name__"])
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+const shardLabelName = "shard"
+
+// labelsHash returns the xxhash of the input labels, sorted by name, like Prometheus hashes label sets.
+func labelsHash(labels []*prompb.Label) uint64 {
+	sorted := make([]*prompb.Label, len(labels))
+	copy(sorted, labels)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	sep := []byte{'\xff'}
+	h := xxhash.New()
+	for _, l := range sorted {
+		_, _ = h.WriteString(l.Name)
+		_, _ = h.Write(sep)
+		_, _ = h.WriteString(l.Value)
+		_, _ = h.Write(sep)
+	}
+
+	return h.Sum64()
+}
+
+// seriesShard returns the shard the series with the input labels falls into, out of the input number of shards.
+func seriesShard(labels []*prompb.Label, shards int) int {
+	return int(labelsHash(labels) % uint64(shards))
+}
+
+// trackSeriesPerShard observes the number of input series falling into each shard.
+func (c *WriteClient) trackSeriesPerShard(series []*prompb.TimeSeries) {
+	counts := make([]int, c.cfg.SeriesShards)
+	for _, s := range series {
+		for _, l := range s.Labels {
+			if l.Name != shardLabelName {
+				continue
+			}
+
+			if shard, err := strconv.Atoi(l.Value); err == nil && shard >= 0 && shard < len(counts) {
+				counts[shard]++
+			}
+		}
+	}
+
+	for _, count := range counts {
+		c.seriesPerShard.Observe(float64(count))
+	}
+}
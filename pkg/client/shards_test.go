@@ -0,0 +1,94 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLabelsHash_ShouldNotDependOnLabelsOrder(t *testing.T) {
+	sorted := []*prompb.Label{{Name: "a", Value: "1"}, {Name: "b", Value: "2"}}
+	unsorted := []*prompb.Label{{Name: "b", Value: "2"}, {Name: "a", Value: "1"}}
+
+	assert.Equal(t, labelsHash(sorted), labelsHash(unsorted))
+	assert.NotEqual(t, labelsHash(sorted), labelsHash([]*prompb.Label{{Name: "a", Value: "1"}, {Name: "b", Value: "3"}}))
+
+	// The input labels should not be modified.
+	assert.Equal(t, "b", unsorted[0].Name)
+}
+
+func TestGenerateSineWaveSeries_WithSeriesShards(t *testing.T) {
+	const (
+		numSeries = 10000
+		numShards = 10
+	)
+
+	series := generateSineWaveSeries(time.Now(), WriteClientConfig{SeriesCount: numSeries, SeriesShards: numShards})
+	require.Len(t, series, numSeries)
+
+	counts := map[string]int{}
+	for _, s := range series {
+		var (
+			shard  string
+			others []*prompb.Label
+		)
+		for _, l := range s.Labels {
+			if l.Name == "shard" {
+				shard = l.Value
+				continue
+			}
+			others = append(others, l)
+		}
+
+		// The shard should be the hash of all the other labels.
+		require.Equal(t, strconv.Itoa(seriesShard(others, numShards)), shard)
+		counts[shard]++
+	}
+
+	// The distribution should be roughly even for uniform series.
+	require.Len(t, counts, numShards)
+	for shard, count := range counts {
+		assert.InDelta(t, numSeries/numShards, count, 0.1*numSeries/numShards, "shard: %s", shard)
+	}
+}
+
+func TestWriteClient_ShouldTrackSeriesPerShard(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	client := NewWriteClient(WriteClientConfig{
+		URL:              *serverURL,
+		UserID:           "user-1",
+		SeriesCount:      100,
+		SeriesShards:     4,
+		WriteInterval:    10 * time.Second,
+		WriteTimeout:     time.Second,
+		WriteConcurrency: 1,
+		WriteBatchSize:   100,
+	}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+	client.writeSeries()
+
+	// The number of series of each shard should have been observed once.
+	metric := &dto.Metric{}
+	require.NoError(t, client.seriesPerShard.Write(metric))
+	assert.Equal(t, uint64(4), metric.GetHistogram().GetSampleCount())
+	assert.Equal(t, 100.0, metric.GetHistogram().GetSampleSum())
+
+	// No shard should be empty.
+	assert.Equal(t, 1.0, metric.GetHistogram().GetBucket()[0].GetUpperBound())
+	assert.Equal(t, uint64(0), metric.GetHistogram().GetBucket()[0].GetCumulativeCount())
+}
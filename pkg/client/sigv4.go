@@ -0,0 +1,62 @@
+package client
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+)
+
+// sigV4ServiceName is the name of the Amazon Managed Service for Prometheus service
+// requests are signed for.
+const sigV4ServiceName = "aps"
+
+// sigV4RoundTripper signs each request with AWS Signature Version 4.
+type sigV4RoundTripper struct {
+	region string
+	signer *v4.Signer
+	rt     http.RoundTripper
+}
+
+// NewSigV4RoundTripper returns a round tripper signing requests with AWS SigV4 for the input region,
+// using the credentials found by the default AWS credential chain.
+func NewSigV4RoundTripper(region string, rt http.RoundTripper) (http.RoundTripper, error) {
+	sess, err := session.NewSession(&aws.Config{Region: aws.String(region)})
+	if err != nil {
+		return nil, err
+	}
+
+	return newSigV4RoundTripper(region, sess.Config.Credentials, rt), nil
+}
+
+func newSigV4RoundTripper(region string, creds *credentials.Credentials, rt http.RoundTripper) *sigV4RoundTripper {
+	return &sigV4RoundTripper{
+		region: region,
+		signer: v4.NewSigner(creds),
+		rt:     rt,
+	}
+}
+
+func (rt *sigV4RoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	// The signature covers the body, so it has to be read.
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		_ = req.Body.Close()
+	}
+
+	req = cloneRequest(req)
+	if _, err := rt.signer.Sign(req, bytes.NewReader(body), sigV4ServiceName, rt.region, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return rt.rt.RoundTrip(req)
+}
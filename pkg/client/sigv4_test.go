@@ -0,0 +1,70 @@
+package client
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigV4RoundTripper_ShouldSignWriteRequests(t *testing.T) {
+	var (
+		receivedMx sync.Mutex
+		received   http.Header
+		series     int
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		req, err := decodeWriteRequest(body)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		receivedMx.Lock()
+		received = r.Header.Clone()
+		series = len(req.Timeseries)
+		receivedMx.Unlock()
+	}))
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	creds := credentials.NewStaticCredentials("AKIDEXAMPLE", "secret", "")
+
+	client := NewWriteClient(WriteClientConfig{
+		URL:              *serverURL,
+		Transport:        newSigV4RoundTripper("us-east-1", creds, http.DefaultTransport),
+		UserID:           "user-1",
+		SeriesCount:      3,
+		WriteInterval:    time.Second,
+		WriteTimeout:     time.Second,
+		WriteConcurrency: 1,
+		WriteBatchSize:   10,
+	}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+	client.writeSeries()
+
+	receivedMx.Lock()
+	defer receivedMx.Unlock()
+	require.NotNil(t, received)
+	assert.Regexp(t, `^AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/\d{8}/us-east-1/aps/aws4_request, SignedHeaders=[a-z0-9;-]*x-scope-orgid[a-z0-9;-]*, Signature=[0-9a-f]{64}$`, received.Get("Authorization"))
+	assert.NotEmpty(t, received.Get("X-Amz-Date"))
+
+	// The body should still be sent once signed.
+	assert.Equal(t, 3, series)
+}
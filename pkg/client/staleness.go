@@ -0,0 +1,159 @@
+package client
+
+import (
+	"context"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/prometheus/pkg/value"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+var staleNaN = math.Float64frombits(value.StaleNaN)
+
+func (c *WriteClient) setLastWrite(ts time.Time) {
+	c.lastWriteMx.Lock()
+	defer c.lastWriteMx.Unlock()
+
+	c.lastWrite = ts
+}
+
+func (c *WriteClient) getLastWrite() time.Time {
+	c.lastWriteMx.Lock()
+	defer c.lastWriteMx.Unlock()
+
+	return c.lastWrite
+}
+
+// writeStaleMarkers writes a stale marker for each series of the last write, and returns the
+// number of series successfully marked as stale. Series are generated again at the timestamp of
+// the last write, so that they're the same series, even if churning.
+func (c *WriteClient) writeStaleMarkers() int {
+	last := c.getLastWrite()
+	if last.IsZero() {
+		return 0
+	}
+
+	// The stale markers must be after the last sample of each series.
+	ts := time.Now()
+	if !ts.After(last) {
+		ts = last.Add(time.Millisecond)
+	}
+
+	pushed := c.sendStaleMarkers(c.generateReplicas(last, c.scheduledConfig(last)), ts)
+
+	level.Info(c.logger).Log("msg", "wrote stale markers", "series", pushed)
+	return pushed
+}
+
+// writeDroppedSeriesStaleMarkers writes a stale marker at ts for each series of the last write
+// which isn't written at ts because the scheduled number of series decreased, and returns the
+// number of series successfully marked as stale.
+func (c *WriteClient) writeDroppedSeriesStaleMarkers(ts time.Time) int {
+	last := c.getLastWrite()
+	if last.IsZero() || !ts.After(last) {
+		return 0
+	}
+
+	lastCfg := c.scheduledConfig(last)
+	cfg := lastCfg
+	cfg.SeriesCount = c.cfg.Schedule.seriesCount(ts, c.cfg.SeriesCount)
+	if cfg.SeriesCount >= lastCfg.SeriesCount {
+		return 0
+	}
+
+	// Series are generated at the timestamp of the last write for both the previous and the
+	// current number of series, so that the label sets of the series still written match.
+	kept := map[string]struct{}{}
+	for _, series := range c.generateReplicas(last, cfg) {
+		for _, s := range series {
+			kept[labelsString(s.Labels)] = struct{}{}
+		}
+	}
+
+	replicas := c.generateReplicas(last, lastCfg)
+	for i, series := range replicas {
+		dropped := series[:0]
+		for _, s := range series {
+			if _, ok := kept[labelsString(s.Labels)]; !ok {
+				dropped = append(dropped, s)
+			}
+		}
+		replicas[i] = dropped
+	}
+
+	pushed := c.sendStaleMarkers(replicas, ts)
+
+	level.Info(c.logger).Log("msg", "wrote stale markers for the series dropped by the schedule", "series", pushed)
+	return pushed
+}
+
+// generateReplicas generates the series at t with the input config, split by HA replica.
+func (c *WriteClient) generateReplicas(t time.Time, cfg WriteClientConfig) [][]*prompb.TimeSeries {
+	// Each HA replica is written in dedicated requests, like the samples.
+	replicas := splitHAReplicas(generateSineWaveSeries(t, cfg), len(haReplicaNames(cfg.HAReplicas)))
+	replicas[len(replicas)-1] = append(replicas[len(replicas)-1], generateInfoSeries(t, c.cfg)...)
+	replicas[len(replicas)-1] = append(replicas[len(replicas)-1], generateSummarySeries(t, cfg)...)
+
+	return replicas
+}
+
+// sendStaleMarkers writes a stale marker at ts for each of the input series, split by HA replica,
+// and returns the number of series successfully marked as stale.
+func (c *WriteClient) sendStaleMarkers(replicas [][]*prompb.TimeSeries, ts time.Time) int {
+	var batches [][]*prompb.TimeSeries
+	for _, series := range replicas {
+		if len(series) == 0 {
+			continue
+		}
+
+		for _, s := range series {
+			s.Samples = []prompb.Sample{{Value: staleNaN, Timestamp: ts.UnixMilli()}}
+		}
+
+		batches = append(batches, splitBatchesBySize(partitionSeries(series, c.cfg.WriteBatchSize, c.cfg.BatchAssignment), c.cfg.MaxWriteBytes, c.metadata)...)
+	}
+
+	var pushed int64
+
+	writeGate := c.getWriteGate(c.cfg.WriteConcurrency)
+	wg := sync.WaitGroup{}
+	wg.Add(len(batches))
+
+	for _, batch := range batches {
+		go func(batch []*prompb.TimeSeries) {
+			defer wg.Done()
+
+			if err := writeGate.Start(context.Background()); err != nil {
+				return
+			}
+			defer writeGate.Done()
+
+			err := c.send(context.Background(), &prompb.WriteRequest{Timeseries: batch})
+			c.writeRequestsTotal.WithLabelValues(writeResult(err)).Inc()
+			if err != nil {
+				level.Error(c.logger).Log("msg", "failed to write stale markers", "err", err)
+				return
+			}
+
+			atomic.AddInt64(&pushed, int64(len(batch)))
+		}(batch)
+	}
+
+	wg.Wait()
+
+	return int(atomic.LoadInt64(&pushed))
+}
+
+// labelsString returns a string representation of the input labels, to use as map key.
+func labelsString(labels []*prompb.Label) string {
+	b := strings.Builder{}
+	for _, l := range labels {
+		b.WriteString(l.Name + "=" + l.Value + ",")
+	}
+	return b.String()
+}
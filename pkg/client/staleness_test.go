@@ -0,0 +1,121 @@
+package client
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/pkg/value"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteClient_Stop(t *testing.T) {
+	tests := map[string]bool{
+		"without stale markers": false,
+		"with stale markers":    true,
+	}
+
+	for testName, staleMarkers := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var (
+				receivedMx sync.Mutex
+				received   []*prompb.TimeSeries
+			)
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				if !assert.NoError(t, err) {
+					return
+				}
+
+				req, err := decodeWriteRequest(body)
+				if !assert.NoError(t, err) {
+					return
+				}
+
+				receivedMx.Lock()
+				received = append(received, req.Timeseries...)
+				receivedMx.Unlock()
+			}))
+			t.Cleanup(server.Close)
+
+			serverURL, err := url.Parse(server.URL)
+			require.NoError(t, err)
+
+			client := NewWriteClient(WriteClientConfig{
+				URL:                *serverURL,
+				UserID:             "user-1",
+				SeriesCount:        3,
+				HAReplicas:         2,
+				InfoSeriesCount:    1,
+				WriteInterval:      time.Hour,
+				WriteTimeout:       time.Second,
+				WriteConcurrency:   2,
+				WriteBatchSize:     2,
+				StaleMarkersOnStop: staleMarkers,
+			}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+			client.Start()
+
+			// Wait until the initial write completed.
+			const numSeries = 3*2 + 1
+			require.Eventually(t, func() bool {
+				receivedMx.Lock()
+				defer receivedMx.Unlock()
+				return len(received) == numSeries
+			}, 5*time.Second, 10*time.Millisecond)
+
+			client.Stop()
+
+			receivedMx.Lock()
+			defer receivedMx.Unlock()
+
+			if !staleMarkers {
+				assert.Len(t, received, numSeries)
+				return
+			}
+
+			// Each written series should have been marked as stale, after its last sample.
+			require.Len(t, received, 2*numSeries)
+
+			written := map[string]int64{}
+			for _, s := range received[:numSeries] {
+				written[labelsString(s.Labels)] = s.Samples[0].Timestamp
+			}
+
+			for _, s := range received[numSeries:] {
+				lastTimestamp, ok := written[labelsString(s.Labels)]
+				require.True(t, ok, "unexpected series %s", labelsString(s.Labels))
+				require.Len(t, s.Samples, 1)
+				assert.True(t, value.IsStaleNaN(s.Samples[0].Value))
+				assert.Greater(t, s.Samples[0].Timestamp, lastTimestamp)
+
+				delete(written, labelsString(s.Labels))
+			}
+			assert.Empty(t, written)
+		})
+	}
+}
+
+func TestWriteClient_ShouldNotWriteStaleMarkersWithoutPreviousWrites(t *testing.T) {
+	client := NewWriteClient(WriteClientConfig{
+		URL:                url.URL{Scheme: "http", Host: "localhost:1"},
+		UserID:             "user-1",
+		SeriesCount:        3,
+		WriteInterval:      time.Hour,
+		WriteConcurrency:   1,
+		WriteBatchSize:     1,
+		StaleMarkersOnStop: true,
+	}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+	assert.Equal(t, 0, client.writeStaleMarkers())
+	client.Stop()
+}
@@ -0,0 +1,14 @@
+package client
+
+import "time"
+
+// StartupDelay returns the delay before starting the client of the tenant with the input
+// index (0-based), so that the clients of all tenants start evenly spread over the input
+// window instead of all at the same time. 0 spread disables it.
+func StartupDelay(tenantIndex, tenantsCount int, spread time.Duration) time.Duration {
+	if spread <= 0 || tenantsCount <= 1 {
+		return 0
+	}
+
+	return time.Duration(int64(spread) * int64(tenantIndex) / int64(tenantsCount))
+}
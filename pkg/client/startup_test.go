@@ -0,0 +1,66 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartupDelay(t *testing.T) {
+	tests := map[string]struct {
+		tenantsCount int
+		spread       time.Duration
+		expected     []time.Duration
+	}{
+		"spread disabled": {
+			tenantsCount: 3,
+			spread:       0,
+			expected:     []time.Duration{0, 0, 0},
+		},
+		"single tenant": {
+			tenantsCount: 1,
+			spread:       time.Minute,
+			expected:     []time.Duration{0},
+		},
+		"tenants evenly spread over the window": {
+			tenantsCount: 4,
+			spread:       time.Minute,
+			expected:     []time.Duration{0, 15 * time.Second, 30 * time.Second, 45 * time.Second},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var actual []time.Duration
+			for i := 0; i < testData.tenantsCount; i++ {
+				actual = append(actual, StartupDelay(i, testData.tenantsCount, testData.spread))
+			}
+
+			assert.Equal(t, testData.expected, actual)
+		})
+	}
+
+	t.Run("start times should be distributed across the window", func(t *testing.T) {
+		const (
+			tenantsCount = 100
+			spread       = 10 * time.Second
+		)
+
+		// Simulate the start time of each tenant, given a fixed clock.
+		now := time.Unix(1000, 0)
+		buckets := make([]int, 10)
+
+		for i := 0; i < tenantsCount; i++ {
+			startTime := now.Add(StartupDelay(i, tenantsCount, spread))
+			assert.False(t, startTime.Before(now))
+			assert.True(t, startTime.Before(now.Add(spread)))
+
+			buckets[startTime.Sub(now)/time.Second]++
+		}
+
+		for idx, count := range buckets {
+			assert.Equal(t, tenantsCount/len(buckets), count, "bucket %d", idx)
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+const (
+	summaryMetricName    = "cortex_load_generator_summary"
+	summaryQuantileLabel = "quantile"
+)
+
+// ParseSummaryQuantiles parses a comma-separated list of summary quantiles between 0 and 1,
+// e.g. "0.5,0.9,0.99". The returned quantiles are sorted.
+func ParseSummaryQuantiles(s string) ([]float64, error) {
+	var quantiles []float64
+
+	for _, part := range strings.Split(s, ",") {
+		q, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || q < 0 || q > 1 {
+			return nil, fmt.Errorf("invalid summary quantile %q, expected a number between 0 and 1", part)
+		}
+
+		quantiles = append(quantiles, q)
+	}
+
+	sort.Float64s(quantiles)
+	for i := 1; i < len(quantiles); i++ {
+		if quantiles[i] == quantiles[i-1] {
+			return nil, fmt.Errorf("duplicated summary quantile %v", quantiles[i])
+		}
+	}
+
+	return quantiles, nil
+}
+
+// generateSummarySeries generates the quantile series of a classic Prometheus summary for each
+// series ID. The value of each quantile is the sine wave series value plus the quantile, so
+// that quantile values are deterministic and increase with the quantile.
+func generateSummarySeries(t time.Time, cfg WriteClientConfig) []*prompb.TimeSeries {
+	if len(cfg.SummaryQuantiles) == 0 {
+		return nil
+	}
+
+	out := make([]*prompb.TimeSeries, 0, cfg.SeriesCount*len(cfg.SummaryQuantiles))
+	baseValue := cfg.Values.baseValue(t)
+	externalLabels := sortedExternalLabels(cfg.ExternalLabels)
+	metricName := tenantMetricName(summaryMetricName, cfg.UserID, cfg.TenantMetricName)
+
+	for seriesID := 1; seriesID <= cfg.SeriesCount; seriesID++ {
+		value := cfg.Values.seriesValue(t, baseValue, seriesID)
+		wave := strconv.Itoa(seriesID)
+
+		for _, q := range cfg.SummaryQuantiles {
+			labels := addExternalLabels([]*prompb.Label{
+				{Name: "__name__", Value: metricName},
+				{Name: summaryQuantileLabel, Value: strconv.FormatFloat(q, 'f', -1, 64)},
+				{Name: "wave", Value: wave},
+			}, externalLabels)
+
+			// Ensure labels are sorted.
+			if len(externalLabels) > 0 {
+				sort.Slice(labels, func(i, j int) bool {
+					return labels[i].Name < labels[j].Name
+				})
+			}
+
+			out = append(out, &prompb.TimeSeries{
+				Labels: labels,
+				Samples: []prompb.Sample{{
+					Value:     value + q,
+					Timestamp: t.UnixMilli(),
+				}},
+			})
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSummaryQuantiles(t *testing.T) {
+	tests := map[string]struct {
+		input       string
+		expected    []float64
+		expectedErr string
+	}{
+		"single quantile": {
+			input:    "0.5",
+			expected: []float64{0.5},
+		},
+		"should sort quantiles": {
+			input:    "0.99, 0.5,0.9",
+			expected: []float64{0.5, 0.9, 0.99},
+		},
+		"invalid quantile": {
+			input:       "0.5,p99",
+			expectedErr: `invalid summary quantile "p99", expected a number between 0 and 1`,
+		},
+		"out of range quantile": {
+			input:       "1.5",
+			expectedErr: `invalid summary quantile "1.5", expected a number between 0 and 1`,
+		},
+		"duplicated quantile": {
+			input:       "0.5,0.5",
+			expectedErr: "duplicated summary quantile 0.5",
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			actual, err := ParseSummaryQuantiles(testData.input)
+			if testData.expectedErr != "" {
+				assert.EqualError(t, err, testData.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, testData.expected, actual)
+		})
+	}
+}
+
+func TestGenerateSummarySeries(t *testing.T) {
+	ts, err := time.Parse(time.RFC3339, "2023-06-29T00:00:00Z")
+	require.NoError(t, err)
+
+	cfg := WriteClientConfig{SeriesCount: 2, SummaryQuantiles: []float64{0.5, 0.9, 0.99}, Values: ValueConfig{Decorrelation: 0.5}}
+	series := generateSummarySeries(ts, cfg)
+	require.Len(t, series, 6)
+
+	for idx, s := range series {
+		seriesID := idx/3 + 1
+		q := cfg.SummaryQuantiles[idx%3]
+
+		assert.Equal(t, []*prompb.Label{
+			{Name: "__name__", Value: "cortex_load_generator_summary"},
+			{Name: "quantile", Value: []string{"0.5", "0.9", "0.99"}[idx%3]},
+			{Name: "wave", Value: []string{"1", "2"}[seriesID-1]},
+		}, s.Labels)
+		assert.Equal(t, []prompb.Sample{{Value: cfg.Values.value(ts, seriesID) + q, Timestamp: ts.UnixMilli()}}, s.Samples)
+
+		// Quantile values should increase with the quantile.
+		if idx%3 > 0 {
+			assert.Greater(t, s.Samples[0].Value, series[idx-1].Samples[0].Value)
+		}
+	}
+
+	// No summary series should be generated if no quantiles are configured.
+	assert.Empty(t, generateSummarySeries(ts, WriteClientConfig{SeriesCount: 2}))
+}
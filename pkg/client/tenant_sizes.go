@@ -0,0 +1,77 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	// TenantSizeUniform gives all tenants the same number of series.
+	TenantSizeUniform = "uniform"
+
+	// TenantSizeZipf gives the k-th tenant a number of series proportional to 1/k^s,
+	// like the long tail of tenants of real clusters.
+	TenantSizeZipf = "zipf"
+)
+
+// TenantSeriesCounts splits the input budget of series across the input number of tenants,
+// according to the distribution. Each tenant gets at least 1 series, if the budget allows,
+// and the counts always sum up to the budget. The exponent is only used by the zipf distribution.
+func TenantSeriesCounts(distribution string, tenants, budget int, exponent float64) ([]int, error) {
+	weights := make([]float64, tenants)
+	for k := range weights {
+		switch distribution {
+		case TenantSizeUniform:
+			weights[k] = 1
+		case TenantSizeZipf:
+			weights[k] = 1 / math.Pow(float64(k+1), exponent)
+		default:
+			return nil, fmt.Errorf("unknown tenant size distribution %q", distribution)
+		}
+	}
+
+	counts := make([]int, tenants)
+	if tenants == 0 {
+		return counts, nil
+	}
+
+	// Give each tenant 1 series first, so that no tenant is left empty.
+	remaining := budget
+	if budget >= tenants {
+		for k := range counts {
+			counts[k] = 1
+		}
+		remaining -= tenants
+	}
+
+	totalWeight := 0.0
+	for _, w := range weights {
+		totalWeight += w
+	}
+
+	// Split the remaining series proportionally to the weights, then assign the series left
+	// by rounding down to the tenants with the largest fractional parts.
+	fractions := make([]float64, tenants)
+	assigned := 0
+	for k, w := range weights {
+		share := float64(remaining) * w / totalWeight
+		counts[k] += int(share)
+		fractions[k] = share - math.Floor(share)
+		assigned += int(share)
+	}
+
+	order := make([]int, tenants)
+	for k := range order {
+		order[k] = k
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return fractions[order[i]] > fractions[order[j]]
+	})
+
+	for i := 0; i < remaining-assigned; i++ {
+		counts[order[i%tenants]]++
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenantSeriesCounts(t *testing.T) {
+	sum := func(counts []int) int {
+		total := 0
+		for _, c := range counts {
+			total += c
+		}
+		return total
+	}
+
+	t.Run("uniform", func(t *testing.T) {
+		counts, err := TenantSeriesCounts(TenantSizeUniform, 4, 4000, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []int{1000, 1000, 1000, 1000}, counts)
+
+		counts, err = TenantSeriesCounts(TenantSizeUniform, 3, 1000, 0)
+		require.NoError(t, err)
+		assert.Equal(t, 1000, sum(counts))
+		for _, c := range counts {
+			assert.InDelta(t, 333, c, 1)
+		}
+	})
+
+	t.Run("zipf", func(t *testing.T) {
+		const (
+			tenants = 10
+			budget  = 10000
+		)
+
+		counts, err := TenantSeriesCounts(TenantSizeZipf, tenants, budget, 1)
+		require.NoError(t, err)
+		require.Len(t, counts, tenants)
+		assert.Equal(t, budget, sum(counts))
+
+		// The k-th tenant should get a number of series proportional to 1/k.
+		for k := 1; k < tenants; k++ {
+			assert.LessOrEqual(t, counts[k], counts[k-1])
+			assert.InDelta(t, float64(counts[0])/float64(k+1), float64(counts[k]), 2)
+		}
+	})
+
+	t.Run("should give each tenant at least 1 series", func(t *testing.T) {
+		counts, err := TenantSeriesCounts(TenantSizeZipf, 100, 120, 2)
+		require.NoError(t, err)
+		assert.Equal(t, 120, sum(counts))
+		for _, c := range counts {
+			assert.GreaterOrEqual(t, c, 1)
+		}
+	})
+
+	t.Run("should return error on unknown distribution", func(t *testing.T) {
+		_, err := TenantSeriesCounts("pareto", 10, 100, 1)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,164 @@
+package client
+
+import (
+	"math"
+	"time"
+)
+
+// ValueConfig configures how sample values are generated. The write and query clients
+// must be configured with the same ValueConfig, so that query results can be verified.
+type ValueConfig struct {
+	// Replay, if set, replays the recorded samples instead of generating a sine wave.
+	Replay *Replay
+
+	// Compressible, if set, generates values approximating a target compression ratio
+	// instead of a sine wave.
+	Compressible *CompressibleValues
+
+	// LogNormal, if set, generates log-normally distributed values, different for each series,
+	// instead of a sine wave.
+	LogNormal *LogNormalValues
+
+	// Seeds, if set, generates adversarial values driven by a seed for each series, instead of
+	// a sine wave.
+	Seeds *SeedValues
+
+	// Decorrelation is the max offset added to each series value. The offset is a deterministic
+	// function of the series ID, so that series don't share the same exact value while their
+	// aggregated value is still predictable. 0 to disable.
+	Decorrelation float64
+
+	// InverseRatio is the fraction (0-1) of series whose value is negated, e.g. an inverse sine
+	// wave, so that their sum with the other series cancels out toward zero. Inverse series are
+	// evenly spread across series IDs. 0 to disable.
+	InverseRatio float64
+
+	// Min and Max, if Max is greater than Min, linearly map the sine wave from [-1, 1] to
+	// [Min, Max], e.g. [0, 100] for percentages. Ignored if values are not a sine wave.
+	Min float64
+	Max float64
+
+	// Quantization is the number of decimal places generated values are rounded to.
+	// 0 to disable quantization.
+	Quantization int
+
+	// MantissaBits is the number of mantissa bits generated values are truncated to, to test
+	// how precision affects the compression of the samples. 0 to keep the full float64 precision.
+	MantissaBits int
+}
+
+// value returns the value of the series with the input ID at t.
+func (cfg ValueConfig) value(t time.Time, seriesID int) float64 {
+	return cfg.seriesValue(t, cfg.baseValue(t), seriesID)
+}
+
+// sum returns the sum of the values of the series with ID from 1 to seriesCount at t.
+func (cfg ValueConfig) sum(t time.Time, seriesCount int) float64 {
+	base := cfg.baseValue(t)
+
+	// All series have the same absolute value, unless decorrelated or generated per series.
+	if cfg.Decorrelation == 0 && cfg.LogNormal == nil && cfg.Seeds == nil {
+		value := base
+		if cfg.Quantization > 0 {
+			value = quantizeValue(value, cfg.Quantization)
+		}
+		if cfg.MantissaBits > 0 {
+			value = truncateMantissa(value, cfg.MantissaBits)
+		}
+
+		// Rounding is symmetric, so inverse series cancel out the value of as many other series.
+		return value * float64(seriesCount-2*inverseSeriesCount(seriesCount, cfg.InverseRatio))
+	}
+
+	sum := 0.0
+	for seriesID := 1; seriesID <= seriesCount; seriesID++ {
+		sum += cfg.seriesValue(t, base, seriesID)
+	}
+
+	return sum
+}
+
+// baseValue returns the value at t, shared by all series.
+func (cfg ValueConfig) baseValue(t time.Time) float64 {
+	switch {
+	case cfg.LogNormal != nil, cfg.Seeds != nil:
+		// Values are generated for each series.
+		return 0
+	case cfg.Replay != nil:
+		return cfg.Replay.Value(t)
+	case cfg.Compressible != nil:
+		return cfg.Compressible.Value(t)
+	default:
+		return cfg.mapSineWaveValue(generateSineWaveValue(t))
+	}
+}
+
+// mapSineWaveValue linearly maps the input sine wave value from [-1, 1] to [Min, Max], if configured.
+func (cfg ValueConfig) mapSineWaveValue(value float64) float64 {
+	if cfg.Max <= cfg.Min {
+		return value
+	}
+
+	return cfg.Min + (value+1)/2*(cfg.Max-cfg.Min)
+}
+
+// seriesValue returns the value of the series with the input ID at t, given the base value shared by all series.
+func (cfg ValueConfig) seriesValue(t time.Time, base float64, seriesID int) float64 {
+	value := base
+	if cfg.LogNormal != nil {
+		value = cfg.LogNormal.Value(t, seriesID)
+	} else if cfg.Seeds != nil {
+		value = cfg.Seeds.Value(t, seriesID)
+	}
+
+	if isInverseSeries(seriesID, cfg.InverseRatio) {
+		value = -value
+	}
+
+	if cfg.Decorrelation != 0 {
+		value += cfg.Decorrelation * hashToUnitValue(0, uint64(seriesID))
+	}
+
+	if cfg.Quantization > 0 {
+		value = quantizeValue(value, cfg.Quantization)
+	}
+
+	if cfg.MantissaBits > 0 {
+		value = truncateMantissa(value, cfg.MantissaBits)
+	}
+
+	return value
+}
+
+// isInverseSeries returns whether the series with the input ID has a negated value, given the
+// fraction of inverse series. Exactly inverseSeriesCount(n, ratio) series with ID from 1 to n are inverse.
+func isInverseSeries(seriesID int, ratio float64) bool {
+	return inverseSeriesCount(seriesID, ratio) != inverseSeriesCount(seriesID-1, ratio)
+}
+
+// inverseSeriesCount returns the number of inverse series among the series with ID from 1 to seriesCount.
+func inverseSeriesCount(seriesCount int, ratio float64) int {
+	if ratio <= 0 {
+		return 0
+	}
+
+	return int(float64(seriesCount) * math.Min(ratio, 1))
+}
+
+// truncateMantissa truncates the mantissa of value to the input number of most significant bits,
+// zeroing the other ones. Truncation is symmetric, like rounding. Values are not truncated if bits
+// is not in the range 1-51.
+func truncateMantissa(value float64, bits int) float64 {
+	if bits <= 0 || bits >= 52 {
+		return value
+	}
+
+	mask := ^uint64(0) << (52 - bits)
+	return math.Float64frombits(math.Float64bits(value) & mask)
+}
+
+// quantizeValue rounds value to the input number of decimal places.
+func quantizeValue(value float64, decimals int) float64 {
+	scale := math.Pow10(decimals)
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,210 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuantizeValue(t *testing.T) {
+	assert.Equal(t, 0.12, quantizeValue(0.123456, 2))
+	assert.Equal(t, 0.124, quantizeValue(0.12351, 3))
+	assert.Equal(t, -0.7, quantizeValue(-0.6999999, 1))
+}
+
+func TestValueConfig_WithQuantization(t *testing.T) {
+	const (
+		numSeries = 3
+		step      = 10 * time.Second
+	)
+
+	cfg := WriteClientConfig{SeriesCount: numSeries, Values: ValueConfig{Quantization: 2}}
+	start, err := time.Parse(time.RFC3339, "2023-06-29T00:00:00Z")
+	require.NoError(t, err)
+
+	var samples []model.SamplePair
+	for ts := start; ts.Before(start.Add(10 * time.Minute)); ts = ts.Add(step) {
+		series := generateSineWaveSeries(ts, cfg)
+
+		sum := 0.0
+		for _, s := range series {
+			assert.Equal(t, quantizeValue(generateSineWaveValue(ts), 2), s.Samples[0].Value)
+			sum += s.Samples[0].Value
+		}
+
+		samples = append(samples, newSamplePair(ts, sum))
+	}
+
+	// The verifier applies the same quantization, so the comparison passes.
+	assert.NoError(t, verifySineWaveSamples(samples, numSeries, SeriesSchedule{}, step, cfg.Values, nil))
+}
+
+func TestTruncateMantissa(t *testing.T) {
+	assert.Equal(t, 1.5, truncateMantissa(1.75, 1))
+	assert.Equal(t, -1.5, truncateMantissa(-1.75, 1))
+	assert.Equal(t, 1.75, truncateMantissa(1.75, 2))
+	assert.Equal(t, math.Pi, truncateMantissa(math.Pi, 52))
+	assert.Equal(t, 0.0, truncateMantissa(0, 10))
+
+	// Out of range bits should not truncate the value.
+	assert.Equal(t, math.Pi, truncateMantissa(math.Pi, 0))
+	assert.Equal(t, math.Pi, truncateMantissa(math.Pi, -5))
+	assert.Equal(t, -math.Pi, truncateMantissa(-math.Pi, 100))
+}
+
+func TestValueConfig_WithMantissaBits(t *testing.T) {
+	const (
+		numSeries    = 3
+		step         = 10 * time.Second
+		mantissaBits = 8
+	)
+
+	cfg := WriteClientConfig{SeriesCount: numSeries, Values: ValueConfig{MantissaBits: mantissaBits}}
+	start, err := time.Parse(time.RFC3339, "2023-06-29T00:00:00Z")
+	require.NoError(t, err)
+
+	var samples []model.SamplePair
+	for ts := start; ts.Before(start.Add(10 * time.Minute)); ts = ts.Add(step) {
+		sum := 0.0
+		for _, s := range generateSineWaveSeries(ts, cfg) {
+			value := s.Samples[0].Value
+			assert.Equal(t, truncateMantissa(generateSineWaveValue(ts), mantissaBits), value)
+
+			// Only the most significant bits of the mantissa should be set.
+			assert.Zero(t, math.Float64bits(value)&(1<<(52-mantissaBits)-1))
+
+			sum += value
+		}
+
+		samples = append(samples, newSamplePair(ts, sum))
+	}
+
+	// The verifier applies the same truncation, so the comparison passes, while it fails with
+	// full precision values.
+	assert.NoError(t, verifySineWaveSamples(samples, numSeries, SeriesSchedule{}, step, cfg.Values, nil))
+	assert.Error(t, verifySineWaveSamples(samples, numSeries, SeriesSchedule{}, step, ValueConfig{}, nil))
+}
+
+func TestValueConfig_WithMinMax(t *testing.T) {
+	const (
+		numSeries = 3
+		step      = 10 * time.Second
+	)
+
+	cfg := WriteClientConfig{SeriesCount: numSeries, Values: ValueConfig{Min: 0, Max: 100}}
+	start, err := time.Parse(time.RFC3339, "2023-06-29T00:00:00Z")
+	require.NoError(t, err)
+
+	var (
+		samples  []model.SamplePair
+		min, max = 100.0, 0.0
+	)
+	for ts := start; ts.Before(start.Add(sineWavePeriod)); ts = ts.Add(step) {
+		sum := 0.0
+		for _, s := range generateSineWaveSeries(ts, cfg) {
+			value := s.Samples[0].Value
+			assert.InDelta(t, 50+50*generateSineWaveValue(ts), value, 1e-9)
+
+			min = math.Min(min, value)
+			max = math.Max(max, value)
+			sum += value
+		}
+
+		samples = append(samples, newSamplePair(ts, sum))
+	}
+
+	// Values should span the whole range, without exceeding it.
+	assert.InDelta(t, 0, min, 0.01)
+	assert.InDelta(t, 100, max, 0.01)
+	assert.GreaterOrEqual(t, min, 0.0)
+	assert.LessOrEqual(t, max, 100.0)
+
+	// The verifier applies the same mapping, so the comparison passes.
+	assert.NoError(t, verifySineWaveSamples(samples, numSeries, SeriesSchedule{}, step, cfg.Values, nil))
+	assert.Error(t, verifySineWaveSamples(samples, numSeries, SeriesSchedule{}, step, ValueConfig{}, nil))
+}
+
+func TestValueConfig_WithDecorrelation(t *testing.T) {
+	const (
+		numSeries = 10
+		step      = 10 * time.Second
+	)
+
+	cfg := WriteClientConfig{SeriesCount: numSeries, Values: ValueConfig{Decorrelation: 0.5}}
+	start, err := time.Parse(time.RFC3339, "2023-06-29T00:00:00Z")
+	require.NoError(t, err)
+
+	var samples []model.SamplePair
+	for ts := start; ts.Before(start.Add(10 * time.Minute)); ts = ts.Add(step) {
+		series := generateSineWaveSeries(ts, cfg)
+
+		sum := 0.0
+		distinct := map[float64]struct{}{}
+		for _, s := range series {
+			// Each series value is offset by at most the configured decorrelation.
+			assert.InDelta(t, generateSineWaveValue(ts), s.Samples[0].Value, 0.5)
+			distinct[s.Samples[0].Value] = struct{}{}
+			sum += s.Samples[0].Value
+		}
+
+		assert.Len(t, distinct, numSeries)
+		samples = append(samples, newSamplePair(ts, sum))
+	}
+
+	// The verifier sums the per-series expected values, so the comparison passes.
+	assert.NoError(t, verifySineWaveSamples(samples, numSeries, SeriesSchedule{}, step, cfg.Values, nil))
+	assert.Error(t, verifySineWaveSamples(samples, numSeries, SeriesSchedule{}, step, ValueConfig{}, nil))
+}
+
+func TestValueConfig_WithInverseSeries(t *testing.T) {
+	const (
+		numSeries = 10
+		step      = 10 * time.Second
+	)
+
+	start, err := time.Parse(time.RFC3339, "2023-06-29T00:00:00Z")
+	require.NoError(t, err)
+
+	for _, ratio := range []float64{0.3, 0.5, 1} {
+		t.Run(fmt.Sprintf("ratio: %v", ratio), func(t *testing.T) {
+			cfg := WriteClientConfig{SeriesCount: numSeries, Values: ValueConfig{InverseRatio: ratio, Quantization: 3}}
+
+			var samples []model.SamplePair
+			for ts := start; ts.Before(start.Add(10 * time.Minute)); ts = ts.Add(step) {
+				expected := quantizeValue(generateSineWaveValue(ts), 3)
+
+				sum, inverse := 0.0, 0
+				for _, s := range generateSineWaveSeries(ts, cfg) {
+					if expected != 0 && s.Samples[0].Value == -expected {
+						inverse++
+					}
+					sum += s.Samples[0].Value
+				}
+
+				// Half of the series cancel out the other half.
+				if ratio == 0.5 {
+					assert.InDelta(t, 0, sum, 1e-9)
+				}
+				if expected != 0 {
+					assert.Equal(t, int(numSeries*ratio), inverse)
+				}
+
+				samples = append(samples, newSamplePair(ts, sum))
+			}
+
+			// The verifier accounts for the sign of each series, so the comparison passes.
+			assert.NoError(t, verifySineWaveSamples(samples, numSeries, SeriesSchedule{}, step, cfg.Values, nil))
+			assert.Error(t, verifySineWaveSamples(samples, numSeries, SeriesSchedule{}, step, ValueConfig{Quantization: 3}, nil))
+
+			// The per-series sum should match the optimized one.
+			decorrelated := cfg.Values
+			decorrelated.Decorrelation = 1e-12
+			assert.InDelta(t, decorrelated.sum(start, numSeries), cfg.Values.sum(start, numSeries), 1e-9)
+		})
+	}
+}
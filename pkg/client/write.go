@@ -3,132 +3,887 @@ package client
 import (
 	"bufio"
 	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"math"
+	"math/rand"
+	"mime"
 	"net/http"
 	"net/url"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
-	"github.com/gogo/protobuf/proto"
 	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/prometheus/pkg/gate"
 	"github.com/prometheus/prometheus/prompb"
 )
 
+var (
+	errInjectedWriteFailure = errors.New("injected write failure")
+)
+
+const (
+	// BatchAssignmentContiguous assigns contiguous series to the same write batch.
+	BatchAssignmentContiguous = "contiguous"
+
+	// BatchAssignmentRoundRobin assigns series to write batches in a round-robin fashion,
+	// so that adjacent series end up in different write requests.
+	BatchAssignmentRoundRobin = "round-robin"
+
+	// ChurnModeGradual spreads the series churning over the churn period, while
+	// ChurnModeCliff churns all series at the same time at the end of each period.
+	ChurnModeGradual = "gradual"
+	ChurnModeCliff   = "cliff"
+
+	// ChurnTargetLabel churns series by changing the value of a dedicated churn label,
+	// ChurnTargetName by changing the metric name suffix and ChurnTargetID by changing
+	// the wave label value.
+	ChurnTargetLabel = "label"
+	ChurnTargetName  = "name"
+	ChurnTargetID    = "id"
+)
+
 const (
 	maxErrMsgLen = 256
+
+	tenantPlaceholder = "{tenant}"
+
+	sineWaveMetricName = "cortex_load_generator_sine_wave"
+	infoMetricName     = "cortex_load_generator_info"
+	checksumLabelName  = "checksum"
+	instanceLabelName  = "instance"
+	createdLabelName   = "created"
+	fullChurnLabelName = "churn_interval"
+
+	// createdLabelEpoch is the creation time of the series with ID 0, in seconds since the Unix epoch.
+	createdLabelEpoch = 1577836800 // 2020-01-01T00:00:00Z
+
+	haClusterLabelName = "cluster"
+	haReplicaLabelName = "__replica__"
+	haClusterName      = "cortex-load-generator"
+
+	// sineWavePeriod is the period of the generated sine wave.
+	sineWavePeriod = 10 * time.Minute
+
+	// clockSkewSeed is the seed used to generate the clock skew of each series.
+	clockSkewSeed = 0x636c6f636b
+
+	// partialWriteContentType is the content type of 2xx responses whose body reports the
+	// number of accepted and rejected samples, when the write has only been partially accepted.
+	partialWriteContentType = "application/json"
+
+	samplesAccepted = "accepted"
+	samplesRejected = "rejected"
+
+	writeSuccess  = "success"
+	writeRejected = "rejected"
+	writeFailed   = "fail"
 )
 
 type WriteClientConfig struct {
-	// Cortex URL.
+	// Cortex URL. The {tenant} placeholder in the URL path is replaced with the UserID.
 	URL url.URL
 
+	// HTTP method used to send write requests. Defaults to POST.
+	WriteMethod string
+
+	// AcceptEncoding, if set, is the Accept-Encoding header of write requests. Gzip-encoded
+	// responses are decoded, even if not asked for, since some gateways gzip error messages.
+	AcceptEncoding string
+
+	// RemoteWriteVersion is the value of the X-Prometheus-Remote-Write-Version header sent with
+	// write requests, for gateways branching on it. Defaults to 0.1.0.
+	RemoteWriteVersion string
+
+	// Transport used to send write requests. It's safe to share the same transport across
+	// clients of different tenants, because the tenant ID is injected in each request.
+	// If nil, a dedicated transport honoring the environment proxy settings is created.
+	Transport http.RoundTripper
+
 	// The tenant ID to use to push metrics to Cortex.
 	UserID string
 
-	// Number of series to generate per write request.
+	// TenantPool, if set, sends each write request to a tenant drawn at random from the pool,
+	// instead of UserID, like a single producer writing many tenants of a tenant-sharded ingest.
+	// The series of each tenant are incomplete, so they can't be verified by querying.
+	TenantPool []string
+
+	// TenantHeaderName is the name of the HTTP header carrying the tenant ID.
+	// If empty, defaults to X-Scope-OrgID.
+	TenantHeaderName string
+
+	// Number of series to generate per write request, for each metric name.
 	SeriesCount int
 
+	// Schedule configures how the number of series changes over time, up to SeriesCount.
+	Schedule SeriesSchedule
+
+	// churnSeriesCount is the configured SeriesCount, which churning series are spread over, when
+	// SeriesCount is overridden with the number of series scheduled at a given time. This way the
+	// bursts and ramp-up steps of the schedule don't shift the churn time of the existing series.
+	churnSeriesCount int
+
+	// Number of distinct metric names to generate. Each metric name gets SeriesCount series.
+	MetricNamesCount int
+
+	// TenantMetricName suffixes the sine wave, info and summary metric names with the tenant ID, so
+	// that the series of different tenants have distinct names even if a backend strips the tenant label.
+	TenantMetricName bool
+
+	// NameRotationPeriod, if greater than 0, adds a version suffix to the metric names
+	// changing every period, to retire all series at once and start new ones, e.g. to test
+	// how compaction handles metric names turnover. The query client must be configured with
+	// the same period.
+	NameRotationPeriod time.Duration
+
 	// SeriesChurnPeriod is the time period during which all series gradually churn.
-	// 0 to disable churning.
+	// Churning only changes the series labels, never their values, so that aggregated
+	// values can still be verified. 0 to disable churning.
 	SeriesChurnPeriod time.Duration
 
+	// ValueChurnLabel is the name of a label whose value rotates every ValueChurnPeriod for
+	// all series, while the other labels don't change. If the label is generated anyway (e.g.
+	// an extra label), its value is replaced. Since labels identify series, the remote endpoint
+	// still sees a different series after each rotation. Empty or 0 to disable.
+	ValueChurnLabel  string
+	ValueChurnPeriod time.Duration
+
+	// ChurnMode is how series churn over the churn period. Defaults to ChurnModeGradual.
+	ChurnMode string
+
+	// ChurnEpoch is the anchor of the churn period boundaries, and churn IDs count the periods
+	// elapsed since then. Zero to anchor them to the Unix epoch.
+	ChurnEpoch time.Time
+
+	// ChurnTarget is which part of the series identity changes when series churn. Defaults to
+	// ChurnTargetLabel. With ChurnTargetName, the series don't match the default query anymore.
+	ChurnTarget string
+
+	// ChurnBackfillSamples is the number of samples of the previous write intervals sent along
+	// with the first sample of each newly churned series, to test the head block handling of
+	// series starting in the past. Backfilled samples overlap the ones of the churned out
+	// series, so the sum of series can't be verified at their timestamps. 0 to disable.
+	ChurnBackfillSamples int
+
+	// FullChurn adds a label to each series whose value is unique for each write interval, so
+	// that no series persists across intervals, for maximum index churn. Since no series is
+	// stable, query results can't be verified.
+	FullChurn bool
+
 	// Number of extra labels to generate per write request.
 	ExtraLabels int
 
+	// DistinctLabelNames is the size of the pool of label names series are spread across, to
+	// stress the number of distinct label names in the index. Each series gets a deterministic
+	// random subset of the pool. 0 to disable.
+	DistinctLabelNames int
+
+	// Dimensions, if set, add a label for each dimension to the series, so that series are the
+	// cross-product of all dimensions. SeriesCount should match the dimensions cardinality.
+	Dimensions []Dimension
+
+	// FakeInstances is the number of synthetic instances series are spread across, through an
+	// instance label whose value cycles over series IDs, like series scraped from many pods.
+	// 0 to disable.
+	FakeInstances int
+
+	// ExternalLabels are static labels added to all generated series, like Prometheus external
+	// labels. Generated labels with the same name take precedence.
+	ExternalLabels map[string]string
+
+	// CreatedLabel adds a label to each series with its creation timestamp, which is deterministic
+	// per series ID, so that it's stable over time but unique across series. Like a start_time
+	// label, it increases the label values cardinality without churning series.
+	CreatedLabel bool
+
+	// SeriesShards is the number of shards series are hashed into, to evaluate how evenly
+	// series are sharded. Each series gets a shard label with the xxhash of its other labels
+	// modulo SeriesShards, and the number of series in each shard is tracked. 0 to disable.
+	SeriesShards int
+
+	// SeriesPool builds the label sets of the series once, and only generates their samples
+	// each write interval, to reduce the CPU usage. It only applies when series labels don't
+	// change over time, e.g. without churning series.
+	SeriesPool bool
+
+	// Number of info series (constant value 1) to generate, and number of labels
+	// to generate for each info series.
+	InfoSeriesCount  int
+	InfoSeriesLabels int
+
+	// SummaryQuantiles, if set, generates the quantile series of a classic summary for each
+	// sine wave series, with a quantile label for each of the quantiles. They must be sorted.
+	SummaryQuantiles []float64
+
 	WriteInterval    time.Duration
 	WriteTimeout     time.Duration
 	WriteConcurrency int
 	WriteBatchSize   int
+
+	// MaxWriteBytes is the max size (in bytes) of each compressed write request, including
+	// the metadata (if any). Batches exceeding it are split further. 0 to disable.
+	MaxWriteBytes int
+
+	// WriteDeadlineRatio is the fraction of the write interval within which all batches must be
+	// written. Batches not written by then are cancelled, so that intervals don't pile up under
+	// backpressure. 0 to disable.
+	WriteDeadlineRatio float64
+
+	// BatchAssignment is the strategy used to assign series to write batches.
+	BatchAssignment string
+
+	// Values configures how sample values are generated.
+	Values ValueConfig
+
+	// SendUnsortedLabels deliberately sends series labels in reverse sorted order, to verify
+	// the remote endpoint rejects them.
+	SendUnsortedLabels bool
+
+	// SortSeriesInRequest sorts the series of each write request by their label sets. The
+	// remote endpoint doesn't require it, but it may improve the request compression.
+	SortSeriesInRequest bool
+
+	// HAReplicas is the number of HA replicas writing each series, to test the remote endpoint
+	// deduplication. If greater than 1, each series is written once per replica, with the same
+	// value and timestamp, a shared cluster label and a different __replica__ label. Each replica
+	// is written in dedicated requests, like Prometheus HA pairs do. Queries expect the remote
+	// endpoint to deduplicate replicas, so the number of expected series doesn't change.
+	HAReplicas int
+
+	// Recorder, if set, records the values successfully pushed for the series targeted by the
+	// default query, so that they can be verified by the query client sharing the same Recorder.
+	Recorder *Recorder
+
+	// ChecksumLabel adds a label to each series with the checksum of its value, so that
+	// the verifier can detect values silently mutated by the remote endpoint. Since the
+	// label value changes with the sample value, each sample is written to a new series.
+	ChecksumLabel bool
+
+	// InjectWriteFailureRate is the probability (0-1) a write request fails without
+	// hitting the network. Failures are drawn from a random generator initialised
+	// with InjectWriteFailureSeed, so that they're reproducible. 0 to disable.
+	InjectWriteFailureRate float64
+	InjectWriteFailureSeed int64
+
+	// HonorRetryAfter pauses writes until the time specified by the Retry-After header
+	// of rate limited (HTTP 429) responses.
+	HonorRetryAfter bool
+
+	// SkipInitialWrite delays the first write by one write interval, instead of
+	// writing immediately once started.
+	SkipInitialWrite bool
+
+	// StaleMarkersOnStop sends a stale marker (the Prometheus stale NaN) for each series of the
+	// last write when the client is stopped, so that the backend doesn't consider them active
+	// until they time out.
+	StaleMarkersOnStop bool
+
+	// Metadata, if set, is sent along with the series in each write request, for each
+	// generated metric name.
+	Metadata *MetricMetadata
+
+	// ClockSkewStdDev is the standard deviation of the skew subtracted from the sample timestamps
+	// of each series, to simulate agents with slightly skewed clocks. The skew is deterministic
+	// per series ID and bounded to less than a write interval. Samples are only skewed back in
+	// time, so that the sample returned at each query step is still the one generated for it.
+	// 0 to disable.
+	ClockSkewStdDev time.Duration
+
+	// ConcurrencySweep, if enabled, overrides WriteConcurrency with a concurrency increasing over
+	// time, and tracks the throughput achieved at each concurrency level.
+	ConcurrencySweep ConcurrencySweep
+
+	// AdaptiveBatchSize, if enabled, auto-tunes the batch size starting from WriteBatchSize,
+	// growing it while write requests are fast and shrinking it while they're slow.
+	AdaptiveBatchSize AdaptiveBatchSize
+
+	// InflightSamples, if set, caps the number of samples in flight across all write requests,
+	// on top of the write concurrency. It can be shared across the write clients of different
+	// tenants, to cap the total.
+	InflightSamples *InflightSamplesLimiter
+
+	// CircuitBreaker, if enabled, pauses writes after consecutive write failures, to let an
+	// overwhelmed remote endpoint recover, then resumes them with a reduced concurrency.
+	CircuitBreaker CircuitBreaker
 }
 
 type WriteClient struct {
-	client    *http.Client
-	cfg       WriteClientConfig
-	writeGate *gate.Gate
-	logger    log.Logger
+	client   *http.Client
+	cfg      WriteClientConfig
+	writeURL string
+	logger   log.Logger
+
+	// The gate honoring the write concurrency, replaced when the concurrency changes.
+	writeGateMx          sync.Mutex
+	writeGate            *gate.Gate
+	writeGateConcurrency int
+
+	// The encoded metadata sent in each write request, if any.
+	metadata []byte
+
+	// The pre-built series, if enabled and the config is static.
+	pool *seriesPool
+
+	// Random generator used to inject write failures.
+	failureRandMx sync.Mutex
+	failureRand   *rand.Rand
+
+	// Random generator used to pick the tenant of each write request from the tenant pool.
+	tenantRandMx sync.Mutex
+	tenantRand   *rand.Rand
+
+	// The controller of the batch size, if adaptive batch sizing is enabled.
+	batchSizer *adaptiveBatchSizer
+
+	// The circuit breaker pausing writes after consecutive failures, if enabled.
+	breaker *circuitBreaker
+
+	// Writes are paused until this time, if the remote endpoint asked us to retry later.
+	pausedUntilMx sync.Mutex
+	pausedUntil   time.Time
+
+	// The timestamp of the last write, used to send stale markers for its series on stop.
+	lastWriteMx sync.Mutex
+	lastWrite   time.Time
+
+	// Closed to stop the write loop.
+	stop    chan struct{}
+	running sync.WaitGroup
+
+	// Receives the flush requests, served by the write loop so that writes never overlap. The
+	// number of series pushed is sent back on the request channel.
+	flush chan chan int
+
+	// Metrics.
+	writeRequestsTotal       *prometheus.CounterVec
+	writeErrorsTotal         *prometheus.CounterVec
+	writeSamplesTotal        *prometheus.CounterVec
+	writeRateLimitedTotal    prometheus.Counter
+	writeBatchesLastInterval prometheus.Gauge
+	seriesConfigured         prometheus.Gauge
+	seriesPushedTotal        prometheus.Counter
+
+	writeIntervalOverrunsTotal prometheus.Counter
+	futureSamplesDroppedTotal  prometheus.Counter
+	writeConcurrency           prometheus.Gauge
+	sweepSamplesPerSecond      *prometheus.GaugeVec
+	seriesPerShard             prometheus.Histogram
+	circuitState               prometheus.Gauge
+	writeBatchSize             prometheus.Gauge
 }
 
-func NewWriteClient(cfg WriteClientConfig, logger log.Logger) *WriteClient {
-	var rt http.RoundTripper = &http.Transport{}
-	rt = &clientRoundTripper{userID: cfg.UserID, rt: rt}
+func NewWriteClient(cfg WriteClientConfig, logger log.Logger, reg prometheus.Registerer) *WriteClient {
+	rt := cfg.Transport
+	if rt == nil {
+		rt = &http.Transport{Proxy: http.ProxyFromEnvironment}
+	}
+	rt = &clientRoundTripper{userID: cfg.UserID, headerName: cfg.TenantHeaderName, rt: rt}
 
 	c := &WriteClient{
-		client:    &http.Client{Transport: rt},
-		cfg:       cfg,
-		writeGate: gate.New(cfg.WriteConcurrency),
-		logger:    logger,
+		client:      &http.Client{Transport: rt},
+		cfg:         cfg,
+		writeURL:    writeURLForTenant(cfg.URL, cfg.UserID),
+		logger:      logger,
+		failureRand: rand.New(rand.NewSource(cfg.InjectWriteFailureSeed)),
+		tenantRand:  rand.New(rand.NewSource(time.Now().UnixNano())),
+		stop:        make(chan struct{}),
+		flush:       make(chan chan int),
+
+		writeRequestsTotal: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name:        "cortex_load_generator_write_requests_total",
+			Help:        "Total number of attempted write requests.",
+			ConstLabels: map[string]string{"user": cfg.UserID},
+		}, []string{"result"}),
+		writeErrorsTotal: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name:        "cortex_load_generator_write_errors_total",
+			Help:        "Total number of failed write requests, by error category.",
+			ConstLabels: map[string]string{"user": cfg.UserID},
+		}, []string{"category"}),
+		writeSamplesTotal: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name:        "cortex_load_generator_write_samples_total",
+			Help:        "Total number of samples sent by successful write requests, by whether the remote endpoint accepted or rejected them.",
+			ConstLabels: map[string]string{"user": cfg.UserID},
+		}, []string{"result"}),
+		writeRateLimitedTotal: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name:        "cortex_load_generator_write_rate_limited_total",
+			Help:        "Total number of write requests rate limited by the remote endpoint (HTTP 429).",
+			ConstLabels: map[string]string{"user": cfg.UserID},
+		}),
+		writeBatchesLastInterval: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name:        "cortex_load_generator_write_batches_last_interval",
+			Help:        "Number of write batches (each one sent by a dedicated goroutine) created in the last write interval.",
+			ConstLabels: map[string]string{"user": cfg.UserID},
+		}),
+		seriesConfigured: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name:        "cortex_load_generator_series_configured",
+			Help:        "Number of series generated to be pushed in the last write interval.",
+			ConstLabels: map[string]string{"user": cfg.UserID},
+		}),
+		seriesPushedTotal: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name:        "cortex_load_generator_series_pushed_total",
+			Help:        "Total number of series successfully pushed to the remote endpoint.",
+			ConstLabels: map[string]string{"user": cfg.UserID},
+		}),
+		writeIntervalOverrunsTotal: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name:        "cortex_load_generator_write_interval_overruns_total",
+			Help:        "Total number of write intervals whose batches didn't complete before the write deadline, and were cancelled.",
+			ConstLabels: map[string]string{"user": cfg.UserID},
+		}),
+		writeConcurrency: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name:        "cortex_load_generator_write_concurrency",
+			Help:        "Max number of concurrent write requests in the last write interval.",
+			ConstLabels: map[string]string{"user": cfg.UserID},
+		}),
+		sweepSamplesPerSecond: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Name:        "cortex_load_generator_concurrency_sweep_samples_per_second",
+			Help:        "Samples per second successfully pushed in the last write interval at each concurrency level of the concurrency sweep, measured over the time taken to send all batches.",
+			ConstLabels: map[string]string{"user": cfg.UserID},
+		}, []string{"concurrency"}),
+		seriesPerShard: promauto.With(reg).NewHistogram(prometheus.HistogramOpts{
+			Name:        "cortex_load_generator_series_per_shard",
+			Help:        "Number of series generated in each write interval falling into each shard, observed once per shard. The wider the distribution, the more skewed the sharding.",
+			Buckets:     prometheus.ExponentialBuckets(1, 1.25, 60),
+			ConstLabels: map[string]string{"user": cfg.UserID},
+		}),
+		writeBatchSize: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name:        "cortex_load_generator_write_batch_size",
+			Help:        "Max number of series sent by each write request in the last write interval.",
+			ConstLabels: map[string]string{"user": cfg.UserID},
+		}),
+		circuitState: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name:        "cortex_load_generator_circuit_state",
+			Help:        "State of the write circuit breaker: 0 closed, 1 open (writes paused), 2 half-open (writes resumed with a reduced concurrency).",
+			ConstLabels: map[string]string{"user": cfg.UserID},
+		}),
+		futureSamplesDroppedTotal: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name:        "cortex_load_generator_future_samples_dropped_total",
+			Help:        "Total number of generated samples dropped because their timestamp was in the future, which backends reject.",
+			ConstLabels: map[string]string{"user": cfg.UserID},
+		}),
+	}
+
+	if cfg.Metadata != nil {
+		c.metadata = encodeMetadata(sineWaveMetricNames(sineWaveBaseMetricName(cfg.UserID, cfg.TenantMetricName), cfg.MetricNamesCount), *cfg.Metadata)
+	}
+
+	if cfg.SeriesPool {
+		c.pool = newSeriesPool(cfg)
+	}
+
+	if cfg.AdaptiveBatchSize.enabled() {
+		c.batchSizer = newAdaptiveBatchSizer(cfg.AdaptiveBatchSize, cfg.WriteBatchSize)
+	}
+
+	if cfg.CircuitBreaker.enabled() {
+		c.breaker = newCircuitBreaker(cfg.CircuitBreaker)
+	}
+
+	// Init metrics.
+	for _, result := range []string{writeSuccess, writeRejected, writeFailed} {
+		c.writeRequestsTotal.WithLabelValues(result).Add(0)
+	}
+	for _, result := range []string{samplesAccepted, samplesRejected} {
+		c.writeSamplesTotal.WithLabelValues(result).Add(0)
+	}
+	for _, category := range writeErrorCategories {
+		c.writeErrorsTotal.WithLabelValues(category).Add(0)
 	}
 
 	return c
 }
 
 func (c *WriteClient) Start() {
+	c.running.Add(1)
 	go c.run()
 }
 
+// Stop stops writing series, waiting until the in-progress write (if any) completes, and
+// then sends the stale markers for the series of the last write, if configured.
+func (c *WriteClient) Stop() {
+	close(c.stop)
+	c.running.Wait()
+
+	if c.cfg.StaleMarkersOnStop {
+		c.writeStaleMarkers()
+	}
+}
+
 func (c *WriteClient) run() {
-	c.writeSeries()
+	defer c.running.Done()
+
+	if !c.cfg.SkipInitialWrite {
+		c.writeSeries()
+	}
 
 	ticker := time.NewTicker(c.cfg.WriteInterval)
+	defer ticker.Stop()
 
-	for range ticker.C {
-		c.writeSeries()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.writeSeries()
+		case done := <-c.flush:
+			done <- c.writeSeries()
+		}
+	}
+}
+
+// Flush writes the series of the current write interval without waiting for the next write,
+// and returns the number of series successfully pushed. The write is run by the write loop, so
+// it never overlaps with a periodic write. Flush returns 0 if the client is stopped.
+func (c *WriteClient) Flush() int {
+	done := make(chan int, 1)
+
+	select {
+	case c.flush <- done:
+		return <-done
+	case <-c.stop:
+		return 0
 	}
 }
 
-func (c *WriteClient) writeSeries() {
+// writeSeries writes the series of the current write interval and returns the number
+// of series successfully pushed.
+func (c *WriteClient) writeSeries() int {
+	if until := c.getPausedUntil(); time.Now().Before(until) {
+		level.Warn(c.logger).Log("msg", "skipped writing series because rate limited by the remote endpoint", "retry_after", until)
+		return 0
+	}
+
+	// Honor the circuit breaker.
+	circuitState := circuitClosed
+	if c.breaker != nil {
+		circuitState = c.breaker.state(time.Now())
+		c.circuitState.Set(float64(circuitState))
+
+		if circuitState == circuitOpen {
+			level.Warn(c.logger).Log("msg", "skipped writing series because the circuit breaker is open after consecutive write failures")
+			return 0
+		}
+	}
+
 	ts := alignTimestampToInterval(time.Now(), c.cfg.WriteInterval)
-	series := generateSineWaveSeries(ts, c.cfg.SeriesCount, c.cfg.ExtraLabels, c.cfg.SeriesChurnPeriod)
 
-	// Honor the batch size.
-	wg := sync.WaitGroup{}
+	// Mark the series dropped since the last write (e.g. at the end of a burst) as stale, so that
+	// queries don't keep returning their last sample until the lookback period elapses.
+	c.writeDroppedSeriesStaleMarkers(ts)
+	c.setLastWrite(ts)
+
+	// The metadata refers to the metric names, which change when rotated.
+	if c.cfg.NameRotationPeriod > 0 && c.cfg.Metadata != nil {
+		c.metadata = encodeMetadata(rotatedMetricNames(sineWaveMetricNames(sineWaveBaseMetricName(c.cfg.UserID, c.cfg.TenantMetricName), c.cfg.MetricNamesCount), ts, c.cfg.NameRotationPeriod), *c.cfg.Metadata)
+	}
+
+	// Honor the concurrency sweep, reduced while the circuit is half-open.
+	concurrency := c.cfg.ConcurrencySweep.concurrency(ts, c.cfg.WriteConcurrency)
+	if c.breaker != nil {
+		concurrency = c.breaker.concurrency(circuitState, concurrency)
+	}
+	writeGate := c.getWriteGate(concurrency)
+	c.writeConcurrency.Set(float64(concurrency))
+
+	// Honor the series count schedule.
+	cfg := c.scheduledConfig(ts)
+
+	var series []*prompb.TimeSeries
+	if c.pool != nil && c.pool.seriesCount == cfg.SeriesCount {
+		series = c.pool.generate(ts, cfg)
+	} else {
+		series = generateSineWaveSeries(ts, cfg)
+	}
+	if cfg.SeriesShards > 0 {
+		c.trackSeriesPerShard(series)
+	}
+
+	// Honor the batch size, writing each HA replica in dedicated requests.
+	batchSize := c.cfg.WriteBatchSize
+	if c.batchSizer != nil {
+		batchSize = c.batchSizer.batchSize()
+	}
+	c.writeBatchSize.Set(float64(batchSize))
 
-	for o := 0; o < len(series); o += c.cfg.WriteBatchSize {
-		wg.Add(1)
+	replicas := splitHAReplicas(series, len(haReplicaNames(cfg.HAReplicas)))
+	replicas[len(replicas)-1] = append(replicas[len(replicas)-1], generateInfoSeries(ts, c.cfg)...)
+	replicas[len(replicas)-1] = append(replicas[len(replicas)-1], generateSummarySeries(ts, cfg)...)
 
-		go func(o int) {
+	// Never send samples in the future, e.g. because of a misconfigured clock skew.
+	now := time.Now()
+	for i := range replicas {
+		replicas[i] = c.dropFutureSamples(replicas[i], now)
+	}
+
+	var (
+		batches     [][]*prompb.TimeSeries
+		seriesCount int
+	)
+	for _, series := range replicas {
+		batches = append(batches, splitBatchesBySize(partitionSeries(series, batchSize, c.cfg.BatchAssignment), c.cfg.MaxWriteBytes, c.metadata)...)
+		seriesCount += len(series)
+	}
+
+	// Honor the write deadline.
+	ctx := context.Background()
+	if c.cfg.WriteDeadlineRatio > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(c.cfg.WriteDeadlineRatio*float64(c.cfg.WriteInterval)))
+		defer cancel()
+	}
+
+	var (
+		abandoned, pushed, pushedSamples int64
+
+		// The sum of the values pushed for the series targeted by the default query.
+		recordedMx  sync.Mutex
+		recordedSum float64
+	)
+
+	startTime := time.Now()
+	wg := sync.WaitGroup{}
+	wg.Add(len(batches))
+
+	for _, batch := range batches {
+		go func(batch []*prompb.TimeSeries) {
 			defer wg.Done()
 
 			// Honor the max concurrency
-			ctx := context.Background()
-			_ = c.writeGate.Start(ctx)
-			defer c.writeGate.Done()
-
-			end := o + c.cfg.WriteBatchSize
-			if end > len(series) {
-				end = len(series)
+			if err := writeGate.Start(ctx); err != nil {
+				atomic.AddInt64(&abandoned, 1)
+				c.writeRequestsTotal.WithLabelValues(writeFailed).Inc()
+				return
 			}
+			defer writeGate.Done()
 
 			req := &prompb.WriteRequest{
-				Timeseries: series[o:end],
+				Timeseries: batch,
 			}
 
+			sendStart := time.Now()
 			err := c.send(ctx, req)
+			c.writeRequestsTotal.WithLabelValues(writeResult(err)).Inc()
+			if c.batchSizer != nil {
+				c.batchSizer.observe(time.Since(sendStart))
+			}
+			if c.breaker != nil {
+				c.breaker.recordResult(err, time.Now())
+			}
+			if err != nil && ctx.Err() != nil {
+				atomic.AddInt64(&abandoned, 1)
+			}
 			if err != nil {
 				level.Error(c.logger).Log("msg", "failed to write series", "err", err)
+				return
+			}
+
+			c.seriesPushedTotal.Add(float64(len(batch)))
+			atomic.AddInt64(&pushed, int64(len(batch)))
+			atomic.AddInt64(&pushedSamples, int64(countSamples(req)))
+
+			if c.cfg.Recorder != nil {
+				recordedMx.Lock()
+				recordedSum += sumRecordedSeries(batch, ts, cfg)
+				recordedMx.Unlock()
 			}
-		}(o)
+		}(batch)
 	}
 
+	c.writeBatchesLastInterval.Set(float64(len(batches)))
+	c.seriesConfigured.Set(float64(seriesCount))
+
 	wg.Wait()
+
+	// Record the values once all batches completed, so that the whole write is recorded at once.
+	if pushed := atomic.LoadInt64(&pushed); c.cfg.Recorder != nil && pushed > 0 {
+		c.cfg.Recorder.Add(ts, recordedSum, int(pushed))
+	}
+
+	if elapsed := time.Since(startTime); c.cfg.ConcurrencySweep.enabled() && elapsed > 0 {
+		c.sweepSamplesPerSecond.WithLabelValues(strconv.Itoa(concurrency)).Set(float64(atomic.LoadInt64(&pushedSamples)) / elapsed.Seconds())
+	}
+
+	// Tune the batch size of the next write interval.
+	if c.batchSizer != nil {
+		c.batchSizer.adjust()
+	}
+
+	if abandoned := atomic.LoadInt64(&abandoned); abandoned > 0 {
+		level.Warn(c.logger).Log("msg", "write batches cancelled because they didn't complete before the write deadline", "batches", abandoned)
+		c.writeIntervalOverrunsTotal.Inc()
+	}
+
+	return int(atomic.LoadInt64(&pushed))
+}
+
+// scheduledConfig returns the config to generate the series at t, with the number of series
+// scheduled at t.
+func (c *WriteClient) scheduledConfig(t time.Time) WriteClientConfig {
+	cfg := c.cfg
+	cfg.churnSeriesCount = cfg.SeriesCount
+	cfg.SeriesCount = cfg.Schedule.seriesCount(t, cfg.SeriesCount)
+
+	return cfg
+}
+
+// getWriteGate returns the gate honoring the input write concurrency. The gate is replaced
+// when the concurrency changes, so batches still in flight with the previous gate don't count
+// toward the new concurrency.
+func (c *WriteClient) getWriteGate(concurrency int) *gate.Gate {
+	c.writeGateMx.Lock()
+	defer c.writeGateMx.Unlock()
+
+	if c.writeGate == nil || c.writeGateConcurrency != concurrency {
+		c.writeGate = gate.New(concurrency)
+		c.writeGateConcurrency = concurrency
+	}
+
+	return c.writeGate
 }
 
-func (c *WriteClient) send(ctx context.Context, req *prompb.WriteRequest) error {
-	data, err := proto.Marshal(req)
+// sumRecordedSeries returns the sum of the values of the input series targeted by the default
+// query, which is the first metric name. Only the first HA replica is taken into account, since
+// replicas are expected to be deduplicated.
+func sumRecordedSeries(series []*prompb.TimeSeries, t time.Time, cfg WriteClientConfig) float64 {
+	metricName := rotatedMetricNames(sineWaveMetricNames(sineWaveBaseMetricName(cfg.UserID, cfg.TenantMetricName), cfg.MetricNamesCount), t, cfg.NameRotationPeriod)[0]
+	replica := haReplicaNames(cfg.HAReplicas)[0]
+
+	sum := 0.0
+	for _, s := range series {
+		var actualName, actualReplica string
+		for _, l := range s.Labels {
+			switch l.Name {
+			case "__name__":
+				actualName = l.Value
+			case haReplicaLabelName:
+				actualReplica = l.Value
+			}
+		}
+
+		// Only the latest sample is taken into account, since previous ones may have been backfilled.
+		if actualName == metricName && actualReplica == replica && len(s.Samples) > 0 {
+			sum += s.Samples[len(s.Samples)-1].Value
+		}
+	}
+
+	return sum
+}
+
+// haReplicaNames returns the __replica__ label values of the input number of HA replicas,
+// or a single empty name if HA replicas are disabled.
+func haReplicaNames(count int) []string {
+	if count <= 1 {
+		return []string{""}
+	}
+
+	names := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		names = append(names, fmt.Sprintf("replica-%c", 'a'+i))
+	}
+
+	return names
+}
+
+// splitHAReplicas splits the series generated by generateSineWaveSeries into the series of each HA replica.
+func splitHAReplicas(series []*prompb.TimeSeries, replicas int) [][]*prompb.TimeSeries {
+	perReplica := len(series) / replicas
+
+	out := make([][]*prompb.TimeSeries, 0, replicas)
+	for r := 0; r < replicas; r++ {
+		out = append(out, series[r*perReplica:(r+1)*perReplica])
+	}
+
+	return out
+}
+
+// partitionSeries splits series into batches of at most batchSize series, assigning
+// series to batches according to the input assignment strategy.
+func partitionSeries(series []*prompb.TimeSeries, batchSize int, assignment string) [][]*prompb.TimeSeries {
+	numBatches := (len(series) + batchSize - 1) / batchSize
+	batches := make([][]*prompb.TimeSeries, 0, numBatches)
+
+	if assignment == BatchAssignmentRoundRobin {
+		for b := 0; b < numBatches; b++ {
+			batch := make([]*prompb.TimeSeries, 0, batchSize)
+			for i := b; i < len(series); i += numBatches {
+				batch = append(batch, series[i])
+			}
+			batches = append(batches, batch)
+		}
+
+		return batches
+	}
+
+	for o := 0; o < len(series); o += batchSize {
+		end := o + batchSize
+		if end > len(series) {
+			end = len(series)
+		}
+
+		batches = append(batches, series[o:end])
+	}
+
+	return batches
+}
+
+func (c *WriteClient) send(ctx context.Context, req *prompb.WriteRequest) (err error) {
+	defer func() {
+		if err != nil {
+			c.writeErrorsTotal.WithLabelValues(writeErrorCategory(err)).Inc()
+		}
+	}()
+
+	if c.shouldInjectWriteFailure() {
+		return errInjectedWriteFailure
+	}
+
+	if c.cfg.SortSeriesInRequest {
+		sort.Slice(req.Timeseries, func(i, j int) bool {
+			return compareLabels(req.Timeseries[i].Labels, req.Timeseries[j].Labels) < 0
+		})
+	}
+
+	// Honor the max in-flight samples.
+	if c.cfg.InflightSamples != nil {
+		release, err := c.cfg.InflightSamples.acquire(ctx, countSamples(req))
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+
+	compressed, err := encodeWriteRequest(req, c.metadata)
 	if err != nil {
 		return err
 	}
 
-	compressed := snappy.Encode(nil, data)
-	httpReq, err := http.NewRequest("POST", c.cfg.URL.String(), bytes.NewReader(compressed))
+	method := c.cfg.WriteMethod
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	// Pick the tenant of the request from the pool, if any.
+	writeURL := c.writeURL
+	if len(c.cfg.TenantPool) > 0 {
+		tenant := c.pickTenant()
+		ctx = withTenant(ctx, tenant)
+		writeURL = writeURLForTenant(c.cfg.URL, tenant)
+	}
+
+	httpReq, err := http.NewRequest(method, writeURL, bytes.NewReader(compressed))
 	if err != nil {
 		// Errors from NewRequest are from unparseable URLs, so are not
 		// recoverable.
@@ -137,10 +892,13 @@ func (c *WriteClient) send(ctx context.Context, req *prompb.WriteRequest) error
 	httpReq.Header.Add("Content-Encoding", "snappy")
 	httpReq.Header.Set("Content-Type", "application/x-protobuf")
 	httpReq.Header.Set("User-Agent", "cortex-load-generator")
-	httpReq.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	httpReq.Header.Set("X-Prometheus-Remote-Write-Version", c.remoteWriteVersion())
+	if c.cfg.AcceptEncoding != "" {
+		httpReq.Header.Set("Accept-Encoding", c.cfg.AcceptEncoding)
+	}
 	httpReq = httpReq.WithContext(ctx)
 
-	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.WriteInterval)
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.WriteInterval)
 	defer cancel()
 
 	httpResp, err := c.client.Do(httpReq.WithContext(ctx))
@@ -149,13 +907,30 @@ func (c *WriteClient) send(ctx context.Context, req *prompb.WriteRequest) error
 	}
 	defer httpResp.Body.Close()
 
+	if httpResp.StatusCode == http.StatusTooManyRequests {
+		c.writeRateLimitedTotal.Inc()
+
+		if c.cfg.HonorRetryAfter {
+			if retryAfter, ok := parseRetryAfter(httpResp.Header.Get("Retry-After"), time.Now()); ok {
+				c.pauseUntil(retryAfter)
+			}
+		}
+	}
+
+	if httpResp.StatusCode/100 == 2 {
+		accepted, rejected := c.parseWriteResponse(httpResp, countSamples(req))
+		c.writeSamplesTotal.WithLabelValues(samplesAccepted).Add(float64(accepted))
+		c.writeSamplesTotal.WithLabelValues(samplesRejected).Add(float64(rejected))
+	}
+
 	if httpResp.StatusCode/100 != 2 {
-		scanner := bufio.NewScanner(io.LimitReader(httpResp.Body, maxErrMsgLen))
 		line := ""
-		if scanner.Scan() {
+		if body, err := responseBody(httpResp); err != nil {
+			line = fmt.Sprintf("unable to decode the response body: %v", err)
+		} else if scanner := bufio.NewScanner(io.LimitReader(body, maxErrMsgLen)); scanner.Scan() {
 			line = scanner.Text()
 		}
-		err = fmt.Errorf("server returned HTTP status %s: %s", httpResp.Status, line)
+		err = httpStatusError{statusCode: httpResp.StatusCode, msg: fmt.Sprintf("server returned HTTP status %s: %s", httpResp.Status, line)}
 	}
 	if httpResp.StatusCode/100 == 5 {
 		return err
@@ -163,61 +938,551 @@ func (c *WriteClient) send(ctx context.Context, req *prompb.WriteRequest) error
 	return err
 }
 
+// partialWriteResponse is the body of a 2xx response to a partially accepted write request.
+// Fields are nil if missing, e.g. in the JSON ack of a proxy.
+type partialWriteResponse struct {
+	Accepted *int `json:"accepted"`
+	Rejected *int `json:"rejected"`
+}
+
+// parseWriteResponse returns the number of accepted and rejected samples of a successful write
+// request. All samples are considered accepted, unless the response reports a partial write with
+// both the number of accepted and rejected samples, adding up to the samples sent.
+func (c *WriteClient) parseWriteResponse(httpResp *http.Response, samples int) (accepted, rejected int) {
+	mediaType, _, err := mime.ParseMediaType(httpResp.Header.Get("Content-Type"))
+	if err != nil || mediaType != partialWriteContentType {
+		return samples, 0
+	}
+
+	body, err := responseBody(httpResp)
+	if err != nil {
+		level.Warn(c.logger).Log("msg", "unable to decode partial write response, considering all samples accepted", "err", err)
+		return samples, 0
+	}
+
+	var partial partialWriteResponse
+	if err := json.NewDecoder(io.LimitReader(body, maxErrMsgLen)).Decode(&partial); err != nil {
+		level.Warn(c.logger).Log("msg", "unable to parse partial write response, considering all samples accepted", "err", err)
+		return samples, 0
+	}
+
+	// Any other JSON body doesn't report a partial write.
+	if partial.Accepted == nil || partial.Rejected == nil {
+		return samples, 0
+	}
+
+	if *partial.Accepted+*partial.Rejected != samples {
+		level.Warn(c.logger).Log("msg", "partial write response doesn't add up to the samples sent, considering all samples accepted", "accepted", *partial.Accepted, "rejected", *partial.Rejected, "samples", samples)
+		return samples, 0
+	}
+
+	return *partial.Accepted, *partial.Rejected
+}
+
+// responseBody returns the body of the write response, decompressed if it's gzip-encoded.
+func responseBody(httpResp *http.Response) (io.Reader, error) {
+	if !strings.EqualFold(httpResp.Header.Get("Content-Encoding"), "gzip") {
+		return httpResp.Body, nil
+	}
+
+	return gzip.NewReader(httpResp.Body)
+}
+
+// countSamples returns the number of samples in the write request.
+func countSamples(req *prompb.WriteRequest) int {
+	count := 0
+	for _, series := range req.Timeseries {
+		count += len(series.Samples)
+	}
+
+	return count
+}
+
+func (c *WriteClient) getPausedUntil() time.Time {
+	c.pausedUntilMx.Lock()
+	defer c.pausedUntilMx.Unlock()
+
+	return c.pausedUntil
+}
+
+// pauseUntil pauses writes until the input time, unless they're already paused for longer.
+func (c *WriteClient) pauseUntil(until time.Time) {
+	c.pausedUntilMx.Lock()
+	defer c.pausedUntilMx.Unlock()
+
+	if until.After(c.pausedUntil) {
+		c.pausedUntil = until
+	}
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which can either be a number of
+// seconds or an HTTP date, and returns the time after which the request can be retried.
+func parseRetryAfter(value string, now time.Time) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return now.Add(time.Duration(seconds) * time.Second), true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return date, true
+	}
+
+	return time.Time{}, false
+}
+
+// writeURLForTenant returns the write URL, replacing the {tenant} placeholder in the path
+// with the input tenant ID.
+func writeURLForTenant(u url.URL, userID string) string {
+	u.Path = strings.ReplaceAll(u.Path, tenantPlaceholder, userID)
+	u.RawPath = ""
+
+	return u.String()
+}
+
+// splitBatchesBySize splits the input batches in halves until each compressed write request,
+// including the input encoded metadata, doesn't exceed maxBytes, or contains a single series.
+// 0 to disable.
+func splitBatchesBySize(batches [][]*prompb.TimeSeries, maxBytes int, metadata []byte) [][]*prompb.TimeSeries {
+	if maxBytes <= 0 {
+		return batches
+	}
+
+	out := make([][]*prompb.TimeSeries, 0, len(batches))
+	for _, batch := range batches {
+		out = append(out, splitBatchBySize(batch, maxBytes, metadata)...)
+	}
+
+	return out
+}
+
+func splitBatchBySize(batch []*prompb.TimeSeries, maxBytes int, metadata []byte) [][]*prompb.TimeSeries {
+	if len(batch) <= 1 {
+		return [][]*prompb.TimeSeries{batch}
+	}
+
+	compressed, err := encodeWriteRequest(&prompb.WriteRequest{Timeseries: batch}, metadata)
+	if err == nil && len(compressed) <= maxBytes {
+		return [][]*prompb.TimeSeries{batch}
+	}
+
+	half := len(batch) / 2
+	return append(splitBatchBySize(batch[:half], maxBytes, metadata), splitBatchBySize(batch[half:], maxBytes, metadata)...)
+}
+
+// encodeWriteRequest marshals the input request, followed by the already encoded metadata (if any),
+// and snappy compresses it. Buffers are pre-sized, so that they don't get reallocated while growing.
+func encodeWriteRequest(req *prompb.WriteRequest, metadata []byte) ([]byte, error) {
+	data := make([]byte, req.Size()+len(metadata))
+	n, err := req.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	n += copy(data[n:], metadata)
+
+	return snappy.Encode(make([]byte, snappy.MaxEncodedLen(n)), data[:n]), nil
+}
+
+// shouldInjectWriteFailure returns whether the next write request should fail,
+// honoring the configured failure injection rate.
+func (c *WriteClient) shouldInjectWriteFailure() bool {
+	if c.cfg.InjectWriteFailureRate <= 0 {
+		return false
+	}
+
+	c.failureRandMx.Lock()
+	defer c.failureRandMx.Unlock()
+
+	return c.failureRand.Float64() < c.cfg.InjectWriteFailureRate
+}
+
+// pickTenant returns a tenant drawn at random from the tenant pool.
+func (c *WriteClient) pickTenant() string {
+	c.tenantRandMx.Lock()
+	defer c.tenantRandMx.Unlock()
+
+	return c.cfg.TenantPool[c.tenantRand.Intn(len(c.cfg.TenantPool))]
+}
+
+// httpStatusError is returned when the remote endpoint responds with a non-2xx status code.
+type httpStatusError struct {
+	statusCode int
+	msg        string
+}
+
+func (e httpStatusError) Error() string {
+	return e.msg
+}
+
+// writeResult returns the result label value for a write request which completed with err.
+func writeResult(err error) string {
+	var statusErr httpStatusError
+
+	switch {
+	case err == nil:
+		return writeSuccess
+	case errors.As(err, &statusErr) && statusErr.statusCode/100 == 4:
+		return writeRejected
+	default:
+		return writeFailed
+	}
+}
+
 func alignTimestampToInterval(ts time.Time, interval time.Duration) time.Time {
 	return time.Unix(0, (ts.UnixNano()/int64(interval))*int64(interval))
 }
 
-func generateSineWaveSeries(t time.Time, seriesCount, extraLabelsCount int, churnPeriod time.Duration) []*prompb.TimeSeries {
-	out := make([]*prompb.TimeSeries, 0, seriesCount)
-	value := generateSineWaveValue(t)
+func generateSineWaveSeries(t time.Time, cfg WriteClientConfig) []*prompb.TimeSeries {
+	replicas := haReplicaNames(cfg.HAReplicas)
+	metricNames := rotatedMetricNames(sineWaveMetricNames(sineWaveBaseMetricName(cfg.UserID, cfg.TenantMetricName), cfg.MetricNamesCount), t, cfg.NameRotationPeriod)
+	out := make([]*prompb.TimeSeries, 0, len(replicas)*len(metricNames)*cfg.SeriesCount)
+	baseValue := cfg.Values.baseValue(t)
+	externalLabels := sortedExternalLabels(cfg.ExternalLabels)
 
 	// Generate the extra labels.
-	extraLabels := make([]*prompb.Label, 0, extraLabelsCount)
-	for j := 0; j < extraLabelsCount; j++ {
+	extraLabels := make([]*prompb.Label, 0, cfg.ExtraLabels)
+	for j := 0; j < cfg.ExtraLabels; j++ {
 		extraLabels = append(extraLabels, &prompb.Label{
 			Name:  fmt.Sprintf("extraLabel%d", j),
 			Value: "default",
 		})
 	}
 
-	for seriesID := 1; seriesID <= seriesCount; seriesID++ {
-		labels := make([]*prompb.Label, 0, 3+extraLabelsCount)
+	// Series are generated grouped by HA replica.
+	for _, replica := range replicas {
+		for _, metricName := range metricNames {
+			for seriesID := 1; seriesID <= cfg.SeriesCount; seriesID++ {
+				// The value only depends on the series ID, which doesn't change when the series churns,
+				// so that churning never alters the aggregated values, even if decorrelated.
+				value := cfg.Values.seriesValue(t, baseValue, seriesID)
+				timestamp := t.Add(-seriesClockSkew(seriesID, cfg.ClockSkewStdDev, cfg.WriteInterval))
+
+				labels := make([]*prompb.Label, 0, 6+cfg.ExtraLabels)
+				labels = append(labels, &prompb.Label{
+					Name:  "__name__",
+					Value: metricName,
+				}, &prompb.Label{
+					Name:  "wave",
+					Value: strconv.Itoa(seriesID),
+				})
+
+				// Add extra labels.
+				labels = append(labels, extraLabels...)
+				labels = append(labels, generateDistinctLabels(seriesID, cfg.DistinctLabelNames)...)
+				labels = append(labels, generateDimensionLabels(seriesID, cfg.Dimensions)...)
+
+				// Spread series across the fake instances.
+				if cfg.FakeInstances > 0 {
+					labels = setLabel(labels, instanceLabelName, fakeInstanceName(seriesID, cfg.FakeInstances))
+				}
+
+				// Add the series creation timestamp, which never changes.
+				if cfg.CreatedLabel {
+					labels = setLabel(labels, createdLabelName, seriesCreatedTimestamp(seriesID))
+				}
+
+				samples := []prompb.Sample{{
+					Value:     value,
+					Timestamp: timestamp.UnixMilli(),
+				}}
+
+				// Change the series identity to simulate churning series.
+				if cfg.SeriesChurnPeriod > 0 {
+					churnID := seriesChurnID(t, cfg, seriesID)
+					switch cfg.ChurnTarget {
+					case ChurnTargetName:
+						labels = setLabel(labels, "__name__", fmt.Sprintf("%s_churn_%d", metricName, churnID))
+					case ChurnTargetID:
+						labels = setLabel(labels, "wave", fmt.Sprintf("%d-%d", seriesID, churnID))
+					default:
+						labels = append(labels, &prompb.Label{
+							Name:  "churn",
+							Value: fmt.Sprintf("%d", churnID),
+						})
+					}
+
+					// Backfill the previous intervals of newly churned series.
+					if cfg.ChurnBackfillSamples > 0 && churnID != seriesChurnID(t.Add(-cfg.WriteInterval), cfg, seriesID) {
+						samples = append(churnBackfillSamples(t, cfg, seriesID), samples...)
+					}
+				}
+
+				// Replace all series every write interval.
+				if cfg.FullChurn {
+					labels = append(labels, &prompb.Label{
+						Name:  fullChurnLabelName,
+						Value: strconv.FormatInt(t.UnixMilli(), 10),
+					})
+				}
+
+				// Rotate the value of the value churn label.
+				if cfg.ValueChurnLabel != "" && cfg.ValueChurnPeriod > 0 {
+					labels = setLabel(labels, cfg.ValueChurnLabel, strconv.FormatInt(t.Unix()/int64(cfg.ValueChurnPeriod.Seconds()), 10))
+				}
+
+				// Add a label with the checksum of the value, to detect silent data corruption.
+				if cfg.ChecksumLabel {
+					labels = append(labels, &prompb.Label{
+						Name:  checksumLabelName,
+						Value: valueChecksum(value),
+					})
+				}
+
+				// Add the HA labels.
+				if replica != "" {
+					labels = append(labels, &prompb.Label{
+						Name:  haClusterLabelName,
+						Value: haClusterName,
+					}, &prompb.Label{
+						Name:  haReplicaLabelName,
+						Value: replica,
+					})
+				}
+
+				labels = addExternalLabels(labels, externalLabels)
+
+				// Add the label with the shard the series falls into, hashing all other labels.
+				if cfg.SeriesShards > 0 {
+					labels = append(labels, &prompb.Label{
+						Name:  shardLabelName,
+						Value: strconv.Itoa(seriesShard(labels, cfg.SeriesShards)),
+					})
+				}
+
+				// Ensure labels are sorted, unless we've been asked to deliberately send them unsorted,
+				// in which case they're reversed once sorted.
+				sort.Slice(labels, func(i, j int) bool {
+					if labels[i].Name == labels[j].Name {
+						return labels[i].Value < labels[j].Value
+					}
+					return labels[i].Name < labels[j].Name
+				})
+				if cfg.SendUnsortedLabels {
+					for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+						labels[i], labels[j] = labels[j], labels[i]
+					}
+				}
+
+				out = append(out, &prompb.TimeSeries{
+					Labels:  labels,
+					Samples: samples,
+				})
+			}
+		}
+	}
+
+	return out
+}
+
+// seriesClockSkew returns the clock skew of the series with the input ID, drawn from the absolute
+// value of a normal distribution with the input standard deviation. The skew is capped to 3 standard
+// deviations and to less than the write interval, so that samples of different intervals don't overlap.
+func seriesClockSkew(seriesID int, stdDev, writeInterval time.Duration) time.Duration {
+	if stdDev <= 0 {
+		return 0
+	}
+
+	// Generate a standard normal value with the Box-Muller transform. The first uniform
+	// value must be in (0, 1] to take its logarithm.
+	key := uint64(seriesID) << 1
+	u1 := 1 - hashToUnitInterval(clockSkewSeed, key)
+	u2 := hashToUnitInterval(clockSkewSeed, key|1)
+	z := math.Sqrt(-2*math.Log(u1)) * math.Cos(2*math.Pi*u2)
+
+	skew := time.Duration(math.Min(math.Abs(z), 3) * float64(stdDev))
+	if maxSkew := writeInterval - time.Millisecond; writeInterval > 0 && skew > maxSkew {
+		skew = maxSkew
+	}
+
+	// Timestamps have millisecond precision.
+	return skew.Truncate(time.Millisecond)
+}
+
+// compareLabels compares two label sets, label by label, and returns 0 if a == b,
+// a negative number if a < b and a positive number if a > b.
+func compareLabels(a, b []*prompb.Label) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i].Name != b[i].Name {
+			return strings.Compare(a[i].Name, b[i].Name)
+		}
+		if a[i].Value != b[i].Value {
+			return strings.Compare(a[i].Value, b[i].Value)
+		}
+	}
+
+	return len(a) - len(b)
+}
+
+// setLabel sets the value of the label with the input name, adding it if missing. Labels may be
+// shared across series, so an existing label is replaced instead of being modified in place.
+func setLabel(labels []*prompb.Label, name, value string) []*prompb.Label {
+	for i, l := range labels {
+		if l.Name == name {
+			labels[i] = &prompb.Label{Name: name, Value: value}
+			return labels
+		}
+	}
+
+	return append(labels, &prompb.Label{Name: name, Value: value})
+}
+
+// churnBackfillSamples returns the samples of the ChurnBackfillSamples write intervals before t,
+// in timestamp order, for the series with the input ID.
+func churnBackfillSamples(t time.Time, cfg WriteClientConfig, seriesID int) []prompb.Sample {
+	skew := seriesClockSkew(seriesID, cfg.ClockSkewStdDev, cfg.WriteInterval)
+	samples := make([]prompb.Sample, 0, cfg.ChurnBackfillSamples+1)
+
+	for i := cfg.ChurnBackfillSamples; i > 0; i-- {
+		ts := t.Add(-time.Duration(i) * cfg.WriteInterval)
+		samples = append(samples, prompb.Sample{
+			Value:     cfg.Values.value(ts, seriesID),
+			Timestamp: ts.Add(-skew).UnixMilli(),
+		})
+	}
+
+	return samples
+}
+
+// seriesChurnID returns the churn label value of the series with the input ID at t.
+func seriesChurnID(t time.Time, cfg WriteClientConfig, seriesID int) int64 {
+	var epoch int64
+	if !cfg.ChurnEpoch.IsZero() {
+		epoch = cfg.ChurnEpoch.Unix()
+	}
+
+	// In cliff mode, all series churn at the end of each "churn period".
+	if cfg.ChurnMode == ChurnModeCliff {
+		return floorDiv(t.Unix()-epoch, int64(cfg.SeriesChurnPeriod.Seconds()))
+	}
+
+	// Spread churning series over the "churn period" we compute the churn ID
+	// starting from the current time, shifted by the series ID. Then the value
+	// is rounded so that it changes every "churn period".
+	seriesCount := cfg.SeriesCount
+	if cfg.churnSeriesCount > 0 {
+		seriesCount = cfg.churnSeriesCount
+	}
+
+	return floorDiv(t.Add((cfg.SeriesChurnPeriod/time.Duration(seriesCount))*time.Duration(seriesID)).Unix()-epoch, int64(cfg.SeriesChurnPeriod.Seconds()))
+}
+
+// floorDiv returns a / b rounded toward negative infinity, so that times before the churn epoch
+// don't share the same churn ID of the first period after it.
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+
+	return q
+}
+
+// generateDistinctLabels returns the labels of the series with the input ID, picked from a pool of
+// poolSize label names. Each series gets a random subset of the pool, which is deterministic per
+// series ID, and all label names of the pool are used across poolSize series.
+func generateDistinctLabels(seriesID, poolSize int) []*prompb.Label {
+	var labels []*prompb.Label
+
+	for j := 0; j < poolSize; j++ {
+		// Ensure each label name is used by at least 1 series.
+		if j != (seriesID-1)%poolSize && hashToUnitInterval(uint64(seriesID), uint64(j)) >= 0.5 {
+			continue
+		}
+
+		labels = append(labels, &prompb.Label{
+			Name:  fmt.Sprintf("distinctLabel%d", j),
+			Value: "default",
+		})
+	}
+
+	return labels
+}
+
+// fakeInstanceName returns the instance label value of the series with the input ID, cycling
+// through the fake instances so that series are evenly spread across them.
+func fakeInstanceName(seriesID, instances int) string {
+	return fmt.Sprintf("instance-%d", (seriesID-1)%instances)
+}
+
+// seriesCreatedTimestamp returns the created label value of the series with the input ID, which
+// is a different Unix timestamp (in seconds) for each series ID.
+func seriesCreatedTimestamp(seriesID int) string {
+	return strconv.FormatInt(createdLabelEpoch+int64(seriesID), 10)
+}
+
+// sineWaveMetricNames returns the names of the sine wave metrics to generate. If count is
+// greater than 1, each metric name has a numeric suffix from 0 to count-1.
+func sineWaveMetricNames(base string, count int) []string {
+	if count <= 1 {
+		return []string{base}
+	}
+
+	names := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		names = append(names, fmt.Sprintf("%s_%d", base, i))
+	}
+
+	return names
+}
+
+// sineWaveBaseMetricName returns the base name of the sine wave metrics of the input tenant,
+// which is suffixed with the tenant ID if perTenant is true.
+func sineWaveBaseMetricName(userID string, perTenant bool) string {
+	return tenantMetricName(sineWaveMetricName, userID, perTenant)
+}
+
+// tenantMetricName returns the input metric name, suffixed with the tenant ID if perTenant is
+// true. Characters of the tenant ID not allowed in metric names are replaced with underscores.
+func tenantMetricName(name, userID string, perTenant bool) string {
+	if !perTenant {
+		return name
+	}
+
+	suffix := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == ':' {
+			return r
+		}
+		return '_'
+	}, userID)
+
+	return name + "_" + suffix
+}
+
+// generateInfoSeries generates info series, resembling kube-state-metrics *_info gauges: each
+// series has a constant value of 1 and a rich set of labels, unique for each series.
+func generateInfoSeries(t time.Time, cfg WriteClientConfig) []*prompb.TimeSeries {
+	out := make([]*prompb.TimeSeries, 0, cfg.InfoSeriesCount)
+	externalLabels := sortedExternalLabels(cfg.ExternalLabels)
+
+	for seriesID := 1; seriesID <= cfg.InfoSeriesCount; seriesID++ {
+		labels := make([]*prompb.Label, 0, 2+cfg.InfoSeriesLabels)
 		labels = append(labels, &prompb.Label{
 			Name:  "__name__",
-			Value: "cortex_load_generator_sine_wave",
+			Value: tenantMetricName(infoMetricName, cfg.UserID, cfg.TenantMetricName),
 		}, &prompb.Label{
-			Name:  "wave",
+			Name:  "info",
 			Value: strconv.Itoa(seriesID),
 		})
 
-		// Add extra labels.
-		labels = append(labels, extraLabels...)
-
-		// Add a label to simulate churning series.
-		if churnPeriod > 0 {
-			// Spread churning series over the "churn period" we compute the churn ID
-			// starting from the current time, shifted by the series ID. Then the value
-			// is rounded so that it changes every "churn period".
-			churnID := t.Add((churnPeriod/time.Duration(seriesCount))*time.Duration(seriesID)).Unix() / int64(churnPeriod.Seconds())
-
+		for j := 0; j < cfg.InfoSeriesLabels; j++ {
 			labels = append(labels, &prompb.Label{
-				Name:  "churn",
-				Value: fmt.Sprintf("%d", churnID),
+				Name:  fmt.Sprintf("infoLabel%d", j),
+				Value: fmt.Sprintf("value-%d-%d", seriesID, j),
 			})
 		}
+		labels = addExternalLabels(labels, externalLabels)
 
 		// Ensure labels are sorted.
 		sort.Slice(labels, func(i, j int) bool {
-			if labels[i].Name != labels[j].Name {
-				return labels[i].Name < labels[j].Name
-			}
-			return labels[i].Value < labels[j].Value
+			return labels[i].Name < labels[j].Name
 		})
 
 		out = append(out, &prompb.TimeSeries{
 			Labels: labels,
 			Samples: []prompb.Sample{{
-				Value:     value,
+				Value:     1,
 				Timestamp: t.UnixMilli(),
 			}},
 		})
@@ -226,9 +1491,17 @@ func generateSineWaveSeries(t time.Time, seriesCount, extraLabelsCount int, chur
 	return out
 }
 
+// valueChecksum returns the CRC32 checksum of the input value, hex encoded.
+func valueChecksum(value float64) string {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, math.Float64bits(value))
+
+	return fmt.Sprintf("%08x", crc32.ChecksumIEEE(buf))
+}
+
+// generateSineWaveValue returns the value of the sine wave at t. The value only depends
+// on t, so the wave shape is the same regardless of the write interval.
 func generateSineWaveValue(t time.Time) float64 {
-	// With a 15-second scrape interval this gives a ten-minute period
-	period := float64(40 * (15 * time.Second))
-	radians := float64(t.UnixNano()) / period * 2 * math.Pi
+	radians := float64(t.UnixNano()) / float64(sineWavePeriod) * 2 * math.Pi
 	return math.Sin(radians)
 }
@@ -0,0 +1,44 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+)
+
+// Categories of failed write requests.
+const (
+	writeErrorDNS               = "dns"
+	writeErrorConnectionRefused = "connection_refused"
+	writeErrorTimeout           = "timeout"
+	writeErrorHTTP4xx           = "http_4xx"
+	writeErrorHTTP5xx           = "http_5xx"
+	writeErrorOther             = "other"
+)
+
+var writeErrorCategories = []string{writeErrorDNS, writeErrorConnectionRefused, writeErrorTimeout, writeErrorHTTP4xx, writeErrorHTTP5xx, writeErrorOther}
+
+// writeErrorCategory returns the category of the error of a failed write request.
+func writeErrorCategory(err error) string {
+	var (
+		dnsErr    *net.DNSError
+		netErr    net.Error
+		statusErr httpStatusError
+	)
+
+	switch {
+	case errors.As(err, &dnsErr):
+		return writeErrorDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return writeErrorConnectionRefused
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return writeErrorTimeout
+	case errors.As(err, &statusErr) && statusErr.statusCode/100 == 4:
+		return writeErrorHTTP4xx
+	case errors.As(err, &statusErr) && statusErr.statusCode/100 == 5:
+		return writeErrorHTTP5xx
+	default:
+		return writeErrorOther
+	}
+}
@@ -0,0 +1,114 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteErrorCategory(t *testing.T) {
+	tests := map[string]struct {
+		err      error
+		expected string
+	}{
+		"dns": {
+			err:      &url.Error{Op: "Post", URL: "http://cortex.invalid", Err: &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "cortex.invalid", IsNotFound: true}}},
+			expected: writeErrorDNS,
+		},
+		"connection refused": {
+			err:      &url.Error{Op: "Post", URL: "http://localhost:1", Err: &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}},
+			expected: writeErrorConnectionRefused,
+		},
+		"timeout": {
+			err:      &url.Error{Op: "Post", URL: "http://localhost", Err: context.DeadlineExceeded},
+			expected: writeErrorTimeout,
+		},
+		"http 4xx": {
+			err:      httpStatusError{statusCode: http.StatusTooManyRequests},
+			expected: writeErrorHTTP4xx,
+		},
+		"http 5xx": {
+			err:      fmt.Errorf("wrapped: %w", httpStatusError{statusCode: http.StatusServiceUnavailable}),
+			expected: writeErrorHTTP5xx,
+		},
+		"other": {
+			err:      errors.New("unexpected"),
+			expected: writeErrorOther,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, testData.expected, writeErrorCategory(testData.err))
+		})
+	}
+}
+
+func TestWriteClient_ShouldTrackWriteErrorsByCategory(t *testing.T) {
+	// A server responding with 5xx.
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(failing.Close)
+
+	// A server slower than the write timeout.
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+	}))
+	t.Cleanup(slow.Close)
+
+	// An address nobody listens on.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	refusedURL := "http://" + listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	tests := map[string]struct {
+		url      string
+		expected string
+	}{
+		"http 5xx":           {url: failing.URL, expected: writeErrorHTTP5xx},
+		"timeout":            {url: slow.URL, expected: writeErrorTimeout},
+		"connection refused": {url: refusedURL, expected: writeErrorConnectionRefused},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			serverURL, err := url.Parse(testData.url)
+			require.NoError(t, err)
+
+			client := NewWriteClient(WriteClientConfig{
+				URL:              *serverURL,
+				UserID:           "user-1",
+				SeriesCount:      2,
+				WriteInterval:    100 * time.Millisecond,
+				WriteTimeout:     time.Second,
+				WriteConcurrency: 1,
+				WriteBatchSize:   1,
+			}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+			client.writeSeries()
+
+			for _, category := range writeErrorCategories {
+				expected := 0.0
+				if category == testData.expected {
+					expected = 2
+				}
+				assert.Equal(t, expected, testutil.ToFloat64(client.writeErrorsTotal.WithLabelValues(category)), category)
+			}
+		})
+	}
+}
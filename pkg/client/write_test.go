@@ -1,10 +1,30 @@
 package client
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/go-kit/log"
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/prompb"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -17,6 +37,22 @@ func TestAlignTimestampToInterval(t *testing.T) {
 	assert.Equal(t, time.Unix(40, 0), alignTimestampToInterval(time.Unix(40, 0), 10*time.Second))
 }
 
+func TestGenerateSineWaveValue_PeriodShouldNotDependOnWriteInterval(t *testing.T) {
+	for _, interval := range []time.Duration{time.Second, 10 * time.Second, 15 * time.Second, time.Minute} {
+		t.Run(interval.String(), func(t *testing.T) {
+			start := alignTimestampToInterval(time.Now(), interval)
+
+			// The wave should repeat after each period and peak after a quarter of it.
+			for ts := start; ts.Before(start.Add(sineWavePeriod)); ts = ts.Add(interval) {
+				assert.InDelta(t, generateSineWaveValue(ts), generateSineWaveValue(ts.Add(sineWavePeriod)), 1e-6)
+				assert.InDelta(t, generateSineWaveValue(ts), generateSineWaveValue(ts.Add(5*sineWavePeriod)), 1e-6)
+			}
+
+			assert.InDelta(t, 1, generateSineWaveValue(time.Unix(0, 0).Add(sineWavePeriod/4)), 1e-9)
+		})
+	}
+}
+
 func TestGenerateSineWaveSeries_WithChurningSeries(t *testing.T) {
 	const (
 		numSeries   = 3
@@ -32,7 +68,7 @@ func TestGenerateSineWaveSeries_WithChurningSeries(t *testing.T) {
 			})
 		}
 
-		assert.Equal(t, expected, generateSineWaveSeries(ts, numSeries, 0, churnPeriod))
+		assert.Equal(t, expected, generateSineWaveSeries(ts, WriteClientConfig{SeriesCount: numSeries, SeriesChurnPeriod: churnPeriod}))
 	}
 
 	ts, err := time.Parse(time.RFC3339, "2023-06-29T00:00:00Z")
@@ -74,6 +110,410 @@ func TestGenerateSineWaveSeries_WithChurningSeries(t *testing.T) {
 	assertGeneratedSeries(t, ts, "28133282", "28133282", "28133282")
 }
 
+func TestWriteClient_ChurningSeriesShouldNotShiftOnScheduledSeriesCountChanges(t *testing.T) {
+	const (
+		numSeries   = 10
+		churnPeriod = time.Minute
+	)
+
+	start, err := time.Parse(time.RFC3339, "2023-06-29T00:00:00Z")
+	require.NoError(t, err)
+
+	cfg := WriteClientConfig{
+		UserID:            "user-1",
+		SeriesCount:       numSeries,
+		SeriesChurnPeriod: churnPeriod,
+		WriteInterval:     10 * time.Second,
+		WriteTimeout:      time.Second,
+		WriteConcurrency:  1,
+		WriteBatchSize:    100,
+		Schedule:          SeriesSchedule{StartTime: start, RampStartCount: 2, RampDuration: 5 * time.Minute, BurstInterval: 10 * time.Minute, BurstDuration: time.Minute, BurstMultiplier: 3},
+	}
+	client := NewWriteClient(cfg, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+	// The series of the configured series count churn at the same time, regardless of the
+	// number of series scheduled at each write.
+	cfg.Schedule = SeriesSchedule{}
+	for ts := start; ts.Before(start.Add(20 * time.Minute)); ts = ts.Add(cfg.WriteInterval) {
+		scheduled := generateSineWaveSeries(ts, client.scheduledConfig(ts))
+		unscheduled := generateSineWaveSeries(ts, cfg)
+
+		for i := 0; i < len(scheduled) && i < len(unscheduled); i++ {
+			assert.Equal(t, unscheduled[i].Labels, scheduled[i].Labels, "timestamp: %s", ts)
+		}
+	}
+}
+
+func TestGenerateSineWaveSeries_WithChurnEpoch(t *testing.T) {
+	const (
+		numSeries   = 3
+		churnPeriod = time.Minute
+	)
+
+	epoch, err := time.Parse(time.RFC3339, "2023-06-29T00:00:20Z")
+	require.NoError(t, err)
+
+	churnIDs := func(ts time.Time, mode string, epoch time.Time) []string {
+		var ids []string
+		for _, s := range generateSineWaveSeries(ts, WriteClientConfig{SeriesCount: numSeries, SeriesChurnPeriod: churnPeriod, ChurnMode: mode, ChurnEpoch: epoch}) {
+			for _, l := range s.Labels {
+				if l.Name == "churn" {
+					ids = append(ids, l.Value)
+				}
+			}
+		}
+		return ids
+	}
+
+	for _, mode := range []string{ChurnModeGradual, ChurnModeCliff} {
+		t.Run(mode, func(t *testing.T) {
+			// The churn boundaries should be shifted by the epoch, compared to the Unix epoch anchor.
+			for offset := time.Duration(0); offset < 2*churnPeriod; offset += 10 * time.Second {
+				ts := epoch.Add(offset)
+				assert.Equal(t, churnIDs(ts.Add(-20*time.Second), mode, time.Time{}), churnIDs(ts, mode, epoch.Add(-28133280*churnPeriod)))
+			}
+
+			// Churn IDs should count the periods elapsed since the epoch, also before it.
+			if mode == ChurnModeCliff {
+				assert.Equal(t, []string{"0", "0", "0"}, churnIDs(epoch, mode, epoch))
+				assert.Equal(t, []string{"0", "0", "0"}, churnIDs(epoch.Add(59*time.Second), mode, epoch))
+				assert.Equal(t, []string{"1", "1", "1"}, churnIDs(epoch.Add(churnPeriod), mode, epoch))
+				assert.Equal(t, []string{"-1", "-1", "-1"}, churnIDs(epoch.Add(-time.Second), mode, epoch))
+			}
+		})
+	}
+}
+
+func TestGenerateSineWaveSeries_WithTenantMetricName(t *testing.T) {
+	tests := map[string]struct {
+		metricNamesCount int
+		expectedNames    []string
+	}{
+		"single metric name": {
+			metricNamesCount: 1,
+			expectedNames:    []string{"cortex_load_generator_sine_wave_load_generator_2"},
+		},
+		"multiple metric names": {
+			metricNamesCount: 2,
+			expectedNames:    []string{"cortex_load_generator_sine_wave_load_generator_2_0", "cortex_load_generator_sine_wave_load_generator_2_1"},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			for tenant := 1; tenant <= 3; tenant++ {
+				userID := fmt.Sprintf("load-generator-%d", tenant)
+				series := generateSineWaveSeries(time.Now(), WriteClientConfig{UserID: userID, SeriesCount: 2, MetricNamesCount: testData.metricNamesCount, TenantMetricName: true})
+
+				names := map[string]bool{}
+				for _, s := range series {
+					for _, l := range s.Labels {
+						if l.Name == "__name__" {
+							names[l.Value] = true
+						}
+					}
+				}
+
+				// Each tenant should use its own metric names.
+				for _, expected := range testData.expectedNames {
+					assert.Equal(t, tenant == 2, names[expected], userID)
+				}
+				assert.Len(t, names, testData.metricNamesCount)
+
+				// The query client of the tenant should query the first metric name of the tenant.
+				client := NewQueryClient(QueryClientConfig{UserID: userID, ExpectedMetricNamesCount: testData.metricNamesCount, TenantMetricName: true}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+				assert.Equal(t, fmt.Sprintf("sum(%s)", sineWaveMetricNames(sineWaveBaseMetricName(userID, true), testData.metricNamesCount)[0]), client.defaultQuery)
+				if tenant == 2 {
+					assert.Equal(t, fmt.Sprintf("sum(%s)", testData.expectedNames[0]), client.defaultQuery)
+				}
+
+				// The info and summary metric names, and the info query, should be suffixed too.
+				cfg := WriteClientConfig{UserID: userID, SeriesCount: 2, InfoSeriesCount: 2, SummaryQuantiles: []float64{0.5, 0.9}, TenantMetricName: true}
+				names = map[string]bool{}
+				for _, s := range append(generateInfoSeries(time.Now(), cfg), generateSummarySeries(time.Now(), cfg)...) {
+					for _, l := range s.Labels {
+						if l.Name == "__name__" {
+							names[l.Value] = true
+						}
+					}
+				}
+				assert.Equal(t, map[string]bool{
+					fmt.Sprintf("cortex_load_generator_info_load_generator_%d", tenant):    true,
+					fmt.Sprintf("cortex_load_generator_summary_load_generator_%d", tenant): true,
+				}, names)
+				assert.Equal(t, fmt.Sprintf("sum(cortex_load_generator_info_load_generator_%d)", tenant), client.infoQuery)
+			}
+		})
+	}
+}
+
+func TestGenerateSineWaveSeries_WithFullChurn(t *testing.T) {
+	const writeInterval = 10 * time.Second
+
+	cfg := WriteClientConfig{SeriesCount: 3, HAReplicas: 2, WriteInterval: writeInterval, FullChurn: true}
+	ts := alignTimestampToInterval(time.Now(), writeInterval)
+
+	// No series identity should repeat across intervals.
+	seen := map[string]bool{}
+	for _, interval := range []time.Time{ts, ts.Add(writeInterval)} {
+		series := generateSineWaveSeries(interval, cfg)
+		require.Len(t, series, 6)
+
+		for _, s := range series {
+			key := labelsString(s.Labels)
+			assert.False(t, seen[key], "series %s repeated", key)
+			seen[key] = true
+		}
+	}
+	assert.Len(t, seen, 12)
+
+	// The series labels change every interval, so they can't be pooled.
+	assert.Nil(t, newSeriesPool(cfg))
+}
+
+func TestGenerateSineWaveSeries_WithChurnTarget(t *testing.T) {
+	ts, err := time.Parse(time.RFC3339, "2023-06-29T00:00:00Z")
+	require.NoError(t, err)
+
+	// In cliff mode all series share the same churn ID.
+	const churnID = "28133280"
+
+	tests := map[string]struct {
+		churnTarget    string
+		expectedLabels [][]*prompb.Label
+	}{
+		"label": {
+			churnTarget: ChurnTargetLabel,
+			expectedLabels: [][]*prompb.Label{
+				{{Name: "__name__", Value: "cortex_load_generator_sine_wave"}, {Name: "churn", Value: churnID}, {Name: "wave", Value: "1"}},
+				{{Name: "__name__", Value: "cortex_load_generator_sine_wave"}, {Name: "churn", Value: churnID}, {Name: "wave", Value: "2"}},
+			},
+		},
+		"name": {
+			churnTarget: ChurnTargetName,
+			expectedLabels: [][]*prompb.Label{
+				{{Name: "__name__", Value: "cortex_load_generator_sine_wave_churn_" + churnID}, {Name: "wave", Value: "1"}},
+				{{Name: "__name__", Value: "cortex_load_generator_sine_wave_churn_" + churnID}, {Name: "wave", Value: "2"}},
+			},
+		},
+		"id": {
+			churnTarget: ChurnTargetID,
+			expectedLabels: [][]*prompb.Label{
+				{{Name: "__name__", Value: "cortex_load_generator_sine_wave"}, {Name: "wave", Value: "1-" + churnID}},
+				{{Name: "__name__", Value: "cortex_load_generator_sine_wave"}, {Name: "wave", Value: "2-" + churnID}},
+			},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			cfg := WriteClientConfig{SeriesCount: 2, SeriesChurnPeriod: time.Minute, ChurnMode: ChurnModeCliff, ChurnTarget: testData.churnTarget}
+
+			series := generateSineWaveSeries(ts, cfg)
+			require.Len(t, series, len(testData.expectedLabels))
+			for idx, s := range series {
+				assert.Equal(t, testData.expectedLabels[idx], s.Labels)
+
+				// Churning should never alter the series value.
+				assert.Equal(t, generateSineWaveValue(ts), s.Samples[0].Value)
+			}
+
+			// The series identity should change once the series churn.
+			churned := generateSineWaveSeries(ts.Add(time.Minute), cfg)
+			for idx := range series {
+				assert.NotEqual(t, series[idx].Labels, churned[idx].Labels)
+			}
+		})
+	}
+}
+
+func TestGenerateSineWaveSeries_WithCliffChurningSeries(t *testing.T) {
+	const (
+		numSeries   = 3
+		churnPeriod = time.Minute
+	)
+
+	churnIDs := func(ts time.Time) []string {
+		var out []string
+		for _, s := range generateSineWaveSeries(ts, WriteClientConfig{SeriesCount: numSeries, SeriesChurnPeriod: churnPeriod, ChurnMode: ChurnModeCliff}) {
+			for _, l := range s.Labels {
+				if l.Name == "churn" {
+					out = append(out, l.Value)
+				}
+			}
+		}
+		return out
+	}
+
+	ts, err := time.Parse(time.RFC3339, "2023-06-29T00:00:00Z")
+	require.NoError(t, err)
+
+	// All series should churn together at the end of each period.
+	for i := 0; i < 6; i++ {
+		assert.Equal(t, []string{"28133280", "28133280", "28133280"}, churnIDs(ts))
+		ts = ts.Add(10 * time.Second)
+	}
+	assert.Equal(t, []string{"28133281", "28133281", "28133281"}, churnIDs(ts))
+	assert.Equal(t, []string{"28133281", "28133281", "28133281"}, churnIDs(ts.Add(50*time.Second)))
+	assert.Equal(t, []string{"28133282", "28133282", "28133282"}, churnIDs(ts.Add(churnPeriod)))
+}
+
+func TestGenerateSineWaveSeries_WithChurnBackfillSamples(t *testing.T) {
+	const (
+		numSeries       = 3
+		backfillSamples = 2
+		writeInterval   = 10 * time.Second
+	)
+
+	cfg := WriteClientConfig{SeriesCount: numSeries, SeriesChurnPeriod: time.Minute, WriteInterval: writeInterval, ChurnBackfillSamples: backfillSamples}
+
+	churnIDs := func(ts time.Time) []string {
+		var out []string
+		for _, s := range generateSineWaveSeries(ts, cfg) {
+			for _, l := range s.Labels {
+				if l.Name == "churn" {
+					out = append(out, l.Value)
+				}
+			}
+		}
+		return out
+	}
+
+	ts, err := time.Parse(time.RFC3339, "2023-06-29T00:00:00Z")
+	require.NoError(t, err)
+
+	churned := 0
+	for i := 0; i < 12; i++ {
+		prevIDs := churnIDs(ts.Add(-writeInterval))
+
+		for idx, s := range generateSineWaveSeries(ts, cfg) {
+			// Series which didn't churn should only have the sample of the current interval.
+			if churnIDs(ts)[idx] == prevIDs[idx] {
+				require.Len(t, s.Samples, 1)
+				assert.Equal(t, ts.UnixMilli(), s.Samples[0].Timestamp)
+				continue
+			}
+
+			// Newly churned series should carry the backfilled samples, in order.
+			churned++
+			require.Len(t, s.Samples, 1+backfillSamples)
+			for j, sample := range s.Samples {
+				sampleTime := ts.Add(-time.Duration(backfillSamples-j) * writeInterval)
+				assert.Equal(t, sampleTime.UnixMilli(), sample.Timestamp)
+				assert.Equal(t, generateSineWaveValue(sampleTime), sample.Value)
+			}
+		}
+
+		ts = ts.Add(writeInterval)
+	}
+
+	// Each series churns once per churn period.
+	assert.Equal(t, 2*numSeries, churned)
+}
+
+func TestGenerateSineWaveSeries_WithChurningAndDecorrelatedSeries(t *testing.T) {
+	const (
+		numSeries     = 5
+		writeInterval = 10 * time.Second
+	)
+
+	values := ValueConfig{Decorrelation: 10, Quantization: 3}
+
+	for _, churnMode := range []string{ChurnModeGradual, ChurnModeCliff} {
+		t.Run(churnMode, func(t *testing.T) {
+			cfg := WriteClientConfig{SeriesCount: numSeries, SeriesChurnPeriod: time.Minute, ChurnMode: churnMode, WriteInterval: writeInterval, Values: values}
+
+			ts, err := time.Parse(time.RFC3339, "2023-06-29T00:00:00Z")
+			require.NoError(t, err)
+
+			// Sum the generated series over 2 churn periods, like the default query does.
+			var (
+				samples        []model.SamplePair
+				churnIDsByWave = map[string]map[string]struct{}{}
+			)
+			for i := 0; i < 12; i++ {
+				sum := 0.0
+				for _, s := range generateSineWaveSeries(ts, cfg) {
+					wave, churnID := "", ""
+					for _, l := range s.Labels {
+						switch l.Name {
+						case "wave":
+							wave = l.Value
+						case "churn":
+							churnID = l.Value
+						}
+					}
+
+					if churnIDsByWave[wave] == nil {
+						churnIDsByWave[wave] = map[string]struct{}{}
+					}
+					churnIDsByWave[wave][churnID] = struct{}{}
+
+					// Churning should never alter the series value.
+					seriesID, err := strconv.Atoi(wave)
+					require.NoError(t, err)
+					require.Len(t, s.Samples, 1)
+					assert.Equal(t, values.value(ts, seriesID), s.Samples[0].Value)
+
+					sum += s.Samples[0].Value
+				}
+
+				samples = append(samples, newSamplePair(ts, sum))
+				ts = ts.Add(writeInterval)
+			}
+
+			// Ensure series actually churned.
+			for wave, churnIDs := range churnIDsByWave {
+				assert.Greater(t, len(churnIDs), 1, "wave: %s", wave)
+			}
+
+			// The aggregated values should match the expected ones.
+			require.NoError(t, verifySineWaveSamples(samples, numSeries, SeriesSchedule{}, writeInterval, values, nil))
+		})
+	}
+}
+
+func TestGenerateSineWaveSeries_WithValueChurnLabel(t *testing.T) {
+	const valueChurnPeriod = time.Minute
+
+	labelsAt := func(ts time.Time, valueChurnLabel string) []map[string]string {
+		var out []map[string]string
+		for _, s := range generateSineWaveSeries(ts, WriteClientConfig{SeriesCount: 2, ExtraLabels: 2, ValueChurnLabel: valueChurnLabel, ValueChurnPeriod: valueChurnPeriod}) {
+			labels := map[string]string{}
+			for _, l := range s.Labels {
+				labels[l.Name] = l.Value
+			}
+			out = append(out, labels)
+		}
+		return out
+	}
+
+	ts, err := time.Parse(time.RFC3339, "2023-06-29T00:00:00Z")
+	require.NoError(t, err)
+
+	for _, valueChurnLabel := range []string{"rotating", "extraLabel1"} {
+		t.Run(valueChurnLabel, func(t *testing.T) {
+			// The label value should stay the same within the period, and then rotate.
+			before := labelsAt(ts, valueChurnLabel)
+			assert.Equal(t, before, labelsAt(ts.Add(valueChurnPeriod-time.Second), valueChurnLabel))
+
+			after := labelsAt(ts.Add(valueChurnPeriod), valueChurnLabel)
+			require.Len(t, after, 2)
+
+			for i := range after {
+				assert.Equal(t, "28133280", before[i][valueChurnLabel])
+				assert.Equal(t, "28133281", after[i][valueChurnLabel])
+
+				// All other labels should stay the same.
+				delete(before[i], valueChurnLabel)
+				delete(after[i], valueChurnLabel)
+				assert.Equal(t, before[i], after[i])
+				assert.Equal(t, "default", after[i]["extraLabel0"])
+			}
+		})
+	}
+}
+
 func TestGenerateSineWaveSeries_WithoutChurningSeries(t *testing.T) {
 	const (
 		numSeries   = 3
@@ -89,7 +529,7 @@ func TestGenerateSineWaveSeries_WithoutChurningSeries(t *testing.T) {
 			})
 		}
 
-		assert.Equal(t, expected, generateSineWaveSeries(ts, numSeries, 0, churnPeriod))
+		assert.Equal(t, expected, generateSineWaveSeries(ts, WriteClientConfig{SeriesCount: numSeries, SeriesChurnPeriod: churnPeriod}))
 	}
 
 	ts, err := time.Parse(time.RFC3339, "2023-06-29T00:00:00Z")
@@ -100,3 +540,1203 @@ func TestGenerateSineWaveSeries_WithoutChurningSeries(t *testing.T) {
 		ts = ts.Add(10 * time.Second)
 	}
 }
+
+func TestGenerateSineWaveSeries_WithFakeInstances(t *testing.T) {
+	const (
+		numSeries    = 10
+		numInstances = 3
+	)
+
+	cfg := WriteClientConfig{SeriesCount: numSeries, FakeInstances: numInstances}
+	now := time.Now()
+
+	series := generateSineWaveSeries(now, cfg)
+	require.Len(t, series, numSeries)
+
+	seriesByInstance := map[string]int{}
+	for _, s := range series {
+		var instances []string
+		for _, l := range s.Labels {
+			if l.Name == "instance" {
+				instances = append(instances, l.Value)
+			}
+		}
+		require.Len(t, instances, 1)
+		seriesByInstance[instances[0]]++
+
+		// Labels should be sorted.
+		assert.True(t, sort.SliceIsSorted(s.Labels, func(i, j int) bool { return s.Labels[i].Name < s.Labels[j].Name }))
+	}
+
+	assert.Equal(t, map[string]int{"instance-0": 4, "instance-1": 3, "instance-2": 3}, seriesByInstance)
+
+	// The instance of each series should be deterministic.
+	assert.Equal(t, series, generateSineWaveSeries(now, cfg))
+}
+
+func TestGenerateSineWaveSeries_WithCreatedLabel(t *testing.T) {
+	now := time.Now()
+	cfg := WriteClientConfig{SeriesCount: 10, MetricNamesCount: 2, CreatedLabel: true}
+
+	createdLabels := func(ts time.Time) []string {
+		var values []string
+		for _, s := range generateSineWaveSeries(ts, cfg) {
+			for _, l := range s.Labels {
+				if l.Name == "created" {
+					values = append(values, l.Value)
+				}
+			}
+		}
+		return values
+	}
+
+	values := createdLabels(now)
+	require.Len(t, values, 20)
+
+	// The created label should be unique across series of the same metric name.
+	for _, metricValues := range [][]string{values[:10], values[10:]} {
+		unique := map[string]struct{}{}
+		for _, value := range metricValues {
+			unique[value] = struct{}{}
+		}
+		assert.Len(t, unique, 10)
+	}
+	assert.Equal(t, "1577836801", values[0])
+
+	// The created label should be stable across write intervals.
+	assert.Equal(t, values, createdLabels(now.Add(time.Minute)))
+	assert.Equal(t, values, createdLabels(now.Add(24*time.Hour)))
+}
+
+func TestWriteClient_SkipInitialWrite(t *testing.T) {
+	const writeInterval = 500 * time.Millisecond
+
+	tests := map[string]struct {
+		skipInitialWrite bool
+		expectedFirstMin time.Duration
+		expectedFirstMax time.Duration
+	}{
+		"should write immediately at startup by default": {
+			skipInitialWrite: false,
+			expectedFirstMin: 0,
+			expectedFirstMax: writeInterval / 2,
+		},
+		"should write only after one interval if the initial write is skipped": {
+			skipInitialWrite: true,
+			expectedFirstMin: writeInterval,
+			expectedFirstMax: 2 * writeInterval,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			firstWrite := make(chan time.Time, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case firstWrite <- time.Now():
+				default:
+				}
+			}))
+			t.Cleanup(server.Close)
+
+			serverURL, err := url.Parse(server.URL)
+			require.NoError(t, err)
+
+			client := NewWriteClient(WriteClientConfig{
+				URL:              *serverURL,
+				UserID:           "user-1",
+				SeriesCount:      1,
+				WriteInterval:    writeInterval,
+				WriteTimeout:     time.Second,
+				WriteConcurrency: 1,
+				WriteBatchSize:   1,
+				SkipInitialWrite: testData.skipInitialWrite,
+			}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+			startTime := time.Now()
+			client.Start()
+
+			select {
+			case ts := <-firstWrite:
+				elapsed := ts.Sub(startTime)
+				assert.GreaterOrEqual(t, elapsed, testData.expectedFirstMin)
+				assert.Less(t, elapsed, testData.expectedFirstMax)
+			case <-time.After(2 * writeInterval):
+				t.Fatal("no write received")
+			}
+		})
+	}
+}
+
+func TestGenerateSineWaveSeries_WithUnsortedLabels(t *testing.T) {
+	ts, err := time.Parse(time.RFC3339, "2023-06-29T00:00:00Z")
+	require.NoError(t, err)
+
+	sortedSeries := generateSineWaveSeries(ts, WriteClientConfig{SeriesCount: 3, ExtraLabels: 2, SeriesChurnPeriod: time.Minute})
+	unsortedSeries := generateSineWaveSeries(ts, WriteClientConfig{SeriesCount: 3, ExtraLabels: 2, SeriesChurnPeriod: time.Minute, SendUnsortedLabels: true})
+	require.Len(t, sortedSeries, 3)
+	require.Len(t, unsortedSeries, 3)
+
+	for i := range sortedSeries {
+		sorted, unsorted := sortedSeries[i].Labels, unsortedSeries[i].Labels
+		require.Len(t, sorted, 5)
+		require.Len(t, unsorted, 5)
+
+		assert.True(t, sort.SliceIsSorted(sorted, func(i, j int) bool {
+			return sorted[i].Name < sorted[j].Name
+		}))
+
+		// The unsorted labels should be the sorted ones in reverse order.
+		for j := range sorted {
+			assert.Equal(t, sorted[j], unsorted[len(unsorted)-1-j])
+		}
+	}
+}
+
+func TestWriteClient_SortSeriesInRequest(t *testing.T) {
+	for _, sortSeries := range []bool{false, true} {
+		t.Run(fmt.Sprintf("sort series: %t", sortSeries), func(t *testing.T) {
+			var (
+				receivedMx sync.Mutex
+				received   []*prompb.WriteRequest
+			)
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				if !assert.NoError(t, err) {
+					return
+				}
+
+				req, err := decodeWriteRequest(body)
+				if !assert.NoError(t, err) {
+					return
+				}
+
+				receivedMx.Lock()
+				received = append(received, req)
+				receivedMx.Unlock()
+			}))
+			t.Cleanup(server.Close)
+
+			serverURL, err := url.Parse(server.URL)
+			require.NoError(t, err)
+
+			// Series IDs from 1 to 12 are not sorted lexicographically by their wave label.
+			client := NewWriteClient(WriteClientConfig{
+				URL:                 *serverURL,
+				UserID:              "user-1",
+				SeriesCount:         12,
+				WriteInterval:       10 * time.Second,
+				WriteTimeout:        time.Second,
+				WriteConcurrency:    1,
+				WriteBatchSize:      100,
+				SortSeriesInRequest: sortSeries,
+			}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+			client.writeSeries()
+
+			receivedMx.Lock()
+			defer receivedMx.Unlock()
+			require.Len(t, received, 1)
+			require.Len(t, received[0].Timeseries, 12)
+
+			sorted := sort.SliceIsSorted(received[0].Timeseries, func(i, j int) bool {
+				return compareLabels(received[0].Timeseries[i].Labels, received[0].Timeseries[j].Labels) < 0
+			})
+			assert.Equal(t, sortSeries, sorted)
+		})
+	}
+}
+
+func TestCompareLabels(t *testing.T) {
+	labels := func(pairs ...string) []*prompb.Label {
+		var out []*prompb.Label
+		for i := 0; i < len(pairs); i += 2 {
+			out = append(out, &prompb.Label{Name: pairs[i], Value: pairs[i+1]})
+		}
+		return out
+	}
+
+	assert.Zero(t, compareLabels(labels("a", "1", "b", "2"), labels("a", "1", "b", "2")))
+	assert.Negative(t, compareLabels(labels("a", "1", "b", "2"), labels("a", "1", "b", "3")))
+	assert.Negative(t, compareLabels(labels("a", "1", "b", "2"), labels("a", "1", "c", "1")))
+	assert.Negative(t, compareLabels(labels("a", "1"), labels("a", "1", "b", "2")))
+	assert.Positive(t, compareLabels(labels("a", "2"), labels("a", "1", "b", "2")))
+}
+
+func TestWriteClient_ShouldTrackRejectedWriteRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "out of order labels", http.StatusBadRequest)
+	}))
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	reg := prometheus.NewPedanticRegistry()
+	client := NewWriteClient(WriteClientConfig{
+		URL:                *serverURL,
+		UserID:             "user-1",
+		SeriesCount:        2,
+		WriteInterval:      time.Second,
+		WriteTimeout:       time.Second,
+		WriteConcurrency:   1,
+		WriteBatchSize:     1,
+		SendUnsortedLabels: true,
+	}, log.NewNopLogger(), reg)
+
+	client.writeSeries()
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_load_generator_write_requests_total Total number of attempted write requests.
+		# TYPE cortex_load_generator_write_requests_total counter
+		cortex_load_generator_write_requests_total{result="fail",user="user-1"} 0
+		cortex_load_generator_write_requests_total{result="rejected",user="user-1"} 2
+		cortex_load_generator_write_requests_total{result="success",user="user-1"} 0
+	`), "cortex_load_generator_write_requests_total"))
+}
+
+func TestWriteClient_ShouldTrackPartiallyAcceptedSamples(t *testing.T) {
+	var requests int64
+
+	// The first batch is partially accepted, the second one is fully accepted.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&requests, 1) == 1 {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			_, _ = w.Write([]byte(`{"accepted":3,"rejected":2}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	reg := prometheus.NewPedanticRegistry()
+	client := NewWriteClient(WriteClientConfig{
+		URL:              *serverURL,
+		UserID:           "user-1",
+		SeriesCount:      10,
+		WriteInterval:    time.Second,
+		WriteTimeout:     time.Second,
+		WriteConcurrency: 1,
+		WriteBatchSize:   5,
+	}, log.NewNopLogger(), reg)
+
+	client.writeSeries()
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_load_generator_write_samples_total Total number of samples sent by successful write requests, by whether the remote endpoint accepted or rejected them.
+		# TYPE cortex_load_generator_write_samples_total counter
+		cortex_load_generator_write_samples_total{result="accepted",user="user-1"} 8
+		cortex_load_generator_write_samples_total{result="rejected",user="user-1"} 2
+	`), "cortex_load_generator_write_samples_total"))
+}
+
+func TestWriteClient_ParseWriteResponse(t *testing.T) {
+	const samples = 5
+
+	tests := map[string]struct {
+		contentType      string
+		body             string
+		expectedAccepted int
+		expectedRejected int
+	}{
+		"no body": {
+			expectedAccepted: 5,
+		},
+		"partial write": {
+			contentType:      "application/json",
+			body:             `{"accepted":3,"rejected":2}`,
+			expectedAccepted: 3,
+			expectedRejected: 2,
+		},
+		"partial write with all samples rejected": {
+			contentType:      "application/json",
+			body:             `{"accepted":0,"rejected":5}`,
+			expectedRejected: 5,
+		},
+		"empty JSON object": {
+			contentType:      "application/json",
+			body:             `{}`,
+			expectedAccepted: 5,
+		},
+		"JSON ack without the accepted and rejected samples": {
+			contentType:      "application/json",
+			body:             `{"status":"ok"}`,
+			expectedAccepted: 5,
+		},
+		"JSON body with only the accepted samples": {
+			contentType:      "application/json",
+			body:             `{"accepted":3}`,
+			expectedAccepted: 5,
+		},
+		"partial write not adding up to the samples sent": {
+			contentType:      "application/json",
+			body:             `{"accepted":3,"rejected":1}`,
+			expectedAccepted: 5,
+		},
+		"invalid JSON": {
+			contentType:      "application/json",
+			body:             `not json`,
+			expectedAccepted: 5,
+		},
+		"other content type": {
+			contentType:      "text/plain",
+			body:             `{"accepted":3,"rejected":2}`,
+			expectedAccepted: 5,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			client := NewWriteClient(WriteClientConfig{UserID: "user-1"}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+			rec := httptest.NewRecorder()
+			if testData.contentType != "" {
+				rec.Header().Set("Content-Type", testData.contentType)
+			}
+			_, _ = rec.WriteString(testData.body)
+
+			accepted, rejected := client.parseWriteResponse(rec.Result(), samples)
+			assert.Equal(t, testData.expectedAccepted, accepted)
+			assert.Equal(t, testData.expectedRejected, rejected)
+		})
+	}
+}
+
+func TestWriteClient_ShouldTrackConfiguredAndPushedSeries(t *testing.T) {
+	var requests int64
+
+	// Fail the first batch only.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&requests, 1) == 1 {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	reg := prometheus.NewPedanticRegistry()
+	client := NewWriteClient(WriteClientConfig{
+		URL:              *serverURL,
+		UserID:           "user-1",
+		SeriesCount:      12,
+		WriteInterval:    time.Second,
+		WriteTimeout:     time.Second,
+		WriteConcurrency: 1,
+		WriteBatchSize:   4,
+	}, log.NewNopLogger(), reg)
+
+	client.writeSeries()
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_load_generator_series_configured Number of series generated to be pushed in the last write interval.
+		# TYPE cortex_load_generator_series_configured gauge
+		cortex_load_generator_series_configured{user="user-1"} 12
+		# HELP cortex_load_generator_series_pushed_total Total number of series successfully pushed to the remote endpoint.
+		# TYPE cortex_load_generator_series_pushed_total counter
+		cortex_load_generator_series_pushed_total{user="user-1"} 8
+	`), "cortex_load_generator_series_configured", "cortex_load_generator_series_pushed_total"))
+}
+
+func TestWriteClient_ShouldCancelBatchesNotCompletedBeforeWriteDeadline(t *testing.T) {
+	// Simulate a slow remote endpoint, which responds only once the test is done.
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	reg := prometheus.NewPedanticRegistry()
+	client := NewWriteClient(WriteClientConfig{
+		URL:                *serverURL,
+		UserID:             "user-1",
+		SeriesCount:        3,
+		WriteInterval:      time.Second,
+		WriteTimeout:       time.Second,
+		WriteConcurrency:   1,
+		WriteBatchSize:     1,
+		WriteDeadlineRatio: 0.1,
+	}, log.NewNopLogger(), reg)
+
+	// Without the deadline, each batch would time out after the write interval.
+	start := time.Now()
+	client.writeSeries()
+	assert.Less(t, time.Since(start), time.Second)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(client.writeIntervalOverrunsTotal))
+	assert.Equal(t, 3.0, testutil.ToFloat64(client.writeRequestsTotal.WithLabelValues(writeFailed)))
+}
+
+func TestWriteClient_ShouldTrackWriteBatchesLastInterval(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		seriesCount     int
+		batchSize       int
+		expectedBatches int
+	}{
+		"series count is a multiple of the batch size": {
+			seriesCount:     100,
+			batchSize:       10,
+			expectedBatches: 10,
+		},
+		"series count is not a multiple of the batch size": {
+			seriesCount:     101,
+			batchSize:       10,
+			expectedBatches: 11,
+		},
+		"batch size is greater than series count": {
+			seriesCount:     5,
+			batchSize:       10,
+			expectedBatches: 1,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			reg := prometheus.NewPedanticRegistry()
+			client := NewWriteClient(WriteClientConfig{
+				URL:              *serverURL,
+				UserID:           "user-1",
+				SeriesCount:      testData.seriesCount,
+				WriteInterval:    time.Second,
+				WriteTimeout:     time.Second,
+				WriteConcurrency: 2,
+				WriteBatchSize:   testData.batchSize,
+			}, log.NewNopLogger(), reg)
+
+			client.writeSeries()
+
+			expectedBatches := int(math.Ceil(float64(testData.seriesCount) / float64(testData.batchSize)))
+			assert.Equal(t, testData.expectedBatches, expectedBatches)
+			assert.Equal(t, float64(expectedBatches), testutil.ToFloat64(client.writeBatchesLastInterval))
+		})
+	}
+}
+
+func TestWriteClient_InjectWriteFailure(t *testing.T) {
+	const numRequests = 10000
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	for _, failureRate := range []float64{0, 0.1, 0.5, 1} {
+		t.Run(fmt.Sprintf("failure rate: %f", failureRate), func(t *testing.T) {
+			client := NewWriteClient(WriteClientConfig{
+				URL:                    *serverURL,
+				UserID:                 "user-1",
+				WriteInterval:          time.Second,
+				WriteTimeout:           time.Second,
+				WriteConcurrency:       1,
+				InjectWriteFailureRate: failureRate,
+				InjectWriteFailureSeed: 1,
+			}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+			failures := 0
+			for i := 0; i < numRequests; i++ {
+				if err := client.send(context.Background(), &prompb.WriteRequest{}); err != nil {
+					require.ErrorIs(t, err, errInjectedWriteFailure)
+					failures++
+				}
+			}
+
+			assert.InDelta(t, failureRate, float64(failures)/numRequests, 0.02)
+		})
+	}
+}
+
+func TestPartitionSeries(t *testing.T) {
+	series := generateSineWaveSeries(time.Now(), WriteClientConfig{SeriesCount: 7})
+
+	// Returns the series IDs (value of the "wave" label) for each batch.
+	getBatchSeriesIDs := func(batches [][]*prompb.TimeSeries) [][]string {
+		out := make([][]string, 0, len(batches))
+		for _, batch := range batches {
+			ids := make([]string, 0, len(batch))
+			for _, s := range batch {
+				for _, l := range s.Labels {
+					if l.Name == "wave" {
+						ids = append(ids, l.Value)
+					}
+				}
+			}
+			out = append(out, ids)
+		}
+		return out
+	}
+
+	tests := map[string]struct {
+		batchSize       int
+		assignment      string
+		expectedBatches [][]string
+	}{
+		"contiguous": {
+			batchSize:       3,
+			assignment:      BatchAssignmentContiguous,
+			expectedBatches: [][]string{{"1", "2", "3"}, {"4", "5", "6"}, {"7"}},
+		},
+		"round-robin": {
+			batchSize:       3,
+			assignment:      BatchAssignmentRoundRobin,
+			expectedBatches: [][]string{{"1", "4", "7"}, {"2", "5"}, {"3", "6"}},
+		},
+		"round-robin with batch size greater than series count": {
+			batchSize:       10,
+			assignment:      BatchAssignmentRoundRobin,
+			expectedBatches: [][]string{{"1", "2", "3", "4", "5", "6", "7"}},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, testData.expectedBatches, getBatchSeriesIDs(partitionSeries(series, testData.batchSize, testData.assignment)))
+		})
+	}
+}
+
+func TestGenerateInfoSeries(t *testing.T) {
+	ts, err := time.Parse(time.RFC3339, "2023-06-29T00:00:00Z")
+	require.NoError(t, err)
+
+	actual := generateInfoSeries(ts, WriteClientConfig{InfoSeriesCount: 2, InfoSeriesLabels: 2})
+
+	assert.Equal(t, []*prompb.TimeSeries{
+		{
+			Labels:  []*prompb.Label{{Name: "__name__", Value: "cortex_load_generator_info"}, {Name: "info", Value: "1"}, {Name: "infoLabel0", Value: "value-1-0"}, {Name: "infoLabel1", Value: "value-1-1"}},
+			Samples: []prompb.Sample{{Timestamp: ts.UnixMilli(), Value: 1}},
+		}, {
+			Labels:  []*prompb.Label{{Name: "__name__", Value: "cortex_load_generator_info"}, {Name: "info", Value: "2"}, {Name: "infoLabel0", Value: "value-2-0"}, {Name: "infoLabel1", Value: "value-2-1"}},
+			Samples: []prompb.Sample{{Timestamp: ts.UnixMilli(), Value: 1}},
+		},
+	}, actual)
+
+	// No info series are generated by default.
+	assert.Empty(t, generateInfoSeries(ts, WriteClientConfig{}))
+}
+
+func TestWriteClient_ShouldHonorMaxWriteBytes(t *testing.T) {
+	const maxWriteBytes = 1024
+
+	// Build a help text which doesn't compress well, so that the metadata takes a
+	// significant part of each request.
+	random := rand.New(rand.NewSource(0))
+	help := make([]byte, 400)
+	for i := range help {
+		help[i] = byte('a' + random.Intn(26))
+	}
+
+	tests := map[string]struct {
+		metadata *MetricMetadata
+	}{
+		"without metadata": {},
+		"with metadata": {
+			metadata: &MetricMetadata{Type: "gauge", Help: string(help)},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var (
+				receivedMx sync.Mutex
+				received   []int
+				series     int
+			)
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				if !assert.NoError(t, err) {
+					return
+				}
+
+				req, err := decodeWriteRequest(body)
+				if !assert.NoError(t, err) {
+					return
+				}
+
+				receivedMx.Lock()
+				received = append(received, len(body))
+				series += len(req.Timeseries)
+				receivedMx.Unlock()
+			}))
+			t.Cleanup(server.Close)
+
+			serverURL, err := url.Parse(server.URL)
+			require.NoError(t, err)
+
+			client := NewWriteClient(WriteClientConfig{
+				URL:              *serverURL,
+				UserID:           "user-1",
+				SeriesCount:      100,
+				ExtraLabels:      10,
+				WriteInterval:    time.Second,
+				WriteTimeout:     time.Second,
+				WriteConcurrency: 10,
+				WriteBatchSize:   100,
+				MaxWriteBytes:    maxWriteBytes,
+				Metadata:         testData.metadata,
+			}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+			client.writeSeries()
+
+			receivedMx.Lock()
+			defer receivedMx.Unlock()
+
+			// The batch should have been split, without losing any series.
+			assert.Greater(t, len(received), 1)
+			assert.Equal(t, 100, series)
+			for _, size := range received {
+				assert.LessOrEqual(t, size, maxWriteBytes)
+			}
+		})
+	}
+}
+
+func TestEncodeWriteRequest(t *testing.T) {
+	req := &prompb.WriteRequest{
+		Timeseries: generateSineWaveSeries(time.Now(), WriteClientConfig{SeriesCount: 1000, ExtraLabels: 5, SeriesChurnPeriod: time.Hour}),
+	}
+
+	actual, err := encodeWriteRequest(req, nil)
+	require.NoError(t, err)
+
+	// Should be byte-identical to the naive encoding.
+	data, err := proto.Marshal(req)
+	require.NoError(t, err)
+	assert.Equal(t, snappy.Encode(nil, data), actual)
+}
+
+func BenchmarkEncodeWriteRequest(b *testing.B) {
+	req := &prompb.WriteRequest{
+		Timeseries: generateSineWaveSeries(time.Now(), WriteClientConfig{SeriesCount: 10000, ExtraLabels: 5}),
+	}
+
+	b.Run("naive", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for n := 0; n < b.N; n++ {
+			data, err := proto.Marshal(req)
+			if err != nil {
+				b.Fatal(err)
+			}
+			_ = snappy.Encode(nil, data)
+		}
+	})
+
+	b.Run("pre-sized", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for n := 0; n < b.N; n++ {
+			if _, err := encodeWriteRequest(req, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestWriteClient_ShouldReplaceTenantPlaceholderInURL(t *testing.T) {
+	var (
+		receivedMx sync.Mutex
+		received   []string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedMx.Lock()
+		received = append(received, r.Method+" "+r.URL.Path)
+		receivedMx.Unlock()
+	}))
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL + "/api/{tenant}/push")
+	require.NoError(t, err)
+
+	for _, userID := range []string{"user-1", "user-2"} {
+		client := NewWriteClient(WriteClientConfig{
+			URL:              *serverURL,
+			WriteMethod:      http.MethodPut,
+			UserID:           userID,
+			SeriesCount:      1,
+			WriteInterval:    time.Second,
+			WriteTimeout:     time.Second,
+			WriteConcurrency: 1,
+			WriteBatchSize:   1,
+		}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+		client.writeSeries()
+	}
+
+	receivedMx.Lock()
+	defer receivedMx.Unlock()
+	assert.Equal(t, []string{"PUT /api/user-1/push", "PUT /api/user-2/push"}, received)
+}
+
+func TestWriteClient_ShouldPickTenantFromPoolForEachRequest(t *testing.T) {
+	const numRequests = 900
+
+	var (
+		receivedMx sync.Mutex
+		received   = map[string]int{}
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant := r.Header.Get("X-Scope-OrgID")
+		assert.Equal(t, "/api/"+tenant+"/push", r.URL.Path)
+
+		receivedMx.Lock()
+		received[tenant]++
+		receivedMx.Unlock()
+	}))
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL + "/api/{tenant}/push")
+	require.NoError(t, err)
+
+	pool := []string{"tenant-a", "tenant-b", "tenant-c"}
+	client := NewWriteClient(WriteClientConfig{
+		URL:              *serverURL,
+		UserID:           "user-1",
+		TenantPool:       pool,
+		SeriesCount:      numRequests,
+		WriteInterval:    10 * time.Second,
+		WriteTimeout:     time.Second,
+		WriteConcurrency: 10,
+		WriteBatchSize:   1,
+	}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+	assert.Equal(t, numRequests, client.writeSeries())
+
+	receivedMx.Lock()
+	defer receivedMx.Unlock()
+
+	// Requests should be evenly spread across the tenants of the pool, and only them.
+	assert.Len(t, received, len(pool))
+	for _, tenant := range pool {
+		assert.InDelta(t, numRequests/len(pool), received[tenant], float64(numRequests/len(pool))*0.25, tenant)
+	}
+}
+
+func TestWriteClient_ShouldDecodeGzipEncodedErrorResponses(t *testing.T) {
+	const errMsg = "per-user series limit of 1000 exceeded"
+
+	tests := map[string]struct {
+		acceptEncoding string
+	}{
+		"default accept encoding": {},
+		"gzip accept encoding":    {acceptEncoding: "gzip"},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if testData.acceptEncoding != "" {
+					assert.Equal(t, testData.acceptEncoding, r.Header.Get("Accept-Encoding"))
+				}
+
+				// The gateway gzips the error message, even if not asked to.
+				w.Header().Set("Content-Encoding", "gzip")
+				w.WriteHeader(http.StatusBadRequest)
+
+				gz := gzip.NewWriter(w)
+				_, _ = gz.Write([]byte(errMsg + "\n"))
+				_ = gz.Close()
+			}))
+			t.Cleanup(server.Close)
+
+			serverURL, err := url.Parse(server.URL)
+			require.NoError(t, err)
+
+			logs := &bytes.Buffer{}
+			client := NewWriteClient(WriteClientConfig{
+				URL:              *serverURL,
+				UserID:           "user-1",
+				AcceptEncoding:   testData.acceptEncoding,
+				SeriesCount:      1,
+				WriteInterval:    time.Second,
+				WriteTimeout:     time.Second,
+				WriteConcurrency: 1,
+				WriteBatchSize:   1,
+			}, log.NewLogfmtLogger(log.NewSyncWriter(logs)), prometheus.NewPedanticRegistry())
+
+			assert.Equal(t, 0, client.writeSeries())
+			assert.Contains(t, logs.String(), "server returned HTTP status 400 Bad Request: "+errMsg)
+		})
+	}
+}
+
+func TestWriteClient_ShouldTrackRateLimitedWriteRequests(t *testing.T) {
+	var (
+		requestsMx sync.Mutex
+		requests   int
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestsMx.Lock()
+		requests++
+		requestsMx.Unlock()
+
+		w.Header().Set("Retry-After", "3600")
+		http.Error(w, "ingestion rate limit exceeded", http.StatusTooManyRequests)
+	}))
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	for _, honorRetryAfter := range []bool{false, true} {
+		t.Run(fmt.Sprintf("honor retry after: %t", honorRetryAfter), func(t *testing.T) {
+			requestsMx.Lock()
+			requests = 0
+			requestsMx.Unlock()
+
+			client := NewWriteClient(WriteClientConfig{
+				URL:              *serverURL,
+				UserID:           "user-1",
+				SeriesCount:      2,
+				WriteInterval:    time.Second,
+				WriteTimeout:     time.Second,
+				WriteConcurrency: 1,
+				WriteBatchSize:   1,
+				HonorRetryAfter:  honorRetryAfter,
+			}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+			client.writeSeries()
+			assert.Equal(t, float64(2), testutil.ToFloat64(client.writeRateLimitedTotal))
+
+			// When honoring Retry-After, the next write should be skipped.
+			client.writeSeries()
+
+			requestsMx.Lock()
+			defer requestsMx.Unlock()
+
+			if honorRetryAfter {
+				assert.Equal(t, 2, requests)
+				assert.Equal(t, float64(2), testutil.ToFloat64(client.writeRateLimitedTotal))
+			} else {
+				assert.Equal(t, 4, requests)
+				assert.Equal(t, float64(4), testutil.ToFloat64(client.writeRateLimitedTotal))
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2023, 6, 29, 0, 0, 0, 0, time.UTC)
+
+	actual, ok := parseRetryAfter("120", now)
+	assert.True(t, ok)
+	assert.Equal(t, now.Add(2*time.Minute), actual)
+
+	actual, ok = parseRetryAfter("Thu, 29 Jun 2023 00:05:00 GMT", now)
+	assert.True(t, ok)
+	assert.True(t, now.Add(5*time.Minute).Equal(actual))
+
+	_, ok = parseRetryAfter("", now)
+	assert.False(t, ok)
+
+	_, ok = parseRetryAfter("soon", now)
+	assert.False(t, ok)
+}
+
+func TestGenerateSineWaveSeries_WithMultipleMetricNames(t *testing.T) {
+	const (
+		numSeries      = 3
+		numMetricNames = 4
+	)
+
+	series := generateSineWaveSeries(time.Now(), WriteClientConfig{SeriesCount: numSeries, MetricNamesCount: numMetricNames})
+	require.Len(t, series, numSeries*numMetricNames)
+
+	seriesPerMetricName := map[string]int{}
+	for _, s := range series {
+		seriesPerMetricName[s.Labels[0].Value]++
+	}
+
+	assert.Equal(t, map[string]int{
+		"cortex_load_generator_sine_wave_0": numSeries,
+		"cortex_load_generator_sine_wave_1": numSeries,
+		"cortex_load_generator_sine_wave_2": numSeries,
+		"cortex_load_generator_sine_wave_3": numSeries,
+	}, seriesPerMetricName)
+}
+
+func TestGenerateSineWaveSeries_WithChecksumLabel(t *testing.T) {
+	ts, err := time.Parse(time.RFC3339, "2023-06-29T00:00:10Z")
+	require.NoError(t, err)
+
+	for _, checksumLabel := range []bool{false, true} {
+		series := generateSineWaveSeries(ts, WriteClientConfig{SeriesCount: 3, Values: ValueConfig{Decorrelation: 1}, ChecksumLabel: checksumLabel})
+		require.Len(t, series, 3)
+
+		for _, s := range series {
+			var checksum *prompb.Label
+			for _, l := range s.Labels {
+				if l.Name == checksumLabelName {
+					checksum = l
+				}
+			}
+
+			if !checksumLabel {
+				assert.Nil(t, checksum)
+				continue
+			}
+
+			require.NotNil(t, checksum)
+			assert.Equal(t, valueChecksum(s.Samples[0].Value), checksum.Value)
+		}
+	}
+}
+
+func TestGenerateSineWaveSeries_WithDistinctLabelNames(t *testing.T) {
+	const numLabelNames = 20
+
+	ts, err := time.Parse(time.RFC3339, "2023-06-29T00:00:10Z")
+	require.NoError(t, err)
+
+	series := generateSineWaveSeries(ts, WriteClientConfig{SeriesCount: 30, DistinctLabelNames: numLabelNames})
+	require.Len(t, series, 30)
+
+	labelNames := map[string]struct{}{}
+	for _, s := range series {
+		for _, l := range s.Labels {
+			if strings.HasPrefix(l.Name, "distinctLabel") {
+				labelNames[l.Name] = struct{}{}
+			}
+		}
+
+		// Series should get a subset of label names.
+		assert.Less(t, len(s.Labels), 2+numLabelNames)
+	}
+	assert.Len(t, labelNames, numLabelNames)
+
+	// Label names should be deterministic per series ID.
+	assert.Equal(t, series, generateSineWaveSeries(ts, WriteClientConfig{SeriesCount: 30, DistinctLabelNames: numLabelNames}))
+}
+
+func TestGenerateSineWaveSeries_WithHAReplicas(t *testing.T) {
+	ts, err := time.Parse(time.RFC3339, "2023-06-29T00:00:10Z")
+	require.NoError(t, err)
+
+	series := generateSineWaveSeries(ts, WriteClientConfig{SeriesCount: 2, HAReplicas: 2})
+	require.Len(t, series, 4)
+
+	expectedLabels := func(wave, replica string) []*prompb.Label {
+		return []*prompb.Label{
+			{Name: "__name__", Value: sineWaveMetricName},
+			{Name: "__replica__", Value: replica},
+			{Name: "cluster", Value: "cortex-load-generator"},
+			{Name: "wave", Value: wave},
+		}
+	}
+
+	assert.Equal(t, expectedLabels("1", "replica-a"), series[0].Labels)
+	assert.Equal(t, expectedLabels("2", "replica-a"), series[1].Labels)
+	assert.Equal(t, expectedLabels("1", "replica-b"), series[2].Labels)
+	assert.Equal(t, expectedLabels("2", "replica-b"), series[3].Labels)
+
+	// Replicas should have identical samples.
+	assert.Equal(t, series[0].Samples, series[2].Samples)
+	assert.Equal(t, series[1].Samples, series[3].Samples)
+}
+
+func TestGenerateSineWaveSeries_WithClockSkew(t *testing.T) {
+	const (
+		numSeries     = 50
+		writeInterval = 10 * time.Second
+	)
+
+	start, err := time.Parse(time.RFC3339, "2023-06-29T00:00:10Z")
+	require.NoError(t, err)
+
+	cfg := WriteClientConfig{SeriesCount: numSeries, WriteInterval: writeInterval, ClockSkewStdDev: 4 * time.Second}
+
+	// Collect the samples written by each series over a few intervals.
+	samples := map[string][]prompb.Sample{}
+	var steps []time.Time
+	skewed := 0
+
+	for ts := start; ts.Before(start.Add(10 * writeInterval)); ts = ts.Add(writeInterval) {
+		steps = append(steps, ts)
+
+		for _, s := range generateSineWaveSeries(ts, cfg) {
+			sampleTime := time.UnixMilli(s.Samples[0].Timestamp)
+
+			// Timestamps should be skewed back in time by less than a write interval.
+			assert.False(t, sampleTime.After(ts))
+			assert.True(t, sampleTime.After(ts.Add(-writeInterval)))
+			if sampleTime.Before(ts) {
+				skewed++
+			}
+
+			wave := s.Labels[1].Value
+			samples[wave] = append(samples[wave], s.Samples[0])
+		}
+	}
+
+	assert.Greater(t, skewed, 0)
+
+	// The skew should be deterministic per series ID.
+	assert.Equal(t, generateSineWaveSeries(start, cfg), generateSineWaveSeries(start, cfg))
+
+	// Sum the latest sample of each series at each step, like a PromQL range query would do,
+	// and verify the result.
+	var sum []model.SamplePair
+	for _, step := range steps {
+		value := 0.0
+		for _, seriesSamples := range samples {
+			var latest prompb.Sample
+			for _, sample := range seriesSamples {
+				if sample.Timestamp <= step.UnixMilli() {
+					latest = sample
+				}
+			}
+			value += latest.Value
+		}
+		sum = append(sum, newSamplePair(step, value))
+	}
+
+	assert.NoError(t, verifySineWaveSamples(sum, numSeries, SeriesSchedule{}, writeInterval, ValueConfig{}, nil))
+}
+
+func TestWriteClient_ShouldWriteHAReplicasInDedicatedRequests(t *testing.T) {
+	var (
+		receivedMx sync.Mutex
+		received   []map[string]int
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		req, err := decodeWriteRequest(body)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		replicas := map[string]int{}
+		for _, series := range req.Timeseries {
+			for _, l := range series.Labels {
+				if l.Name == haReplicaLabelName {
+					replicas[l.Value]++
+				}
+			}
+		}
+
+		receivedMx.Lock()
+		received = append(received, replicas)
+		receivedMx.Unlock()
+	}))
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	client := NewWriteClient(WriteClientConfig{
+		URL:              *serverURL,
+		UserID:           "user-1",
+		SeriesCount:      3,
+		HAReplicas:       2,
+		WriteInterval:    time.Second,
+		WriteTimeout:     time.Second,
+		WriteConcurrency: 1,
+		WriteBatchSize:   2,
+	}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+	client.writeSeries()
+
+	receivedMx.Lock()
+	defer receivedMx.Unlock()
+	assert.ElementsMatch(t, []map[string]int{
+		{"replica-a": 2},
+		{"replica-a": 1},
+		{"replica-b": 2},
+		{"replica-b": 1},
+	}, received)
+}
+
+func TestWriteClient_ShouldShareTransportAcrossTenants(t *testing.T) {
+	const numTenants = 5
+
+	var (
+		receivedMx sync.Mutex
+		received   []string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedMx.Lock()
+		received = append(received, r.Header.Get("X-Scope-OrgID"))
+		receivedMx.Unlock()
+	}))
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	transport := &countingRoundTripper{rt: &http.Transport{}}
+
+	var expected []string
+	for i := 1; i <= numTenants; i++ {
+		userID := fmt.Sprintf("user-%d", i)
+		expected = append(expected, userID)
+
+		client := NewWriteClient(WriteClientConfig{
+			URL:              *serverURL,
+			Transport:        transport,
+			UserID:           userID,
+			SeriesCount:      1,
+			WriteInterval:    time.Second,
+			WriteTimeout:     time.Second,
+			WriteConcurrency: 1,
+			WriteBatchSize:   1,
+		}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+		// The client should wrap the shared transport.
+		require.Same(t, transport, client.client.Transport.(*clientRoundTripper).rt)
+
+		client.writeSeries()
+	}
+
+	receivedMx.Lock()
+	defer receivedMx.Unlock()
+	assert.Equal(t, expected, received)
+	assert.Equal(t, int64(numTenants), atomic.LoadInt64(&transport.count))
+}
+
+type countingRoundTripper struct {
+	rt    http.RoundTripper
+	count int64
+}
+
+func (c *countingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt64(&c.count, 1)
+	return c.rt.RoundTrip(req)
+}
+
+// decodeWriteRequest decodes the compressed remote write request received by a test server.
+func decodeWriteRequest(compressed []byte) (*prompb.WriteRequest, error) {
+	data, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &prompb.WriteRequest{}
+	if err := proto.Unmarshal(data, req); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
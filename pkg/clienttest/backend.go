@@ -0,0 +1,398 @@
+// Package clienttest provides an in-memory remote write and query backend, to exercise the
+// write and query clients together in tests.
+package clienttest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/value"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+const (
+	// PushPath is the path of the remote write endpoint.
+	PushPath = "/api/v1/push"
+
+	// TenantHeaderName is the name of the HTTP header carrying the tenant ID.
+	TenantHeaderName = "X-Scope-OrgID"
+
+	// lookbackDelta is the max time range a query looks back in time to find the latest sample
+	// of a series, like the PromQL engine default.
+	lookbackDelta = 5 * time.Minute
+)
+
+// Backend is an in-memory remote write sink serving range queries on the received samples.
+// It only supports a subset of PromQL: a vector selector with equality label matchers,
+// optionally wrapped in sum(), which is what the load generator queries by default.
+type Backend struct {
+	server *httptest.Server
+
+	mx      sync.Mutex
+	tenants map[string]map[string]*series
+}
+
+type series struct {
+	metric  model.Metric
+	samples []model.SamplePair
+}
+
+// NewBackend starts a new backend. It must be closed once done.
+func NewBackend() *Backend {
+	b := &Backend{
+		tenants: map[string]map[string]*series{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(PushPath, b.handlePush)
+	mux.HandleFunc("/api/v1/query_range", b.handleQueryRange)
+	b.server = httptest.NewServer(mux)
+
+	return b
+}
+
+// URL returns the base URL of the backend, to be used as the query client URL.
+func (b *Backend) URL() string {
+	return b.server.URL
+}
+
+// PushURL returns the URL of the remote write endpoint, to be used as the write client URL.
+func (b *Backend) PushURL() string {
+	return b.server.URL + PushPath
+}
+
+// Close shuts down the backend.
+func (b *Backend) Close() {
+	b.server.Close()
+}
+
+// SeriesCount returns the number of series stored for the tenant.
+func (b *Backend) SeriesCount(tenant string) int {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	return len(b.tenants[tenant])
+}
+
+// SamplesCount returns the number of samples stored for the tenant.
+func (b *Backend) SamplesCount(tenant string) int {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	count := 0
+	for _, s := range b.tenants[tenant] {
+		count += len(s.samples)
+	}
+
+	return count
+}
+
+func (b *Backend) handlePush(w http.ResponseWriter, r *http.Request) {
+	tenant := r.Header.Get(TenantHeaderName)
+	if tenant == "" {
+		http.Error(w, "no org id", http.StatusUnauthorized)
+		return
+	}
+
+	compressed, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req := &prompb.WriteRequest{}
+	if err := proto.Unmarshal(data, req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	for _, ts := range req.Timeseries {
+		b.append(tenant, ts)
+	}
+}
+
+// append adds the samples of the input series to the storage. Samples with the same
+// timestamp of a stored one overwrite it. Must be called with the lock held.
+func (b *Backend) append(tenant string, ts *prompb.TimeSeries) {
+	metric := make(model.Metric, len(ts.Labels))
+	for _, l := range ts.Labels {
+		metric[model.LabelName(l.Name)] = model.LabelValue(l.Value)
+	}
+
+	tenantSeries, ok := b.tenants[tenant]
+	if !ok {
+		tenantSeries = map[string]*series{}
+		b.tenants[tenant] = tenantSeries
+	}
+
+	key := metric.String()
+	s, ok := tenantSeries[key]
+	if !ok {
+		s = &series{metric: metric}
+		tenantSeries[key] = s
+	}
+
+	for _, sample := range ts.Samples {
+		pair := model.SamplePair{Timestamp: model.Time(sample.Timestamp), Value: model.SampleValue(sample.Value)}
+
+		idx := sort.Search(len(s.samples), func(i int) bool { return s.samples[i].Timestamp >= pair.Timestamp })
+		if idx < len(s.samples) && s.samples[idx].Timestamp == pair.Timestamp {
+			s.samples[idx] = pair
+			continue
+		}
+
+		s.samples = append(s.samples, model.SamplePair{})
+		copy(s.samples[idx+1:], s.samples[idx:])
+		s.samples[idx] = pair
+	}
+}
+
+func (b *Backend) handleQueryRange(w http.ResponseWriter, r *http.Request) {
+	tenant := r.Header.Get(TenantHeaderName)
+	if tenant == "" {
+		writeQueryError(w, http.StatusUnauthorized, "bad_data", "no org id")
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeQueryError(w, http.StatusBadRequest, "bad_data", err.Error())
+		return
+	}
+
+	start, err := parseTime(r.Form.Get("start"))
+	if err != nil {
+		writeQueryError(w, http.StatusBadRequest, "bad_data", fmt.Sprintf("invalid start: %s", err))
+		return
+	}
+	end, err := parseTime(r.Form.Get("end"))
+	if err != nil {
+		writeQueryError(w, http.StatusBadRequest, "bad_data", fmt.Sprintf("invalid end: %s", err))
+		return
+	}
+	step, err := parseDuration(r.Form.Get("step"))
+	if err != nil || step <= 0 {
+		writeQueryError(w, http.StatusBadRequest, "bad_data", "invalid step")
+		return
+	}
+
+	query, err := parseQuery(r.Form.Get("query"))
+	if err != nil {
+		writeQueryError(w, http.StatusBadRequest, "bad_data", err.Error())
+		return
+	}
+
+	matrix := b.evaluate(tenant, query, start, end, step)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "success",
+		"data": map[string]interface{}{
+			"resultType": model.ValMatrix.String(),
+			"result":     matrix,
+		},
+	})
+}
+
+// evaluate runs the query at each step between start and end, both included.
+func (b *Backend) evaluate(tenant string, q query, start, end model.Time, step time.Duration) model.Matrix {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	matrix := model.Matrix{}
+	for _, s := range b.tenants[tenant] {
+		if !q.matches(s.metric) {
+			continue
+		}
+
+		stream := &model.SampleStream{Metric: s.metric}
+		for ts := start; ts <= end; ts = ts.Add(step) {
+			if value, ok := s.valueAt(ts); ok {
+				stream.Values = append(stream.Values, model.SamplePair{Timestamp: ts, Value: value})
+			}
+		}
+
+		if len(stream.Values) > 0 {
+			matrix = append(matrix, stream)
+		}
+	}
+
+	if q.sum {
+		matrix = sumMatrix(matrix)
+	}
+
+	sort.Sort(matrix)
+	return matrix
+}
+
+// valueAt returns the value of the latest sample at or before ts, within the lookback delta,
+// unless it's a stale marker.
+func (s *series) valueAt(ts model.Time) (model.SampleValue, bool) {
+	idx := sort.Search(len(s.samples), func(i int) bool { return s.samples[i].Timestamp > ts })
+	if idx == 0 {
+		return 0, false
+	}
+
+	// Series marked as stale are not returned anymore, like the PromQL engine does.
+	sample := s.samples[idx-1]
+	if ts.Sub(sample.Timestamp) > lookbackDelta || value.IsStaleNaN(float64(sample.Value)) {
+		return 0, false
+	}
+
+	return sample.Value, true
+}
+
+// sumMatrix sums all series of the input matrix in a single series without labels.
+func sumMatrix(matrix model.Matrix) model.Matrix {
+	if len(matrix) == 0 {
+		return matrix
+	}
+
+	sums := map[model.Time]model.SampleValue{}
+	for _, stream := range matrix {
+		for _, sample := range stream.Values {
+			sums[sample.Timestamp] += sample.Value
+		}
+	}
+
+	stream := &model.SampleStream{Metric: model.Metric{}}
+	for ts, value := range sums {
+		stream.Values = append(stream.Values, model.SamplePair{Timestamp: ts, Value: value})
+	}
+	sort.Slice(stream.Values, func(i, j int) bool { return stream.Values[i].Timestamp < stream.Values[j].Timestamp })
+
+	return model.Matrix{stream}
+}
+
+// query is a parsed query supported by the backend.
+type query struct {
+	sum      bool
+	matchers model.LabelSet
+}
+
+func (q query) matches(metric model.Metric) bool {
+	for name, value := range q.matchers {
+		if metric[name] != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseQuery parses a vector selector with equality label matchers, optionally wrapped
+// in sum(). Any other query is unsupported.
+func parseQuery(input string) (query, error) {
+	q := query{matchers: model.LabelSet{}}
+
+	input = strings.TrimSpace(input)
+	if strings.HasPrefix(input, "sum(") && strings.HasSuffix(input, ")") {
+		q.sum = true
+		input = strings.TrimSpace(input[len("sum(") : len(input)-1])
+	}
+
+	name := input
+	if idx := strings.IndexByte(input, '{'); idx >= 0 {
+		if !strings.HasSuffix(input, "}") {
+			return q, fmt.Errorf("unsupported query %q", input)
+		}
+
+		name = strings.TrimSpace(input[:idx])
+		if err := parseMatchers(input[idx+1:len(input)-1], q.matchers); err != nil {
+			return q, err
+		}
+	}
+
+	if name != "" {
+		if !model.IsValidMetricName(model.LabelValue(name)) {
+			return q, fmt.Errorf("unsupported query %q", input)
+		}
+		q.matchers[model.MetricNameLabel] = model.LabelValue(name)
+	}
+
+	if len(q.matchers) == 0 {
+		return q, errors.New("the query must select at least one label")
+	}
+
+	return q, nil
+}
+
+// parseMatchers parses comma separated equality label matchers (eg. a="1",b="2") into out.
+func parseMatchers(input string, out model.LabelSet) error {
+	for _, matcher := range strings.Split(input, ",") {
+		matcher = strings.TrimSpace(matcher)
+		if matcher == "" {
+			continue
+		}
+
+		parts := strings.SplitN(matcher, "=", 2)
+		if len(parts) != 2 || strings.ContainsAny(parts[0], "!~") || strings.HasPrefix(parts[1], "~") {
+			return fmt.Errorf("unsupported label matcher %q", matcher)
+		}
+
+		name := model.LabelName(strings.TrimSpace(parts[0]))
+		if !name.IsValid() {
+			return fmt.Errorf("invalid label name in matcher %q", matcher)
+		}
+
+		value, err := strconv.Unquote(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return fmt.Errorf("invalid label value in matcher %q: %w", matcher, err)
+		}
+
+		out[name] = model.LabelValue(value)
+	}
+
+	return nil
+}
+
+// parseTime parses a query timestamp, in seconds since the epoch.
+func parseTime(input string) (model.Time, error) {
+	seconds, err := strconv.ParseFloat(input, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return model.Time(math.Round(seconds * 1000)), nil
+}
+
+// parseDuration parses a query step, either in seconds or as a Prometheus duration.
+func parseDuration(input string) (time.Duration, error) {
+	if seconds, err := strconv.ParseFloat(input, 64); err == nil {
+		return time.Duration(math.Round(seconds*1000)) * time.Millisecond, nil
+	}
+
+	d, err := model.ParseDuration(input)
+	return time.Duration(d), err
+}
+
+func writeQueryError(w http.ResponseWriter, statusCode int, errorType, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"status":    "error",
+		"errorType": errorType,
+		"error":     msg,
+	})
+}
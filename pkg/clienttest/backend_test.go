@@ -0,0 +1,96 @@
+package clienttest
+
+import (
+	"math"
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/value"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := map[string]struct {
+		input       string
+		expected    query
+		expectedErr bool
+	}{
+		"metric name": {
+			input:    "cortex_load_generator_sine_wave",
+			expected: query{matchers: model.LabelSet{"__name__": "cortex_load_generator_sine_wave"}},
+		},
+		"metric name with label matchers": {
+			input:    `cortex_load_generator_sine_wave{wave="1", region="eu"}`,
+			expected: query{matchers: model.LabelSet{"__name__": "cortex_load_generator_sine_wave", "wave": "1", "region": "eu"}},
+		},
+		"label matchers only": {
+			input:    `{__name__="cortex_load_generator_sine_wave"}`,
+			expected: query{matchers: model.LabelSet{"__name__": "cortex_load_generator_sine_wave"}},
+		},
+		"sum": {
+			input:    "sum(cortex_load_generator_sine_wave)",
+			expected: query{sum: true, matchers: model.LabelSet{"__name__": "cortex_load_generator_sine_wave"}},
+		},
+		"unsupported aggregation": {
+			input:       "avg(cortex_load_generator_sine_wave)",
+			expectedErr: true,
+		},
+		"unsupported matcher": {
+			input:       `cortex_load_generator_sine_wave{wave=~"1"}`,
+			expectedErr: true,
+		},
+		"empty selector": {
+			input:       "{}",
+			expectedErr: true,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			actual, err := parseQuery(testData.input)
+			if testData.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, testData.expected, actual)
+		})
+	}
+}
+
+func TestSeries_ValueAt(t *testing.T) {
+	s := &series{samples: []model.SamplePair{{Timestamp: 10000, Value: 1}, {Timestamp: 20000, Value: 2}}}
+
+	for ts, expected := range map[model.Time]interface{}{
+		9999:                                  nil,
+		10000:                                 model.SampleValue(1),
+		19999:                                 model.SampleValue(1),
+		20000:                                 model.SampleValue(2),
+		20000 + model.Time(lookbackDelta/1e6): model.SampleValue(2),
+		20001 + model.Time(lookbackDelta/1e6): nil,
+	} {
+		actual, ok := s.valueAt(ts)
+		if expected == nil {
+			assert.False(t, ok, "timestamp: %d", ts)
+		} else {
+			assert.True(t, ok, "timestamp: %d", ts)
+			assert.Equal(t, expected, actual, "timestamp: %d", ts)
+		}
+	}
+}
+
+func TestSeries_ValueAt_ShouldNotReturnStaleMarkers(t *testing.T) {
+	s := &series{samples: []model.SamplePair{{Timestamp: 10000, Value: 1}, {Timestamp: 20000, Value: model.SampleValue(math.Float64frombits(value.StaleNaN))}}}
+
+	actual, ok := s.valueAt(19999)
+	assert.True(t, ok)
+	assert.Equal(t, model.SampleValue(1), actual)
+
+	_, ok = s.valueAt(20000)
+	assert.False(t, ok)
+
+	_, ok = s.valueAt(30000)
+	assert.False(t, ok)
+}
@@ -15,19 +15,32 @@ import (
 type InstrumentationServer struct {
 	port     int
 	registry *prometheus.Registry
+	router   *mux.Router
 	srv      *http.Server
 	logger   log.Logger
 }
 
 // NewInstrumentationServer returns a server exposing Prometheus metrics.
 func NewInstrumentationServer(port int, logger log.Logger, registry *prometheus.Registry) *InstrumentationServer {
+	router := mux.NewRouter()
+	router.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+
 	return &InstrumentationServer{
 		port:     port,
 		registry: registry,
+		router:   router,
 		logger:   logger,
 	}
 }
 
+// Handle registers an additional handler for the input path. It must be called before Start.
+func (s *InstrumentationServer) Handle(path string, handler http.Handler, methods ...string) {
+	route := s.router.Handle(path, handler)
+	if len(methods) > 0 {
+		route.Methods(methods...)
+	}
+}
+
 // Start the instrumentation server.
 func (s *InstrumentationServer) Start() error {
 	// Setup listener first, so we can fail early if the port is in use.
@@ -36,11 +49,8 @@ func (s *InstrumentationServer) Start() error {
 		return err
 	}
 
-	router := mux.NewRouter()
-	router.Handle("/metrics", promhttp.HandlerFor(s.registry, promhttp.HandlerOpts{}))
-
 	s.srv = &http.Server{
-		Handler: router,
+		Handler: s.router,
 	}
 
 	go func() {